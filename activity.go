@@ -0,0 +1,99 @@
+package maptiler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// IngestParams are the inputs of IngestActivity. All fields are serializable so
+// the params can be passed through workflow engines as is.
+type IngestParams struct {
+	// DatasetID of the dataset to update, leave empty to create a new dataset.
+	DatasetID string `json:"dataset_id,omitempty"`
+	// FilePath of the file to ingest.
+	FilePath string `json:"file_path"`
+	// IngestID of a previous attempt, e.g. taken from the last recorded heartbeat.
+	IngestID string `json:"ingest_id,omitempty"`
+}
+
+// IngestResult is the outcome of IngestActivity.
+type IngestResult struct {
+	IngestID   string      `json:"ingest_id"`
+	DocumentID string      `json:"document_id"`
+	State      IngestState `json:"state"`
+	Resumed    bool        `json:"resumed"`
+}
+
+func (p IngestParams) String() string { return toJSONString(p) }
+func (r IngestResult) String() string { return toJSONString(r) }
+
+// IngestActivity runs a complete ingest in a shape suitable for activities of
+// workflow engines such as Temporal.
+//
+// Progress is reported through WithProgress, which makes a natural heartbeat:
+//
+//	c.IngestActivity(ctx, params, maptiler.WithProgress(func(p maptiler.Progress) {
+//		activity.RecordHeartbeat(ctx, p)
+//	}))
+//
+// Retries are idempotent if the IngestID of the last heartbeat is passed in params.
+// An ingest that already reached processing or completed is returned as is, an
+// ingest that is stuck in upload is canceled and started over.
+// Use IsRetryable to decide whether a returned error should fail the activity permanently.
+func (c *Client) IngestActivity(ctx context.Context, params IngestParams, opts ...IngestOption) (IngestResult, error) {
+	if params.IngestID != "" {
+		res, done, err := c.resume(ctx, params.IngestID)
+		if err != nil || done {
+			return res, err
+		}
+	}
+
+	var (
+		ir  IngestResponse
+		err error
+	)
+	if params.DatasetID == "" {
+		ir, err = c.Create(ctx, params.FilePath, opts...)
+	} else {
+		ir, err = c.Update(ctx, params.DatasetID, params.FilePath, opts...)
+	}
+	if err != nil {
+		return IngestResult{}, err
+	}
+
+	return IngestResult{
+		IngestID:   ir.ID,
+		DocumentID: ir.DocumentID,
+		State:      ir.State,
+	}, nil
+}
+
+// resume inspects the ingest of a previous attempt. It reports done if the ingest
+// does not have to be uploaded again, and cancels ingests that are stuck in upload.
+func (c *Client) resume(ctx context.Context, id string) (IngestResult, bool, error) {
+	gr, err := c.Get(ctx, id)
+	if err != nil {
+		var aerr APIError
+		if errors.As(err, &aerr) && aerr.StatusCode == http.StatusNotFound {
+			return IngestResult{}, false, nil
+		}
+		return IngestResult{}, false, err
+	}
+
+	switch gr.State {
+	case StateProcessing, StateCompleted:
+		return IngestResult{
+			IngestID:   gr.ID,
+			DocumentID: gr.DocumentID,
+			State:      gr.State,
+			Resumed:    true,
+		}, true, nil
+	case StateUpload:
+		if _, err := c.cancel(ctx, id); err != nil {
+			return IngestResult{}, false, err
+		}
+	}
+
+	return IngestResult{}, false, nil
+}
@@ -0,0 +1,77 @@
+package maptiler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIngestActivityResumesProcessedIngest(t *testing.T) {
+	t.Parallel()
+
+	var ingestHits int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/datasets/ingest/ing-done", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := json.Marshal(IngestGetResponse{ID: "ing-done", DocumentID: "doc-1", State: "processing"})
+		_, _ = w.Write(b)
+	})
+	mux.HandleFunc("/v1/datasets/ingest", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&ingestHits, 1)
+		http.Error(w, "should not be called", http.StatusBadRequest)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cl, err := New(srv.URL+"/v1", "test-token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	got, err := cl.IngestActivity(t.Context(), IngestParams{FilePath: "ignored", IngestID: "ing-done"})
+	if err != nil {
+		t.Fatalf("IngestActivity() unexpected error: %v", err)
+	}
+	if !got.Resumed || got.IngestID != "ing-done" || got.DocumentID != "doc-1" {
+		t.Fatalf("unexpected result: %+v", got)
+	}
+	if atomic.LoadInt32(&ingestHits) != 0 {
+		t.Fatalf("ingest endpoint should not be called, got %d", ingestHits)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "invalid file", err: fmt.Errorf("wrapped: %w", ErrInvalidFile), want: false},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "unauthorized", err: APIError{StatusCode: http.StatusUnauthorized}, want: false},
+		{name: "too many requests", err: APIError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "server error", err: APIError{StatusCode: http.StatusBadGateway}, want: true},
+		{name: "upload failed wrapping 4xx", err: UploadFailedError{ID: "x", Err: APIError{StatusCode: http.StatusForbidden}}, want: false},
+		{name: "s3 request timeout", err: APIError{StatusCode: http.StatusBadRequest, S3: &S3Error{Code: "RequestTimeout"}}, want: true},
+		{name: "s3 access denied", err: APIError{StatusCode: http.StatusForbidden, S3: &S3Error{Code: "AccessDenied"}}, want: false},
+		{name: "unknown", err: errors.New("connection reset"), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Fatalf("IsRetryable(%v)=%t want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package maptiler
+
+import (
+	"sync"
+	"time"
+)
+
+// progressAggregator merges the progress of several ingests into a single
+// Progress, so a batch can be reported through one ProgressFunc.
+type progressAggregator struct {
+	mu     sync.Mutex
+	fn     ProgressFunc
+	sizes  map[int]int64
+	latest map[int]Progress
+	phase  Phase
+	rate   rateEstimator
+}
+
+func newProgressAggregator(fn ProgressFunc) *progressAggregator {
+	a := &progressAggregator{
+		fn:     fn,
+		sizes:  make(map[int]int64),
+		latest: make(map[int]Progress),
+		phase:  PhaseIngest,
+		rate:   rateEstimator{tau: DefaultRateWindow},
+	}
+	// nothing is sent at the start, so the first report already has a rate.
+	a.rate.add(time.Now(), 0)
+	return a
+}
+
+// track registers an ingest of size bytes under key, e.g. its index in a batch,
+// and returns the ProgressFunc to pass to it. All ingests should be registered before the first one starts,
+// so the combined totals are known upfront.
+func (a *progressAggregator) track(key int, size int64) ProgressFunc {
+	a.mu.Lock()
+	a.sizes[key] = size
+	a.mu.Unlock()
+
+	return func(p Progress) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.latest[key] = p
+		a.phase = p.Phase
+		a.report()
+	}
+}
+
+// remove drops an ingest that failed from the combined totals.
+func (a *progressAggregator) remove(key int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sizes, key)
+	delete(a.latest, key)
+	a.report()
+}
+
+// report sends the combined progress to the ProgressFunc, a.mu must be held.
+func (a *progressAggregator) report() {
+	p := Progress{Phase: a.phase}
+	done := true
+	for key, size := range a.sizes {
+		l, ok := a.latest[key]
+		done = done && ok && l.Phase == PhaseDone
+		p.PartsTotal += l.PartsTotal
+		p.PartsDone += l.PartsDone
+		p.BytesTotal += max(size, l.BytesTotal)
+		p.BytesDone += l.BytesDone
+		p.BytesInFlight += l.BytesInFlight
+	}
+	switch {
+	case done:
+		p.Phase = PhaseDone
+	case p.Phase == PhaseDone:
+		// a single ingest finished, but others are still pending.
+		p.Phase = PhaseUpload
+	}
+
+	sent := p.BytesDone + p.BytesInFlight
+	a.rate.add(time.Now(), sent)
+	p.ETA = etaOf(p.BytesTotal, sent, a.rate.rate())
+	a.fn(p)
+}
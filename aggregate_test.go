@@ -0,0 +1,53 @@
+package maptiler
+
+import "testing"
+
+func TestProgressAggregator(t *testing.T) {
+	t.Parallel()
+
+	var last Progress
+	agg := newProgressAggregator(func(p Progress) { last = p })
+	a := agg.track(0, 100)
+	b := agg.track(1, 300)
+
+	a(Progress{Phase: PhaseUpload, PartsTotal: 2, PartsDone: 1, BytesTotal: 100, BytesDone: 50})
+	if last.BytesTotal != 400 || last.BytesDone != 50 || last.PartsTotal != 2 {
+		t.Fatalf("unexpected progress %+v", last)
+	}
+	if got := last.Percent(); got != 12.5 {
+		t.Fatalf("Percent() = %v, want 12.5", got)
+	}
+	if last.ETA <= 0 {
+		t.Fatalf("expected an ETA, got %v", last.ETA)
+	}
+
+	a(Progress{Phase: PhaseDone, PartsTotal: 2, PartsDone: 2, BytesTotal: 100, BytesDone: 100})
+	if last.Phase != PhaseUpload {
+		t.Fatalf("phase=%q want %q while b is pending", last.Phase, PhaseUpload)
+	}
+
+	b(Progress{Phase: PhaseUpload, PartsTotal: 3, BytesTotal: 300, BytesInFlight: 150})
+	if got := last.Percent(); got != 62.5 {
+		t.Fatalf("Percent() = %v, want 62.5", got)
+	}
+
+	b(Progress{Phase: PhaseDone, PartsTotal: 3, PartsDone: 3, BytesTotal: 300, BytesDone: 300})
+	if last.Phase != PhaseDone || last.Percent() != 100 || last.ETA != 0 {
+		t.Fatalf("unexpected final progress %+v", last)
+	}
+}
+
+func TestProgressAggregatorRemove(t *testing.T) {
+	t.Parallel()
+
+	var last Progress
+	agg := newProgressAggregator(func(p Progress) { last = p })
+	a := agg.track(0, 100)
+	agg.track(1, 300)
+
+	a(Progress{Phase: PhaseDone, BytesTotal: 100, BytesDone: 100})
+	agg.remove(1)
+	if last.Phase != PhaseDone || last.BytesTotal != 100 {
+		t.Fatalf("unexpected progress after remove %+v", last)
+	}
+}
@@ -0,0 +1,78 @@
+package maptiler
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// DefaultAPIVersion is the version of the service API used unless WithAPIVersion
+// is given.
+const DefaultAPIVersion = "v1"
+
+// ErrUnsupportedAPIVersion is returned by New for a version of the service API
+// the client does not know the endpoints of.
+var ErrUnsupportedAPIVersion = errors.New("unsupported API version")
+
+// Endpoint identifies an endpoint of the service API, see WithEndpointPath.
+type Endpoint string
+
+const (
+	EndpointIngestCreate  Endpoint = "ingest_create"
+	EndpointIngestUpdate  Endpoint = "ingest_update"
+	EndpointIngestGet     Endpoint = "ingest_get"
+	EndpointIngestCancel  Endpoint = "ingest_cancel"
+	EndpointIngestProcess Endpoint = "ingest_process"
+	EndpointDatasetGet    Endpoint = "dataset_get"
+	EndpointDatasetDelete Endpoint = "dataset_delete"
+)
+
+// apiPaths are the path templates of the endpoints of a version of the service
+// API. :id is replaced by the ID of the ingest or dataset.
+type apiPaths map[Endpoint]string
+
+// apiVersions are the supported versions of the service API. A new version gets
+// templates of its own, so clients pinned to an older version keep its paths.
+var apiVersions = map[string]apiPaths{
+	"v1": {
+		EndpointIngestCreate:  "/datasets/ingest",
+		EndpointIngestUpdate:  "/datasets/:id/ingest",
+		EndpointIngestGet:     "/datasets/ingest/:id",
+		EndpointIngestCancel:  "/datasets/ingest/:id/cancel",
+		EndpointIngestProcess: "/datasets/ingest/:id/process",
+		EndpointDatasetGet:    "/datasets/:id",
+		EndpointDatasetDelete: "/datasets/:id",
+	},
+}
+
+// SupportedAPIVersions returns the versions of the service API WithAPIVersion accepts.
+func SupportedAPIVersions() []string {
+	return slices.Sorted(maps.Keys(apiVersions))
+}
+
+// pathsOf returns the endpoints of version with overrides applied.
+func pathsOf(version string, overrides map[Endpoint]string) (apiPaths, error) {
+	defaults, ok := apiVersions[version]
+	if !ok {
+		return nil, fmt.Errorf("%w %q, expected one of %s",
+			ErrUnsupportedAPIVersion, version, strings.Join(SupportedAPIVersions(), ", "))
+	}
+
+	paths := maps.Clone(defaults)
+	for e, tmpl := range overrides {
+		def, ok := defaults[e]
+		if !ok {
+			return nil, fmt.Errorf("overriding endpoint %q: unknown endpoint", e)
+		}
+		if !strings.HasPrefix(tmpl, "/") {
+			return nil, fmt.Errorf("overriding endpoint %q: path %q has to start with /", e, tmpl)
+		}
+		if strings.Contains(def, ":id") && !strings.Contains(tmpl, ":id") {
+			return nil, fmt.Errorf("overriding endpoint %q: path %q has to contain :id", e, tmpl)
+		}
+		paths[e] = tmpl
+	}
+	return paths, nil
+}
@@ -0,0 +1,150 @@
+package maptiler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestWithAPIVersion(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New("", "token", WithAPIVersion("v2")); !errors.Is(err, ErrUnsupportedAPIVersion) {
+		t.Fatalf("expected ErrUnsupportedAPIVersion, got %v", err)
+	}
+	if !slices.Contains(SupportedAPIVersions(), DefaultAPIVersion) {
+		t.Fatalf("expected %s to be supported, got %v", DefaultAPIVersion, SupportedAPIVersions())
+	}
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "ds-1"}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL+"/v1", "token", WithAPIVersion("v1"))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if _, err := c.GetDataset(t.Context(), "ds-1"); err != nil {
+		t.Fatalf("GetDataset() unexpected error: %v", err)
+	}
+	if got != "/v1/datasets/ds-1" {
+		t.Fatalf("expected the v1 dataset path, got %s", got)
+	}
+}
+
+func TestAPIVersionsComplete(t *testing.T) {
+	t.Parallel()
+
+	for v, paths := range apiVersions {
+		for _, e := range endpoints {
+			id, _ := e.spec()
+			if paths[id] == "" {
+				t.Errorf("%s: missing path of %s", v, id)
+			}
+		}
+		if len(paths) != len(endpoints) {
+			t.Errorf("%s: expected paths of the %d declared endpoints, got %d", v, len(endpoints), len(paths))
+		}
+	}
+}
+
+func TestWithEndpointPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		endpoint Endpoint
+		path     string
+	}{
+		{name: "unknown endpoint", endpoint: "ingest_delete", path: "/gw/delete/:id"},
+		{name: "relative path", endpoint: EndpointIngestCreate, path: "gw/ingest"},
+		{name: "missing id", endpoint: EndpointIngestCancel, path: "/gw/cancel"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := New("", "token", WithEndpointPath(tt.endpoint, tt.path)); err == nil {
+				t.Fatalf("expected an error for %s %q", tt.endpoint, tt.path)
+			}
+		})
+	}
+}
+
+func TestClientBehindRewritingGateway(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer()
+	defer srv.Close()
+	upstream, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the gateway serves the service API at its root and maps its own paths for
+	// the ingest endpoints.
+	rewrites := map[*regexp.Regexp]string{
+		regexp.MustCompile(`^/gw/create$`):               "/v1/datasets/ingest",
+		regexp.MustCompile(`^/gw/ingests/([^/]+)/done$`): "/v1/datasets/ingest/$1/process",
+		regexp.MustCompile(`^/gw/ingests/([^/]+)/stop$`): "/v1/datasets/ingest/$1/cancel",
+	}
+	var mu sync.Mutex
+	var rewritten int
+	gw := httptest.NewServer(&httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(&url.URL{Scheme: upstream.Scheme, Host: upstream.Host})
+			for re, repl := range rewrites {
+				if re.MatchString(r.In.URL.Path) {
+					r.Out.URL.Path = re.ReplaceAllString(r.In.URL.Path, repl)
+					mu.Lock()
+					rewritten++
+					mu.Unlock()
+					return
+				}
+			}
+			r.Out.URL.Path = "/v1" + r.In.URL.Path
+		},
+	})
+	defer gw.Close()
+
+	c, err := New(gw.URL, "token",
+		WithEndpointPath(EndpointIngestCreate, "/gw/create"),
+		WithEndpointPath(EndpointIngestProcess, "/gw/ingests/:id/done"),
+		WithEndpointPath(EndpointIngestCancel, "/gw/ingests/:id/stop"),
+	)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("tiles"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ir, err := c.Create(t.Context(), fp)
+	if err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+	if IngestState(srv.State(ir.ID)) != StateCompleted {
+		t.Fatalf("expected ingest %s to complete, got %q", ir.ID, IngestState(srv.State(ir.ID)))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if rewritten != 2 {
+		t.Fatalf("expected create and process to go through the gateway paths, got %d", rewritten)
+	}
+}
@@ -0,0 +1,199 @@
+package maptiler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bandwidthChunk is the largest read a limitedReader issues at once. Small reads
+// keep concurrent parts interleaved instead of one part draining the bucket.
+const bandwidthChunk = 32 << 10
+
+// bandwidthLimiter is a token bucket limiting throughput to rate bytes per second.
+// Callers reserve bytes and wait until the bucket refilled, so concurrent callers
+// are served roughly in the order they arrive.
+type bandwidthLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	// schedule changes the rate by the time of day, bps applies outside of its
+	// windows. A rate of 0 is unlimited.
+	schedule BandwidthSchedule
+	bps      int64
+}
+
+// newBandwidthLimiter returns a limiter for bps bytes per second with a burst of
+// one second worth of bytes, or nil if bps is not positive.
+func newBandwidthLimiter(bps int64) *bandwidthLimiter {
+	if bps <= 0 {
+		return nil
+	}
+	return &bandwidthLimiter{
+		rate:   float64(bps),
+		burst:  float64(bps),
+		tokens: float64(bps),
+		last:   time.Now(),
+	}
+}
+
+// newScheduledLimiter returns a limiter for bps bytes per second that follows
+// schedule, or nil if neither limits the bandwidth.
+func newScheduledLimiter(bps int64, schedule BandwidthSchedule) *bandwidthLimiter {
+	if len(schedule) == 0 {
+		return newBandwidthLimiter(bps)
+	}
+	return &bandwidthLimiter{schedule: schedule, bps: max(bps, 0), last: time.Now()}
+}
+
+// setRate switches to bps bytes per second. The bucket is full when the
+// limit starts and never holds more than one second worth of bytes.
+func (l *bandwidthLimiter) setRate(bps int64) {
+	r := float64(bps)
+	if r == l.rate {
+		return
+	}
+	if l.rate <= 0 {
+		l.tokens = r
+	}
+	l.rate, l.burst, l.tokens = r, r, min(l.tokens, r)
+}
+
+// wait blocks until n bytes may be sent or ctx is done. A nil limiter never blocks.
+func (l *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.schedule != nil {
+		l.setRate(l.schedule.at(now, l.bps))
+	}
+	if l.rate <= 0 {
+		l.last = now
+		l.mu.Unlock()
+		return nil
+	}
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	var d time.Duration
+	if l.tokens < 0 {
+		d = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+	return sleep(ctx, d)
+}
+
+// BandwidthWindow limits the bandwidth to BPS bytes per second from Start to
+// End, as times of day in local time, e.g. 6*time.Hour for 06:00. A window
+// with an End before its Start spans midnight, one with an End equal to its
+// Start the whole day. A BPS of 0 is unlimited.
+type BandwidthWindow struct {
+	Start time.Duration
+	End   time.Duration
+	BPS   int64
+}
+
+// contains reports whether the time of day d is in the window.
+func (w BandwidthWindow) contains(d time.Duration) bool {
+	switch {
+	case w.Start == w.End:
+		return true
+	case w.Start < w.End:
+		return d >= w.Start && d < w.End
+	default:
+		return d >= w.Start || d < w.End
+	}
+}
+
+// BandwidthSchedule changes the bandwidth limit by the time of day, see
+// WithBandwidthSchedule. The first window containing a time applies.
+type BandwidthSchedule []BandwidthWindow
+
+// at returns the limit of s at t, or bps if no window contains t.
+func (s BandwidthSchedule) at(t time.Time, bps int64) int64 {
+	h, m, sec := t.Clock()
+	d := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec)*time.Second
+	for _, w := range s {
+		if w.contains(d) {
+			return w.BPS
+		}
+	}
+	return bps
+}
+
+// ParseBandwidthSchedule parses comma separated windows of the form
+// HH:MM-HH:MM=BPS, e.g. "01:00-06:00=0,08:00-18:00=6250000" for no limit at
+// night and 50 Mbit/s during office hours.
+func ParseBandwidthSchedule(v string) (BandwidthSchedule, error) {
+	var s BandwidthSchedule
+	for part := range strings.SplitSeq(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		span, bps, ok := strings.Cut(part, "=")
+		start, end, ok2 := strings.Cut(span, "-")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("parsing bandwidth window %q: expected HH:MM-HH:MM=BPS", part)
+		}
+		var w BandwidthWindow
+		var err error
+		if w.Start, err = parseTimeOfDay(start); err != nil {
+			return nil, fmt.Errorf("parsing bandwidth window %q: %w", part, err)
+		}
+		if w.End, err = parseTimeOfDay(end); err != nil {
+			return nil, fmt.Errorf("parsing bandwidth window %q: %w", part, err)
+		}
+		if w.BPS, err = strconv.ParseInt(strings.TrimSpace(bps), 10, 64); err != nil || w.BPS < 0 {
+			return nil, fmt.Errorf("parsing bandwidth window %q: invalid bytes per second %q", part, bps)
+		}
+		s = append(s, w)
+	}
+	return s, nil
+}
+
+// parseTimeOfDay parses HH:MM into the time since midnight, 24:00 is midnight.
+func parseTimeOfDay(v string) (time.Duration, error) {
+	v = strings.TrimSpace(v)
+	if v == "24:00" {
+		return 0, nil
+	}
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", v)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// limitedReader throttles reads from r through all of its limiters.
+type limitedReader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*bandwidthLimiter
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > bandwidthChunk {
+		p = p[:bandwidthChunk]
+	}
+	n, err := l.r.Read(p)
+	for _, lim := range l.limiters {
+		if werr := lim.wait(l.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
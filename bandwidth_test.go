@@ -0,0 +1,114 @@
+package maptiler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestLimitedReaderThrottles(t *testing.T) {
+	t.Parallel()
+
+	const rate = 1 << 20
+	data := make([]byte, rate+rate/4)
+
+	r := &limitedReader{
+		ctx:      t.Context(),
+		r:        bytes.NewReader(data),
+		limiters: []*bandwidthLimiter{newBandwidthLimiter(rate), nil},
+	}
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, r)
+	if err != nil {
+		t.Fatalf("copy failed: %v", err)
+	}
+	if n != int64(len(data)) {
+		t.Fatalf("read %d bytes, want %d", n, len(data))
+	}
+	// the first second worth of bytes is the burst, the remaining quarter is throttled.
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("expected throttling, copy took %s", elapsed)
+	}
+}
+
+func TestBandwidthLimiterContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	l := newBandwidthLimiter(1)
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if err := l.wait(ctx, 1<<20); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestNilBandwidthLimiter(t *testing.T) {
+	t.Parallel()
+
+	if l := newBandwidthLimiter(0); l != nil {
+		t.Fatalf("expected nil limiter for unlimited bandwidth")
+	}
+	var l *bandwidthLimiter
+	if err := l.wait(t.Context(), 1<<30); err != nil {
+		t.Fatalf("nil limiter should never block, got %v", err)
+	}
+}
+
+func TestParseBandwidthSchedule(t *testing.T) {
+	t.Parallel()
+
+	s, err := ParseBandwidthSchedule("01:00-06:00=0, 22:00-01:00=1000,08:30-18:00=6250000")
+	if err != nil {
+		t.Fatalf("ParseBandwidthSchedule() unexpected error: %v", err)
+	}
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		at   time.Duration
+		want int64
+	}{
+		{0, 1000},
+		{time.Hour, 0},
+		{5*time.Hour + 59*time.Minute, 0},
+		{6 * time.Hour, 42},
+		{8*time.Hour + 30*time.Minute, 6250000},
+		{18 * time.Hour, 42},
+		{23 * time.Hour, 1000},
+	}
+	for _, tt := range tests {
+		if got := s.at(day.Add(tt.at), 42); got != tt.want {
+			t.Errorf("at(%s) = %d, want %d", tt.at, got, tt.want)
+		}
+	}
+
+	for _, v := range []string{"01:00=0", "01:00-06:00", "25:00-06:00=0", "01:00-06:00=-1", "01:00-06:00=fast"} {
+		if _, err := ParseBandwidthSchedule(v); err == nil {
+			t.Errorf("ParseBandwidthSchedule(%q) expected an error", v)
+		}
+	}
+}
+
+func TestScheduledLimiter(t *testing.T) {
+	t.Parallel()
+
+	if l := newScheduledLimiter(0, nil); l != nil {
+		t.Fatalf("expected nil limiter without a limit or schedule")
+	}
+
+	// a window of the whole day without a limit overrides the limit.
+	l := newScheduledLimiter(1, BandwidthSchedule{{BPS: 0}})
+	if err := l.wait(t.Context(), 1<<30); err != nil {
+		t.Fatalf("expected no limit, got %v", err)
+	}
+
+	l = newScheduledLimiter(0, BandwidthSchedule{{BPS: 1}})
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := l.wait(ctx, 1<<20); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the window to limit, got %v", err)
+	}
+}
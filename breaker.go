@@ -0,0 +1,67 @@
+package maptiler
+
+import (
+	"net/url"
+	"sync"
+)
+
+// circuitBreaker tracks consecutive transient part failures per upload host of an
+// ingest. Once a host reached the threshold the circuit opens and all further
+// parts to that host fail immediately. A nil breaker always allows requests.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	failures  map[string]int
+	lastErr   map[string]error
+}
+
+func newCircuitBreaker(threshold int) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &circuitBreaker{
+		threshold: threshold,
+		failures:  make(map[string]int),
+		lastErr:   make(map[string]error),
+	}
+}
+
+// allow returns a CircuitOpenError if the circuit for host is open.
+func (b *circuitBreaker) allow(host string) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n := b.failures[host]; n >= b.threshold {
+		return CircuitOpenError{Host: host, Failures: n, Err: b.lastErr[host]}
+	}
+	return nil
+}
+
+// record counts transient failures for host and resets the count on success.
+func (b *circuitBreaker) record(host string, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		delete(b.failures, host)
+		delete(b.lastErr, host)
+		return
+	}
+	if IsRetryable(err) {
+		b.failures[host]++
+		b.lastErr[host] = err
+	}
+}
+
+// hostOf returns the host of a part upload url.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Host
+}
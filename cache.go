@@ -0,0 +1,87 @@
+package maptiler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// responseCacheSize is the number of responses kept by a responseCache.
+const responseCacheSize = 1000
+
+type cachedResponse struct {
+	etag string
+	body []byte
+}
+
+// responseCache keeps the last response of read endpoints by their ETag, so
+// they can be revalidated with If-None-Match. A nil responseCache caches nothing.
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: make(map[string]cachedResponse)}
+}
+
+// get sends req with t and returns the body of the response. If a response is
+// cached for key it is revalidated, and its body returned if the server responds
+// with 304 Not Modified. Error responses are returned as APIError.
+func (rc *responseCache) get(ctx context.Context, t transport, req request, key string) ([]byte, error) {
+	cached, ok := rc.lookup(key)
+	if ok {
+		req.header = map[string]string{"If-None-Match": cached.etag}
+	}
+
+	resp, err := t.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if ok && resp.StatusCode == http.StatusNotModified {
+		return cached.body, nil
+	}
+	if isError(resp) {
+		return nil, serviceError(newAPIError(resp))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	rc.store(key, resp.Header.Get("ETag"), body)
+	return body, nil
+}
+
+func (rc *responseCache) lookup(key string) (cachedResponse, bool) {
+	if rc == nil {
+		return cachedResponse{}, false
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	r, ok := rc.entries[key]
+	return r, ok
+}
+
+func (rc *responseCache) store(key, etag string, body []byte) {
+	if rc == nil {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if etag == "" {
+		delete(rc.entries, key)
+		return
+	}
+	if _, ok := rc.entries[key]; !ok && len(rc.entries) >= responseCacheSize {
+		// evict an arbitrary entry, watchers revalidate a small set of resources.
+		for k := range rc.entries {
+			delete(rc.entries, k)
+			break
+		}
+	}
+	rc.entries[key] = cachedResponse{etag: etag, body: body}
+}
@@ -0,0 +1,64 @@
+package maptiler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClientConditionalRequests(t *testing.T) {
+	t.Parallel()
+
+	var full, notModified int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&full, 1)
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"id":"ing-1","document_id":"ds-1","state":"processing"}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name            string
+		options         []Option
+		wantFull        int32
+		wantNotModified int32
+	}{
+		{name: "disabled", wantFull: 3},
+		{name: "enabled", options: []Option{WithConditionalRequests()}, wantFull: 1, wantNotModified: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&full, 0)
+			atomic.StoreInt32(&notModified, 0)
+
+			c, err := New(srv.URL, "token", tt.options...)
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+
+			for range 3 {
+				ir, err := c.Get(t.Context(), "ing-1")
+				if err != nil {
+					t.Fatalf("Get() unexpected error: %v", err)
+				}
+				if ir.ID != "ing-1" || ir.State != StateProcessing {
+					t.Fatalf("unexpected response %+v", ir)
+				}
+			}
+
+			if got := atomic.LoadInt32(&full); got != tt.wantFull {
+				t.Fatalf("full responses=%d want %d", got, tt.wantFull)
+			}
+			if got := atomic.LoadInt32(&notModified); got != tt.wantNotModified {
+				t.Fatalf("not modified responses=%d want %d", got, tt.wantNotModified)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package maptiler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const checksumAlgorithmSHA256 = "sha256"
+
+// ErrChecksumUnavailable is returned when the service does not expose a checksum for a dataset.
+var ErrChecksumUnavailable = errors.New("remote checksum unavailable")
+
+// RemoteChecksum returns the checksum of the file stored for a dataset in the
+// form "<algorithm>:<hex>". Checksums reported without an algorithm are assumed
+// to be sha256. It returns ErrChecksumUnavailable if the service does not report one.
+func (c *Client) RemoteChecksum(ctx context.Context, datasetID string) (string, error) {
+	d, err := c.GetDataset(ctx, datasetID)
+	if err != nil {
+		return "", err
+	}
+	if d.Checksum == "" {
+		return "", fmt.Errorf("dataset %s: %w", datasetID, ErrChecksumUnavailable)
+	}
+	return normalizeChecksum(d.Checksum), nil
+}
+
+// FileChecksum returns the sha256 checksum of a local file in the form "sha256:<hex>",
+// which can be compared against RemoteChecksum.
+func FileChecksum(fp string) (string, error) {
+	fp = osPath(fp)
+	if _, err := fileInfo(fp); err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(fp)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file at path '%s': %w", fp, err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing file at path '%s': %w", fp, err)
+	}
+	return checksumAlgorithmSHA256 + ":" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// holdsFile reports whether dataset id holds the file at fp, by its checksum.
+// It is false if the service reports no checksum for the dataset.
+func (c *Client) holdsFile(ctx context.Context, id, fp string) (bool, error) {
+	remote, err := c.RemoteChecksum(ctx, id)
+	if errors.Is(err, ErrChecksumUnavailable) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("comparing checksums: %w", err)
+	}
+	local, err := FileChecksum(fp)
+	if err != nil {
+		return false, fmt.Errorf("comparing checksums: %w", err)
+	}
+	return local == remote, nil
+}
+
+// unchangedResponse is the response of an update skipped by holdsFile.
+func (c *Client) unchangedResponse(id, name string, size int64) IngestResponse {
+	return IngestResponse{
+		DocumentID: id,
+		State:      StateCompleted,
+		Filename:   name,
+		Size:       size,
+		Errors:     []MapTilerError{},
+		Tileset:    c.tileset(StateCompleted, id),
+		Unchanged:  true,
+	}
+}
+
+func normalizeChecksum(sum string) string {
+	sum = strings.ToLower(strings.TrimSpace(sum))
+	if !strings.Contains(sum, ":") {
+		return checksumAlgorithmSHA256 + ":" + sum
+	}
+	return sum
+}
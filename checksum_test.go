@@ -0,0 +1,118 @@
+package maptiler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRemoteChecksumMatchesFileChecksum(t *testing.T) {
+	t.Parallel()
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("abcdefghijklmnopqrstuvwxyz"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/datasets/ds-1", func(w http.ResponseWriter, r *http.Request) {
+		// sha256 of the alphabet, reported upper case and without algorithm
+		_, _ = w.Write([]byte(`{"id":"ds-1","checksum":"71C480DF93D6AE2F1EFAD1447C66C9525E316218CF51FC8D9ED832F2DAF18B73"}`))
+	})
+	mux.HandleFunc("/v1/datasets/ds-2", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"ds-2"}`))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cl, err := New(srv.URL+"/v1", "test-token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	remote, err := cl.RemoteChecksum(t.Context(), "ds-1")
+	if err != nil {
+		t.Fatalf("RemoteChecksum() unexpected error: %v", err)
+	}
+	local, err := FileChecksum(fp)
+	if err != nil {
+		t.Fatalf("FileChecksum() unexpected error: %v", err)
+	}
+	if remote != local {
+		t.Fatalf("remote=%q local=%q, want equal", remote, local)
+	}
+
+	if _, err := cl.RemoteChecksum(t.Context(), "ds-2"); !errors.Is(err, ErrChecksumUnavailable) {
+		t.Fatalf("expected ErrChecksumUnavailable, got %v", err)
+	}
+}
+
+func TestIdempotentUpdate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	same := filepath.Join(dir, "same.pmtiles")
+	if err := os.WriteFile(same, []byte("abcdefghijklmnopqrstuvwxyz"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	changed := filepath.Join(dir, "changed.pmtiles")
+	if err := os.WriteFile(changed, []byte("zyxwvutsrqponmlkjihgfedcba"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var ingests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/datasets/ds-1", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"ds-1","checksum":"71c480df93d6ae2f1efad1447c66c9525e316218cf51fc8d9ed832f2daf18b73"}`))
+	})
+	mux.HandleFunc("/v1/datasets/ds-2", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"ds-2"}`))
+	})
+	mux.HandleFunc("POST /v1/datasets/{id}/ingest", func(w http.ResponseWriter, r *http.Request) {
+		ingests.Add(1)
+		http.Error(w, "stop here", http.StatusTeapot)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cl, err := New(srv.URL+"/v1", "test-token", WithIdempotentUpdates())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ir, err := cl.Update(t.Context(), "ds-1", same)
+	if err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	if !ir.Unchanged || ir.DocumentID != "ds-1" || ir.State != StateCompleted || ir.Tileset == nil {
+		t.Errorf("Update() = %+v, want ds-1 unchanged and completed", ir)
+	}
+	if n := ingests.Load(); n != 0 {
+		t.Fatalf("%d ingests created for an unchanged file", n)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		fp   string
+		opts []IngestOption
+	}{
+		{name: "changed", id: "ds-1", fp: changed},
+		{name: "forced", id: "ds-1", fp: same, opts: []IngestOption{WithForceUpdate()}},
+		{name: "no checksum", id: "ds-2", fp: same},
+	}
+	for _, tt := range tests {
+		before := ingests.Load()
+		if _, err := cl.Update(t.Context(), tt.id, tt.fp, tt.opts...); err == nil {
+			t.Fatalf("%s: Update() expected the ingest to fail", tt.name)
+		}
+		if ingests.Load() != before+1 {
+			t.Errorf("%s: expected the dataset to be updated", tt.name)
+		}
+	}
+}
@@ -3,45 +3,99 @@
 package maptiler
 
 import (
+	"cmp"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"maps"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
 	"os"
 	"slices"
+	"sync"
 	"time"
 
-	"github.com/iwpnd/rip"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 )
 
-const (
-	serviceHost          = "https://service.maptiler.com/v1"
-	serviceIngestUpdate  = "/datasets/:id/ingest"
-	serviceIngestCreate  = "/datasets/ingest"
-	serviceIngestGet     = "/datasets/ingest/:id"
-	serviceIngestCancel  = "/datasets/ingest/:id/cancel"
-	serviceIngestProcess = "/datasets/ingest/:id/process"
-)
+// DefaultServiceHost is the host of the service API New uses if host is empty,
+// without the version.
+const DefaultServiceHost = "https://service.maptiler.com"
 
 // processorFn defines a function type for processing dataset operations.
-// It takes a context, dataset ID, and file path, returning an IngestResponse.
-type processorFn func(context.Context, string, string) (IngestResponse, error)
+// It takes a context, dataset ID, file path and call configuration, returning an IngestResponse.
+type processorFn func(context.Context, string, string, ingestConfig) (IngestResponse, error)
 
 // Client provides methods for interacting with the MapTiler service API.
 // It manages HTTP requests and concurrent file uploads.
 type Client struct {
-	h  *rip.Client
-	wp *pool[uploadTask]
+	h           transport
+	paths       apiPaths
+	tiles       transport
+	tilesHost   string
+	up          processor[uploadTask, uploadTaskResponse]
+	concurrency int
+	uploadHosts hostAllowlist
+	// inFlight limits the bytes of parts uploaded at the same time, 0 is unlimited.
+	inFlight int64
+	lockDir  string
+	cache    *responseCache
+	// apiLimit is a token bucket of one token per call to the service API.
+	apiLimit *bandwidthLimiter
+	gets     singleflight.Group
+	// retry is applied to calls to the service API that are safe to repeat.
+	retry RetryPolicy
+	log   *slog.Logger
+	// src reads the sources of CreateFromURL.
+	src *http.Client
+	// metadata is attached to every ingest, see WithDefaultMetadata.
+	metadata map[string]string
+	// idempotent skips updates with the file of the dataset, see WithIdempotentUpdates.
+	idempotent bool
+	// state seals the journals of uploads, see WithStateCipher.
+	state *StateCipher
+	// timeout limits requests to the service API, see WithTimeout.
+	timeout time.Duration
+	// readOnly rejects calls other than GET, see WithReadOnly.
+	readOnly bool
 }
 
 // New creates a new MapTiler client with the specified host and authentication token.
-// If host is empty, it defaults to the MapTiler service host.
+// If host is empty, it defaults to the MapTiler service host and the version of
+// WithAPIVersion. A host that is given has to include the version, e.g. /v1.
 // If token is empty, it attempts to read from the MAPTILER_TOKEN environment variable.
-func New(host, token string) (*Client, error) {
+func New(host, token string, options ...Option) (*Client, error) {
+	config := &clientConfig{
+		etagExtractor:  HeaderETag,
+		etagNormalizer: NormalizeETag,
+		tilesHost:      tilesHost,
+		lockDir:        defaultLockDir(),
+		concurrency:    defaultConcurrency,
+		apiVersion:     DefaultAPIVersion,
+		partHeaders:    DefaultPartResponseHeaders,
+		retry:          defaultRetryPolicy,
+	}
+	for _, o := range options {
+		o(config)
+	}
+	if config.concurrency <= 0 {
+		config.concurrency = defaultConcurrency
+	}
+	if config.wrapTransport != nil {
+		var rt http.RoundTripper = http.DefaultTransport
+		if config.transport != nil {
+			rt = config.transport
+		}
+		config.transport = asHTTPTransport(config.wrapTransport(rt))
+	}
+
+	paths, err := pathsOf(config.apiVersion, config.endpoints)
+	if err != nil {
+		return nil, fmt.Errorf("initializing maptiler client: %w", err)
+	}
+
 	tok := token
 	if tok == "" {
 		tok = os.Getenv("MAPTILER_TOKEN")
@@ -52,7 +106,7 @@ func New(host, token string) (*Client, error) {
 
 	var addr string
 	if host == "" {
-		addr = serviceHost
+		addr = DefaultServiceHost + "/" + config.apiVersion
 	} else {
 		addr = host
 	}
@@ -61,122 +115,357 @@ func New(host, token string) (*Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("initializing maptiler client: %w", err)
 	}
-	h, err := rip.NewClient(
-		addr,
-		rip.WithCookieJar(jar),
-		rip.WithDefaultHeaders(map[string]string{
-			"Authorization": "Token " + tok,
-		}),
-	)
+	h, err := newRipTransport(transportConfig{
+		host:      addr,
+		transport: config.transport,
+		timeout:   config.timeout,
+		header:    map[string]string{"Authorization": "Token " + tok},
+		jar:       jar,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	tr := &http.Transport{
-		IdleConnTimeout: 30 * time.Second,
-		MaxIdleConns:    10,
+	tr := config.transport
+	if tr == nil {
+		tr = &http.Transport{
+			IdleConnTimeout: 30 * time.Second,
+			MaxIdleConns:    10,
+		}
 	}
 
 	// initialize with empty host, as part uris are provided later.
-	wc, err := rip.NewClient("", rip.WithTransport(tr))
+	wc, err := newRipTransport(transportConfig{
+		transport: newRedirectTransport(tr, config.redirects, newHostAllowlist(config.uploadHosts), config.logger),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("initializing worker http client: %w", err)
 	}
 
-	wp := newPool(newUploadProcessor(wc), withPoolConcurrency(10))
-	return &Client{wp: wp, h: h}, nil
+	// the tiles API is authorized by an API key per request, not by the token.
+	tc, err := newRipTransport(transportConfig{
+		host:      config.tilesHost,
+		transport: config.transport,
+		timeout:   config.timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("initializing tiles http client: %w", err)
+	}
+
+	var cache *responseCache
+	if config.conditional {
+		cache = newResponseCache()
+	}
+
+	return &Client{
+		h:           h,
+		paths:       paths,
+		tiles:       tc,
+		tilesHost:   config.tilesHost,
+		up:          newUploadProcessor(wc, config),
+		src:         &http.Client{Transport: tr, Timeout: config.timeout},
+		concurrency: config.concurrency,
+		inFlight:    config.inFlightBytes,
+		uploadHosts: newHostAllowlist(config.uploadHosts),
+		lockDir:     config.lockDir,
+		cache:       cache,
+		apiLimit:    newBandwidthLimiter(int64(config.apiRateLimit)),
+		retry:       config.retry,
+		log:         config.logger,
+		metadata:    config.metadata,
+		idempotent:  config.idempotent,
+		state:       config.state,
+		timeout:     config.timeout,
+		readOnly:    config.readOnly,
+	}, nil
 }
 
 // Create initiates a new dataset ingestion process with the specified file.
 // It uploads the file and processes it, returning the ingestion response.
-func (c *Client) Create(ctx context.Context, fp string) (IngestResponse, error) {
+func (c *Client) Create(ctx context.Context, fp string, opts ...IngestOption) (IngestResponse, error) {
 	return c.withCancel(
 		ctx,
 		c.process,
 		"", fp,
+		newIngestConfig(opts...),
 	)
 }
 
 // Update updates an existing dataset with the specified ID using the provided file.
 // It uploads the file and processes it, returning the ingestion response.
-func (c *Client) Update(ctx context.Context, id, fp string) (IngestResponse, error) {
+func (c *Client) Update(ctx context.Context, id, fp string, opts ...IngestOption) (IngestResponse, error) {
 	return c.withCancel(
 		ctx,
 		c.process,
 		id, fp,
+		newIngestConfig(opts...),
 	)
 }
 
+// CreateAll creates a new dataset for every file in fps, one after another. A
+// ProgressFunc registered with WithProgress receives the combined progress of
+// all files, with BytesTotal covering the files that are not started yet.
+// Files are ingested by their Priority, see WithFilePriorities, and in the order
+// of fps within a class. A failed file does not stop the batch, the responses
+// are returned in the order of fps and a FileError for every failed file is
+// joined into the error.
+func (c *Client) CreateAll(ctx context.Context, fps []string, opts ...IngestOption) ([]IngestResponse, error) {
+	cfg := newIngestConfig(opts...)
+
+	progress := make([]ProgressFunc, len(fps))
+	var agg *progressAggregator
+	if cfg.progress != nil {
+		agg = newProgressAggregator(cfg.progress)
+		for i, fp := range fps {
+			var size int64
+			if info, err := os.Stat(osPath(fp)); err == nil {
+				size = info.Size()
+			}
+			progress[i] = agg.track(i, size)
+		}
+	}
+
+	resps := make([]IngestResponse, len(fps))
+	var errs []error
+	base := cfg
+	for _, i := range byPriority(fps, base) {
+		fp := fps[i]
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		cfg.progress = progress[i]
+		cfg.priority = base.priorityOf(fp)
+		ir, err := c.withCancel(ctx, c.process, "", fp, cfg)
+		if err != nil {
+			if agg != nil {
+				agg.remove(i)
+			}
+			errs = append(errs, FileError{Path: fp, Err: err})
+		}
+		resps[i] = ir
+	}
+
+	return resps, errors.Join(errs...)
+}
+
 // Cancel sends a cancellation request to the MapTiler service for the specified ingest/dataset ID.
 func (c *Client) Cancel(ctx context.Context, id string) (IngestResponse, error) {
 	return c.cancel(ctx, id)
 }
 
-// Get returns an active upload by ID.
+// partRetryPolicy returns the policy for the parts of an ingest. The Client's
+// policy applies unless retries were configured for the ingest.
+func (c *Client) partRetryPolicy(cfg ingestConfig) RetryPolicy {
+	if cfg.partRetry == nil && !cfg.partRetriesSet {
+		return c.retry
+	}
+	return cfg.partRetry
+}
+
+// Get returns an active upload by ID. Concurrent calls for the same ID share a
+// single request. The shared request is not canceled with the context of the
+// call that issued it, only by the timeout of the Client, so every call only
+// stops waiting once its own context is done.
 func (c *Client) Get(ctx context.Context, id string) (IngestGetResponse, error) {
-	req := c.h.NR().SetParams(rip.Params{"id": id})
-	resp, err := req.Execute(ctx, "GET", serviceIngestGet)
+	ch := c.gets.DoChan(id, func() (any, error) {
+		fctx := context.WithoutCancel(ctx)
+		if c.timeout > 0 {
+			var cancel context.CancelFunc
+			fctx, cancel = context.WithTimeout(fctx, c.timeout)
+			defer cancel()
+		}
+		return withRetry(fctx, c.retry, func() (IngestGetResponse, error) {
+			return c.get(fctx, id)
+		})
+	})
+	select {
+	case <-ctx.Done():
+		return IngestGetResponse{}, fmt.Errorf("getting upload: %w", ctx.Err())
+	case r := <-ch:
+		ir, ok := r.Val.(IngestGetResponse)
+		if !ok {
+			return IngestGetResponse{}, r.Err
+		}
+		return ir, r.Err
+	}
+}
+
+// get fetches an upload by ID.
+func (c *Client) get(ctx context.Context, id string) (IngestGetResponse, error) {
+	ir, err := ingestGet.call(ctx, c, id, nil)
 	if err != nil {
-		return IngestGetResponse{}, fmt.Errorf("getting upload: %w", err)
+		return ir, fmt.Errorf("getting upload: %w", err)
 	}
-	defer resp.Close() //nolint:errcheck
+	return ir, nil
+}
+
+// GetDataset returns a dataset by ID.
+func (c *Client) GetDataset(ctx context.Context, id string) (Dataset, error) {
+	return withRetry(ctx, c.retry, func() (Dataset, error) {
+		return c.getDataset(ctx, id)
+	})
+}
 
-	if resp.IsError() {
-		return IngestGetResponse{}, fmt.Errorf("getting upload: %w", err)
+// Delete deletes the dataset with the given ID and its tileset, e.g. to clean
+// up the throwaway datasets of integration tests. It cannot be undone. A
+// dataset that does not exist fails with an APIError with status 404.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	_, err := withRetry(ctx, c.retry, func() (none, error) {
+		_, err := datasetDelete.send(ctx, c, id)
+		return none{}, err
+	})
+	if err != nil {
+		return fmt.Errorf("deleting dataset: %w", err)
 	}
+	return nil
+}
 
-	var ir IngestGetResponse
-	uerr := json.Unmarshal(resp.Body(), &ir)
-	if uerr != nil {
-		return ir, fmt.Errorf("getting upload: %w", uerr)
+// getDataset fetches a dataset by ID.
+func (c *Client) getDataset(ctx context.Context, id string) (Dataset, error) {
+	d, err := datasetGet.call(ctx, c, id, nil)
+	if err != nil {
+		return d, fmt.Errorf("getting dataset: %w", err)
 	}
 
-	return ir, err
+	return d, nil
 }
 
 // process handles the complete ingestion workflow: file validation, ingestion request,
 // upload, and finalization. It returns an IngestResponse or an error.
-func (c *Client) process(ctx context.Context, id, fp string) (IngestResponse, error) {
-	info, err := fileInfo(fp)
+func (c *Client) process(ctx context.Context, id, fp string, cfg ingestConfig) (IngestResponse, error) {
+	fp = osPath(fp)
+	info, err := checkFile(fp, cfg)
 	if err != nil {
 		return IngestResponse{}, err
 	}
 
-	req := newIngestRequest(id, info.Name(), info.Size())
+	if cfg.lock {
+		unlock, err := lockFile(fp)
+		if err != nil {
+			return IngestResponse{}, err
+		}
+		defer unlock() //nolint:errcheck
+	}
+
+	name := cfg.filename(info.Name())
+	create := id == ""
+	if create {
+		if id, err = c.checkDuplicate(ctx, name, cfg); err != nil {
+			return IngestResponse{}, err
+		}
+	}
+
+	if !create && c.idempotent && !cfg.force {
+		same, err := c.holdsFile(ctx, id, fp)
+		if err != nil {
+			return IngestResponse{}, err
+		}
+		if same {
+			logger(c.log).Debug("update skipped, the dataset holds the file", "dataset_id", id, "file", fp)
+			return c.unchangedResponse(id, name, info.Size()), nil
+		}
+	}
+
+	if id != "" {
+		release, err := c.guardDataset(ctx, id, cfg)
+		if err != nil {
+			return IngestResponse{}, err
+		}
+		defer release()
+	}
+
+	req := newIngestRequest(id, name, info.Size())
+	req.Metadata = c.metadataOf(cfg)
+	req.PartSize = cfg.partSize
 	resp, err := c.ingest(ctx, req)
 	if err != nil {
 		return resp, err
 	}
+	log := logger(c.log).With("ingest_id", resp.ID)
+	log.Debug("ingest created",
+		"dataset_id", resp.DocumentID, "file", fp, "filename", name, "size", resp.Size,
+		"parts", len(resp.Upload.Parts), "part_size", resp.Upload.PartSize,
+	)
 
-	uresp, err := c.upload(ctx, resp, fp)
+	if id != "" && cfg.conflictCheck {
+		if err := recordIngest(c.lockDir, id, resp.ID); err != nil {
+			logger(c.log).Warn("conflict check of the next update will be skipped", "dataset_id", id, "error", err)
+		}
+	}
+
+	j, err := c.startJournal(cfg, resp, fp, info)
+	if err != nil {
+		return IngestResponse{}, UploadFailedError{ID: resp.ID, Err: err}
+	}
+	presp, err := c.finish(ctx, resp, fp, cfg, j, nil)
 	if err != nil {
-		return IngestResponse{}, UploadFailedError{
-			ID:  resp.ID,
-			Err: err,
+		return IngestResponse{}, err
+	}
+
+	if create {
+		c.recordCreate(name, presp.DocumentID, cfg)
+	}
+
+	return presp, nil
+}
+
+// finish uploads the parts of resp that are not in done, records them in j and
+// finalizes the ingest. Failures are returned as UploadFailedError.
+func (c *Client) finish(ctx context.Context, resp IngestResponse, fp string, cfg ingestConfig, j *journal, done map[int64]string) (IngestResponse, error) {
+	log := logger(c.log).With("ingest_id", resp.ID)
+	fail := func(err error) (IngestResponse, error) {
+		uerr := UploadFailedError{ID: resp.ID, Err: err}
+		if j != nil {
+			j.close()
+			uerr.Journal = j.path
 		}
+		return IngestResponse{}, uerr
+	}
+
+	pt := newProgressTracker(cfg.progress)
+	parts, size := pendingSize(resp, done)
+	pt.start(resp.ID, parts, size)
+
+	opts := c.uploadOptions(cfg, pt)
+	opts.done = done
+	opts.journal = j
+	uresp, err := c.upload(ctx, resp, fp, opts)
+	if err != nil {
+		return fail(err)
 	}
 
+	log.Debug("upload finished",
+		"parts", uresp.Stats.Parts, "retries", uresp.Stats.Retries, "duration", uresp.Stats.Duration,
+	)
+
+	pt.phase(PhaseFinalize)
+	j.finalizing()
 	presp, err := c.finalize(ctx, uresp)
 	if err != nil {
-		return IngestResponse{}, UploadFailedError{
-			ID:  resp.ID,
-			Err: err,
+		if isRejected(err) {
+			j.finalizeRejected()
 		}
+		return fail(err)
 	}
-
+	log.Debug("ingest finalized", "dataset_id", presp.DocumentID, "state", presp.State)
+	j.remove()
+	pt.phase(PhaseDone)
+	presp.Stats = uresp.Stats
+	presp.Tileset = c.tileset(presp.State, presp.DocumentID)
 	return presp, nil
 }
 
 // withCancel wraps the processFn and automatically cancels the upload with the MapTiler
 // service API if an UploadFailedError occurs during processing.
-func (c *Client) withCancel(ctx context.Context, run processorFn, id, fp string) (IngestResponse, error) {
-	ir, err := run(ctx, id, fp)
+func (c *Client) withCancel(ctx context.Context, run processorFn, id, fp string, cfg ingestConfig) (IngestResponse, error) {
+	ir, err := run(ctx, id, fp, cfg)
 	if err == nil {
 		return ir, nil
 	}
 
 	var uerr UploadFailedError
-	if errors.As(err, &uerr) {
+	if errors.As(err, &uerr) && uerr.Journal == "" {
 		if ir, cerr := c.cancel(ctx, uerr.ID); cerr != nil {
 			return ir, fmt.Errorf("upload failed: %w; cancel failed: %w", err, cerr)
 		}
@@ -188,40 +477,101 @@ func (c *Client) withCancel(ctx context.Context, run processorFn, id, fp string)
 // cancel sends a cancellation request to the MapTiler service for the specified dataset ID.
 // It returns the final ingestion response after cancellation.
 func (c *Client) cancel(ctx context.Context, id string) (IngestResponse, error) {
-	req := c.h.NR().SetParams(rip.Params{"id": id})
-	resp, err := req.Execute(ctx, "POST", serviceIngestCancel)
+	return withRetry(ctx, c.retry, func() (IngestResponse, error) {
+		return c.sendCancel(ctx, id)
+	})
+}
+
+// sendCancel sends a single cancellation request.
+func (c *Client) sendCancel(ctx context.Context, id string) (IngestResponse, error) {
+	ir, err := ingestCancel.call(ctx, c, id, nil)
 	if err != nil {
-		return IngestResponse{}, fmt.Errorf("canceling upload: %w", err)
+		return ir, fmt.Errorf("canceling upload: %w", err)
 	}
-	defer resp.Close() //nolint:errcheck
+	return ir, nil
+}
 
-	if resp.IsError() {
-		return IngestResponse{}, fmt.Errorf("canceling upload: %w", err)
-	}
+// uploadOptions are passed on to every part of an upload.
+type uploadOptions struct {
+	// first is the position of the first of the parts in the ingest, when only
+	// some of them are uploaded, see UploadAssignment.
+	first int
+	// done are the ETags of parts uploaded before, which are skipped, see Resume.
+	done          map[int64]string
+	journal       *journal
+	priority      Priority
+	progress      *progressTracker
+	limit         *bandwidthLimiter
+	partBandwidth int64
+	retries       int
+	retry         RetryPolicy
+	budget        *retryBudget
+	breaker       *circuitBreaker
+	control       *UploadControl
+	reader        io.ReaderAt
+	drain         time.Duration
+}
 
-	var ir IngestResponse
-	uerr := json.Unmarshal(resp.Body(), &ir)
-	if uerr != nil {
-		return ir, fmt.Errorf("canceling upload: %w", uerr)
+// uploadOptions returns the options of an upload with the limits of cfg.
+func (c *Client) uploadOptions(cfg ingestConfig, pt *progressTracker) uploadOptions {
+	return uploadOptions{
+		priority:      cfg.priority,
+		progress:      pt,
+		limit:         newScheduledLimiter(cfg.bandwidth, cfg.schedule),
+		partBandwidth: cfg.partBandwidth,
+		retries:       cfg.partRetries,
+		retry:         c.partRetryPolicy(cfg),
+		budget:        newRetryBudget(cfg.retryMax, cfg.retryMaxTime),
+		breaker:       newCircuitBreaker(cfg.breaker),
+		control:       cfg.control,
+		reader:        cfg.reader,
+		drain:         cfg.drain,
 	}
-
-	return ir, err
 }
 
 // upload handles concurrent multipart file upload using the upload URLs provided
 // in the IngestResponse. It returns an UploadResult containing all part responses.
-func (c *Client) upload(ctx context.Context, ir IngestResponse, fp string) (UploadResult, error) {
+func (c *Client) upload(ctx context.Context, ir IngestResponse, fp string, opts uploadOptions) (UploadResult, error) {
+	if err := c.uploadHosts.check(ir.Upload.Parts); err != nil {
+		return UploadResult{}, err
+	}
+
+	start := time.Now()
 	parts := ir.Upload.Parts
 	partSize := ir.Upload.PartSize
 	fileSize := ir.Size
 
-	respCh := make(chan uploadTaskResponse, len(parts))
-	results := make(map[string]uploadTaskResponse)
+	results := newPartResults(parts)
+	for id, etag := range opts.done {
+		results.add(uploadTaskResponse{PartID: id, ETag: etag})
+	}
+
+	// every upload gets its own pool, a pool can not be restarted once stopped.
+	concurrency := opts.priority.concurrency(c.partConcurrency(partSize))
+	warn := warnBackpressure(logger(c.log), ir.ID, concurrency)
+	wp := newPool(
+		c.up,
+		withPoolConcurrency(concurrency),
+		withPoolBackpressure(backpressureWarnAfter, func(s poolStats) {
+			// the workers of a paused upload wait on purpose.
+			if !opts.control.Paused() {
+				warn(s)
+			}
+		}),
+	)
+	wp.Listen(joinListeners(partProgressListener(opts.progress), partLogListener(c.log, ir.ID)))
 
 	eg, gctx := errgroup.WithContext(ctx)
+	poolCtx := gctx
+	var stopDrain func() bool
+	if opts.drain > 0 {
+		// in-flight parts are given opts.drain to finish once ctx is canceled.
+		poolCtx = context.WithoutCancel(gctx)
+		stopDrain = drainOnCancel(ctx, wp, opts.drain)
+	}
+
 	eg.Go(func() error {
-		defer close(respCh)
-		if wErr := c.wp.Start(gctx); wErr != nil {
+		if wErr := wp.Start(poolCtx); wErr != nil {
 			return fmt.Errorf("processing worker pool: %w", wErr)
 		}
 		return nil
@@ -229,50 +579,186 @@ func (c *Client) upload(ctx context.Context, ir IngestResponse, fp string) (Uplo
 
 	eg.Go(func() error {
 		for i, p := range parts {
-			offset, length := getRange(int64(i), partSize, fileSize)
+			offset, length := getRange(int64(opts.first+i), partSize, fileSize)
 			if length <= 0 {
 				break
 			}
-			c.wp.Enqueue(newTask(uploadTask{
+			if _, ok := opts.done[p.PartID]; ok {
+				continue
+			}
+			// the workers may have returned on a failed part, leaving nobody to
+			// take parts from a full queue.
+			err := wp.Submit(poolCtx, newTask(uploadTask{
 				uploadPart: uploadPart{
 					PartID: p.PartID,
 					URL:    p.URL,
 				},
-				FilePath: fp,
-				RespCh:   respCh,
-				Offset:   offset,
-				Length:   length,
+				IngestID:  ir.ID,
+				FilePath:  fp,
+				Reader:    opts.reader,
+				Progress:  opts.progress,
+				Limit:     opts.limit,
+				PartLimit: opts.partBandwidth,
+				Retries:   opts.retries,
+				Retry:     opts.retry,
+				Budget:    opts.budget,
+				Breaker:   opts.breaker,
+				Control:   opts.control,
+				Offset:    offset,
+				Length:    length,
 			}))
+			if err != nil {
+				break
+			}
 		}
-		c.wp.Stop()
+		wp.Stop()
 		return nil
 	})
 
 	eg.Go(func() error {
-		for r := range respCh {
-			results[fmt.Sprint(r.PartID)] = r
+		for r := range wp.Results() {
+			results.add(r.Body)
+			opts.journal.add(r.Body)
 		}
 		return nil
 	})
 
-	if gErr := eg.Wait(); gErr != nil {
-		return UploadResult{}, fmt.Errorf("waiting for error group to finish: %w", gErr)
+	gErr := eg.Wait()
+	if stopDrain != nil && stopDrain() && results.received < len(parts) {
+		return UploadResult{}, phaseTimeout(ctx, PhaseUpload, start, 0, fmt.Errorf(
+			"upload interrupted, %d of %d parts abandoned: %w",
+			len(parts)-results.received, len(parts), context.Cause(ctx),
+		))
+	}
+	if gErr != nil {
+		return UploadResult{}, phaseTimeout(ctx, PhaseUpload, start, 0, fmt.Errorf("waiting for error group to finish: %w", gErr))
+	}
+
+	responses := results.list()
+	ur := newUploadResult(ir.ID, responses)
+	retries, retryTime := opts.budget.consumed()
+	ur.Stats = UploadStats{
+		Parts:       len(responses),
+		Retries:     retries,
+		RetryTime:   retryTime,
+		Duration:    time.Since(start),
+		EnqueueWait: wp.Stats().EnqueueWait,
+		PartStats:   partStats(responses),
 	}
 
-	seq := maps.Values(results)
-	responses := slices.Collect(seq)
+	return ur, nil
+}
+
+// partResults collects the responses of the parts of an upload in a slice
+// indexed by part ID, as the service numbers parts from 1 in order. Other
+// numberings are mapped to the position of the part.
+type partResults struct {
+	responses []uploadTaskResponse
+	// slots maps part IDs to positions, nil if parts are numbered from 1 in order.
+	slots    map[int64]int
+	received int
+}
 
-	slices.SortFunc(responses, func(a, b uploadTaskResponse) int {
-		if a.PartID < b.PartID {
-			return -1
+func newPartResults(parts uploadParts) *partResults {
+	r := &partResults{responses: make([]uploadTaskResponse, len(parts))}
+	for i, p := range parts {
+		if p.PartID == int64(i+1) {
+			continue
 		}
-		if a.PartID > b.PartID {
-			return 1
+		r.slots = make(map[int64]int, len(parts))
+		for j, p := range parts {
+			r.slots[p.PartID] = j
 		}
-		return 0
-	})
+		break
+	}
+	return r
+}
 
-	return newUploadResult(ir.ID, responses), nil
+// add records the response of a part, responses of unknown parts are dropped.
+func (r *partResults) add(resp uploadTaskResponse) {
+	i := int(resp.PartID - 1)
+	if r.slots != nil {
+		j, ok := r.slots[resp.PartID]
+		if !ok {
+			return
+		}
+		i = j
+	}
+	if i < 0 || i >= len(r.responses) {
+		return
+	}
+	if r.responses[i].PartID == 0 {
+		r.received++
+	}
+	r.responses[i] = resp
+}
+
+// list returns the received responses ordered by part ID.
+func (r *partResults) list() []uploadTaskResponse {
+	responses := r.responses
+	if r.received < len(responses) {
+		responses = make([]uploadTaskResponse, 0, r.received)
+		for _, resp := range r.responses {
+			if resp.PartID != 0 {
+				responses = append(responses, resp)
+			}
+		}
+	}
+	if r.slots != nil {
+		slices.SortFunc(responses, func(a, b uploadTaskResponse) int {
+			return cmp.Compare(a.PartID, b.PartID)
+		})
+	}
+	return responses
+}
+
+// backpressureWarnAfter is how long a part may wait for a free upload worker
+// before upload warns that the workers do not keep up.
+const backpressureWarnAfter = 30 * time.Second
+
+// warnBackpressure returns a backpressure handler that logs a warning once.
+func warnBackpressure(log *slog.Logger, id string, workers int) func(poolStats) {
+	var once sync.Once
+	return func(s poolStats) {
+		once.Do(func() {
+			log.Warn(
+				"upload workers do not keep up, check concurrency and bandwidth limits",
+				"ingest_id", id,
+				"workers", workers,
+				"queue_depth", s.QueueDepth,
+				"enqueue_wait", s.MaxEnqueueWait,
+			)
+		})
+	}
+}
+
+// drainOnCancel stops wp with a deadline of d once ctx is canceled. The returned
+// function stops watching ctx and reports whether wp was stopped.
+func drainOnCancel[In, Out any](ctx context.Context, wp *pool[In, Out], d time.Duration) func() bool {
+	finished := make(chan struct{})
+	stopped := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			wp.StopWithDeadline(d)
+			stopped <- true
+		case <-finished:
+			stopped <- false
+		}
+	}()
+	return func() bool {
+		close(finished)
+		return <-stopped
+	}
+}
+
+// partConcurrency returns the number of parts of partSize to upload at the same
+// time within the in-flight budget of the Client.
+func (c *Client) partConcurrency(partSize int64) int {
+	if c.inFlight <= 0 || partSize <= 0 {
+		return c.concurrency
+	}
+	return int(min(max(c.inFlight/partSize, 1), int64(c.concurrency)))
 }
 
 // getRange calculates the byte offset and length for a specific part in a multipart upload.
@@ -296,35 +782,29 @@ func getRange(idx, partSize, fileSize int64) (off, length int64) {
 // ingest sends an ingestion request to the MapTiler service, either creating a new
 // dataset or updating an existing one based on the request ID.
 func (c *Client) ingest(ctx context.Context, request ingestRequest) (IngestResponse, error) {
-	req := c.h.NR()
-	var url string
+	e := ingestCreate
 	if request.ID != "" {
-		url = serviceIngestUpdate
-		req.SetParams(rip.Params{"id": request.ID})
-	} else {
-		url = serviceIngestCreate
+		e = ingestUpdate
 	}
-
-	resp, err := req.SetBody(request).Execute(ctx, "POST", url)
+	start := time.Now()
+	ir, err := withRetry(ctx, onlyRejected(c.retry), func() (IngestResponse, error) {
+		return e.call(ctx, c, request.ID, &request)
+	})
 	if err != nil {
-		return IngestResponse{}, err
-	}
-	defer resp.Close() //nolint:errcheck
-
-	if resp.IsError() {
-		return IngestResponse{}, fmt.Errorf("request failed with %d", resp.StatusCode())
-	}
-
-	var ir IngestResponse
-	uerr := json.Unmarshal(resp.Body(), &ir)
-	if uerr != nil {
-		return IngestResponse{}, uerr
+		return IngestResponse{}, phaseTimeout(ctx, PhaseIngest, start, c.timeout, err)
 	}
 
 	// we something goes wrong at this point we force a cancel
-	if ir.State == "failed" {
+	if ir.State == StateFailed {
 		return IngestResponse{}, UploadFailedError{ID: ir.ID}
 	}
+	if err := checkUpload(request, ir); err != nil {
+		return IngestResponse{}, UploadFailedError{ID: ir.ID, Err: err}
+	}
+	if request.PartSize > 0 && ir.Upload.PartSize != request.PartSize {
+		logger(c.log).Debug("service chose another part size",
+			"ingest_id", ir.ID, "part_size", ir.Upload.PartSize, "requested", request.PartSize)
+	}
 
 	return ir, nil
 }
@@ -332,25 +812,25 @@ func (c *Client) ingest(ctx context.Context, request ingestRequest) (IngestRespo
 // finalize completes the ingestion process by sending the upload results to the
 // MapTiler service for final processing.
 func (c *Client) finalize(ctx context.Context, ur UploadResult) (IngestResponse, error) {
-	req := c.h.NR().SetBody(uploadResultRequest{UploadResult: ur}).SetParams(rip.Params{"id": ur.ID})
-	resp, err := req.Execute(ctx, "POST", serviceIngestProcess)
+	start := time.Now()
+	body, err := withRetry(ctx, onlyRejected(c.retry), func() ([]byte, error) {
+		return ingestProcess.do(ctx, c, ur.ID, &uploadResultRequest{UploadResult: ur})
+	})
 	if err != nil {
-		return IngestResponse{}, UploadFailedError{ID: ur.ID}
-	}
-	defer resp.Close() //nolint:errcheck
-
-	if resp.IsError() {
-		return IngestResponse{}, fmt.Errorf("request failed with %d", resp.StatusCode())
+		var aerr APIError
+		if errors.As(err, &aerr) {
+			return IngestResponse{}, serviceError(aerr)
+		}
+		return IngestResponse{}, UploadFailedError{ID: ur.ID, Err: phaseTimeout(ctx, PhaseFinalize, start, c.timeout, err)}
 	}
 
-	var ir IngestResponse
-	uerr := json.Unmarshal(resp.Body(), &ir)
-	if uerr != nil {
-		return IngestResponse{}, uerr
+	ir, err := ingestProcess.decode(body)
+	if err != nil {
+		return IngestResponse{}, err
 	}
 
 	// we something goes wrong at this point we force a cancel
-	if ir.State == "failed" {
+	if ir.State == StateFailed {
 		return IngestResponse{}, UploadFailedError{ID: ur.ID}
 	}
 
@@ -361,13 +841,14 @@ func (c *Client) finalize(ctx context.Context, ur UploadResult) (IngestResponse,
 // It returns the file information or an error if validation fails.
 func fileInfo(fp string) (os.FileInfo, error) {
 	info, err := os.Stat(fp)
-	if err == nil {
-		if info.IsDir() {
-			return nil, fmt.Errorf("expected file %q to exist, but it is a directory", fp)
-		}
-	}
 	if os.IsNotExist(err) {
-		return nil, fmt.Errorf("expected file %q to exist, but it does not", fp)
+		return nil, fmt.Errorf("expected file %q to exist, but it does not: %w", fp, ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading file %q: %w", fp, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("expected file %q to exist, but it is a directory: %w", fp, ErrInvalidFile)
 	}
 	return info, nil
 }
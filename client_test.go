@@ -2,18 +2,27 @@ package maptiler
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
 )
 
 // minimal shapes used by the handler to assert request bodies
@@ -39,14 +48,14 @@ func newTestClient(t *testing.T, base, token string) (*Client, error) {
 	return c, nil
 }
 
-func newClientWithPool(t *testing.T, proc processor[uploadTask], conc int) *Client {
+func newClientWithPool(t *testing.T, proc processor[uploadTask, uploadTaskResponse], conc int) *Client {
 	t.Helper()
 	if conc <= 0 {
 		conc = 2
 	}
-	wp := newPool(proc, withPoolConcurrency(conc))
 	return &Client{
-		wp: wp,
+		up:          proc,
+		concurrency: conc,
 	}
 }
 
@@ -279,7 +288,7 @@ func TestClientUploadSortsAndCollects(t *testing.T) {
 	proc := &fakeProcessor{}
 	cl := newClientWithPool(t, proc, 3)
 
-	got, err := cl.upload(t.Context(), ir, "ignored/path")
+	got, err := cl.upload(t.Context(), ir, "ignored/path", uploadOptions{})
 	if err != nil {
 		t.Fatalf("upload returned error: %v", err)
 	}
@@ -299,6 +308,69 @@ func TestClientUploadSortsAndCollects(t *testing.T) {
 	}
 }
 
+func TestClientUploadReusable(t *testing.T) {
+	t.Parallel()
+
+	ir := IngestResponse{
+		Size: 20,
+		Upload: upload{
+			PartSize: 10,
+			Parts:    uploadParts{{PartID: 1, URL: "u1"}, {PartID: 2, URL: "u2"}},
+			Type:     ingestUploadTypeS3MultiPart,
+		},
+	}
+
+	cl := newClientWithPool(t, &fakeProcessor{}, 2)
+
+	for i := range 2 {
+		got, err := cl.upload(t.Context(), ir, "ignored/path", uploadOptions{})
+		if err != nil {
+			t.Fatalf("upload %d returned error: %v", i, err)
+		}
+		if len(got.Parts) != 2 {
+			t.Fatalf("upload %d: got %d parts, want 2", i, len(got.Parts))
+		}
+	}
+}
+
+func TestClientUploadDrainOnCancel(t *testing.T) {
+	t.Parallel()
+
+	ir := IngestResponse{
+		Size: 30,
+		Upload: upload{
+			PartSize: 10,
+			Parts:    uploadParts{{PartID: 1, URL: "u1"}, {PartID: 2, URL: "u2"}, {PartID: 3, URL: "u3"}},
+			Type:     ingestUploadTypeS3MultiPart,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	var finished atomic.Int32
+	proc := &fakeProcessor{check: func(ut uploadTask) error {
+		if ut.PartID == 1 {
+			cancel()
+			time.Sleep(20 * time.Millisecond)
+		}
+		finished.Add(1)
+		return nil
+	}}
+	cl := newClientWithPool(t, proc, 1)
+
+	_, err := cl.upload(ctx, ir, "ignored/path", uploadOptions{drain: time.Second})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("upload() error = %v, want %v", err, context.Canceled)
+	}
+	if !strings.Contains(err.Error(), "2 of 3 parts abandoned") {
+		t.Fatalf("upload() error = %v, want 2 of 3 parts abandoned", err)
+	}
+	if got := finished.Load(); got != 1 {
+		t.Fatalf("finished parts = %d, want the in flight part only", got)
+	}
+}
+
 func TestClientUploadComputesRanges(t *testing.T) {
 	t.Parallel()
 	// file size 35, part size 10 => 4 chunks; lengths: 10,10,10,5
@@ -361,7 +433,7 @@ func TestClientUploadComputesRanges(t *testing.T) {
 	proc := &fakeProcessor{check: checkRanges(t)}
 	cl := newClientWithPool(t, proc, 2)
 
-	got, err := cl.upload(t.Context(), ir, "ignored/path")
+	got, err := cl.upload(t.Context(), ir, "ignored/path", uploadOptions{})
 	if err != nil {
 		t.Fatalf("upload returned error: %v", err)
 	}
@@ -967,17 +1039,543 @@ type fakeProcessor struct {
 	check func(uploadTask) error
 }
 
-func (p *fakeProcessor) Process(ctx context.Context, tsk task[uploadTask]) error {
+func (p *fakeProcessor) Process(ctx context.Context, tsk task[uploadTask]) (uploadTaskResponse, error) {
 	if p.check != nil {
 		if err := p.check(tsk.Body); err != nil {
-			return err
+			return uploadTaskResponse{}, err
 		}
 	}
 	// always respond with an ETag for the part
-	tsk.Body.RespCh <- uploadTaskResponse{
+	return uploadTaskResponse{
 		PartID: tsk.Body.PartID,
 		ETag:   fmt.Sprintf("etag-%d", tsk.Body.PartID),
-	}
-	return nil
+	}, nil
 }
 func (p *fakeProcessor) Close() {}
+
+func TestClientCreateAll(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/datasets/ingest", func(w http.ResponseWriter, r *http.Request) {
+		var req ingestReqBody
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+		resp := IngestResponse{
+			ID:    "ing-" + req.Filename,
+			Size:  req.Size,
+			State: "upload",
+			Upload: upload{
+				PartSize: req.Size,
+				Type:     ingestUploadTypeS3MultiPart,
+				Parts:    uploadParts{{PartID: 1, URL: "http://" + r.Host + "/upload"}},
+			},
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"etag-1"`)
+	})
+	mux.HandleFunc("/v1/datasets/ingest/{id}/process", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(IngestResponse{ID: r.PathValue("id"), State: "completed"})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	a := dir + "/a.pmtiles"
+	b := dir + "/b.pmtiles"
+	if err := os.WriteFile(a, []byte("abc"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("defghij"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := dir + "/missing.pmtiles"
+
+	cl, err := New(srv.URL+"/v1", "test-token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	var last Progress
+	got, err := cl.CreateAll(t.Context(), []string{a, missing, b}, WithProgress(func(p Progress) { last = p }))
+	if !errors.Is(err, ErrInvalidFile) || !strings.Contains(err.Error(), missing) {
+		t.Fatalf("CreateAll() error = %v, want the missing file to fail", err)
+	}
+	if len(got) != 3 || got[0].ID != "ing-a.pmtiles" || got[2].ID != "ing-b.pmtiles" {
+		t.Fatalf("unexpected responses %+v", got)
+	}
+	if last.Phase != PhaseDone || last.BytesTotal != 10 || last.BytesDone != 10 || last.PartsDone != 2 {
+		t.Fatalf("unexpected combined progress %+v", last)
+	}
+}
+
+func TestClientAPIRateLimit(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"ing-1","state":"processing"}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token", WithAPIRateLimit(20))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	// the first second worth of calls is not delayed, the rest at 20 per second.
+	start := time.Now()
+	for range 30 {
+		if _, err := c.Get(t.Context(), "ing-1"); err != nil {
+			t.Fatalf("Get() unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("30 calls took %v, want at least 400ms", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := c.Get(ctx, "ing-1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled while waiting for the limit, got %v", err)
+	}
+}
+
+func TestClientGetDeduplicatesConcurrentCalls(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		_, _ = w.Write([]byte(`{"id":"ing-1","state":"processing"}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for range 5 {
+		wg.Go(func() {
+			ir, err := c.Get(t.Context(), "ing-1")
+			if err == nil && ir.ID != "ing-1" {
+				err = fmt.Errorf("unexpected response %+v", ir)
+			}
+			errs <- err
+		})
+	}
+
+	// let all calls join the request in flight before it completes.
+	for atomic.LoadInt32(&hits) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Get() unexpected error: %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Fatalf("requests=%d want 1", got)
+	}
+}
+
+func TestClientGetSurvivesCanceledCaller(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		_, _ = w.Write([]byte(`{"id":"ing-1","state":"processing"}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.Get(ctx, "ing-1")
+		first <- err
+	}()
+	for atomic.LoadInt32(&hits) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	second := make(chan error, 1)
+	go func() {
+		ir, err := c.Get(t.Context(), "ing-1")
+		if err == nil && ir.ID != "ing-1" {
+			err = fmt.Errorf("unexpected response %+v", ir)
+		}
+		second <- err
+	}()
+	// let the second call join the request in flight.
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled for the canceled call, got %v", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Fatalf("requests=%d want 1", got)
+	}
+}
+
+func TestClientAgainstFakeServer(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ir, err := c.Create(t.Context(), fp)
+	if err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+	if ir.State != StateCompleted || ir.Stats.Parts != 3 {
+		t.Fatalf("unexpected response %+v", ir)
+	}
+	for i, ps := range ir.Stats.PartStats {
+		want := fmt.Sprintf("%s-%d", ir.ID, i+1)
+		if ps.PartID != int64(i+1) || ps.Header["X-Amz-Request-Id"] != want {
+			t.Fatalf("part stat %d: expected request id %s, got %+v", i, want, ps)
+		}
+	}
+
+	ur, err := c.Update(t.Context(), ir.DocumentID, fp)
+	if err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	if ur.DocumentID != ir.DocumentID || IngestState(srv.State(ur.ID)) != StateCompleted {
+		t.Fatalf("unexpected update %+v", ur)
+	}
+}
+
+func TestPartResults(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		parts []int64
+		add   []int64
+		want  []int64
+	}{
+		{name: "in order", parts: []int64{1, 2, 3}, add: []int64{3, 1, 2}, want: []int64{1, 2, 3}},
+		{name: "missing part", parts: []int64{1, 2, 3}, add: []int64{3, 1}, want: []int64{1, 3}},
+		{name: "duplicate response", parts: []int64{1, 2}, add: []int64{2, 2, 1}, want: []int64{1, 2}},
+		{name: "unknown part", parts: []int64{1, 2}, add: []int64{2, 7, 1}, want: []int64{1, 2}},
+		{name: "other numbering", parts: []int64{10, 30, 20}, add: []int64{20, 10, 30, 40}, want: []int64{10, 20, 30}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			parts := make(uploadParts, 0, len(tt.parts))
+			for _, id := range tt.parts {
+				parts = append(parts, uploadPart{PartID: id})
+			}
+			r := newPartResults(parts)
+			for _, id := range tt.add {
+				r.add(uploadTaskResponse{PartID: id, ETag: fmt.Sprint(id)})
+			}
+
+			var got []int64
+			for _, resp := range r.list() {
+				got = append(got, resp.PartID)
+			}
+			if !slices.Equal(got, tt.want) || r.received != len(tt.want) {
+				t.Fatalf("expected parts %v, got %v (%d received)", tt.want, got, r.received)
+			}
+		})
+	}
+}
+
+// BenchmarkPartResults compares collecting the responses of an upload with
+// thousands of parts in a slice to the map keyed by the formatted part ID it
+// replaced.
+func BenchmarkPartResults(b *testing.B) {
+	const n = 10000
+	parts := make(uploadParts, n)
+	responses := make([]uploadTaskResponse, n)
+	for i := range n {
+		parts[i] = uploadPart{PartID: int64(i + 1)}
+		// parts finish out of order.
+		id := int64((i*7919)%n + 1)
+		responses[i] = uploadTaskResponse{PartID: id, ETag: "etag"}
+	}
+
+	b.Run("slice", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			r := newPartResults(parts)
+			for _, resp := range responses {
+				r.add(resp)
+			}
+			_ = r.list()
+		}
+	})
+
+	b.Run("map", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			results := make(map[string]uploadTaskResponse)
+			for _, resp := range responses {
+				results[fmt.Sprint(resp.PartID)] = resp
+			}
+			list := slices.Collect(maps.Values(results))
+			slices.SortFunc(list, func(a, b uploadTaskResponse) int {
+				return cmp.Compare(a.PartID, b.PartID)
+			})
+		}
+	})
+}
+
+// etagProcessor uploads nothing and answers every part with the same ETag, to
+// measure the upload path alone.
+type etagProcessor struct{}
+
+func (etagProcessor) Process(_ context.Context, t task[uploadTask]) (uploadTaskResponse, error) {
+	return uploadTaskResponse{PartID: t.Body.PartID, ETag: "etag"}, nil
+}
+func (etagProcessor) Close() {}
+
+func BenchmarkClientUpload(b *testing.B) {
+	for _, n := range []int{1000, 10000, 50000} {
+		parts := make(uploadParts, n)
+		for i := range parts {
+			parts[i] = uploadPart{PartID: int64(i + 1), URL: "https://bucket.s3.amazonaws.com/tiles?partNumber=" + strconv.Itoa(i+1)}
+		}
+		ir := IngestResponse{
+			Size:   int64(n) * 10,
+			Upload: upload{PartSize: 10, Parts: parts, Type: ingestUploadTypeS3MultiPart},
+		}
+		c := &Client{up: etagProcessor{}, concurrency: defaultConcurrency}
+		// the listeners run for every part, as they do when uploading with progress.
+		opts := uploadOptions{progress: newProgressTracker(func(Progress) {})}
+
+		b.Run(fmt.Sprintf("parts=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := c.upload(b.Context(), ir, "ignored/path", opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ir, err := c.Create(t.Context(), fp)
+	if err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+
+	if err := c.Delete(t.Context(), ir.DocumentID); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	var aerr APIError
+	if _, err := c.GetDataset(t.Context(), ir.DocumentID); !errors.As(err, &aerr) || aerr.StatusCode != http.StatusNotFound {
+		t.Errorf("GetDataset() error = %v, want the dataset to be gone", err)
+	}
+	if err := c.Delete(t.Context(), ir.DocumentID); !errors.As(err, &aerr) || aerr.StatusCode != http.StatusNotFound {
+		t.Errorf("Delete() error = %v, want an APIError with 404", err)
+	}
+}
+
+func TestPhaseTimeout(t *testing.T) {
+	t.Parallel()
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("slow service API", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(200 * time.Millisecond):
+			}
+		}))
+		defer srv.Close()
+
+		c, err := New(srv.URL, "token", WithTimeout(20*time.Millisecond))
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		_, err = c.Create(t.Context(), fp)
+		var terr PhaseTimeoutError
+		if !errors.As(err, &terr) {
+			t.Fatalf("Create() error = %v, want PhaseTimeoutError", err)
+		}
+		if terr.Phase != PhaseIngest || terr.Configured != 20*time.Millisecond || terr.Elapsed < terr.Configured {
+			t.Errorf("Create() error = %+v, want the ingest phase to time out after 20ms", terr)
+		}
+	})
+
+	t.Run("upload past the deadline", func(t *testing.T) {
+		t.Parallel()
+
+		srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+		defer srv.Close()
+
+		c, err := New(srv.URL, "token")
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		c.up = processorFunc(func(ctx context.Context, tsk task[uploadTask]) (uploadTaskResponse, error) {
+			<-ctx.Done()
+			return uploadTaskResponse{}, ctx.Err()
+		})
+		ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+		defer cancel()
+		_, err = c.Create(ctx, fp)
+		var terr PhaseTimeoutError
+		if !errors.As(err, &terr) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Create() error = %v, want PhaseTimeoutError of the deadline", err)
+		}
+		if terr.Phase != PhaseUpload || terr.Configured <= 0 || terr.Configured > 50*time.Millisecond {
+			t.Errorf("Create() error = %+v, want the upload phase to time out within 50ms", terr)
+		}
+	})
+}
+
+func TestServiceErrors(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(path.Base(r.URL.Path))
+		if code == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "7")
+		}
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+
+	// the errors of the first response are checked, without retrying them.
+	c, err := New(srv.URL, "token", WithRetryPolicy(NoRetry{}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	tests := []struct {
+		code  int
+		check func(error) bool
+	}{
+		{code: http.StatusUnauthorized, check: func(err error) bool { var e AuthError; return errors.As(err, &e) }},
+		{code: http.StatusForbidden, check: func(err error) bool { var e AuthError; return errors.As(err, &e) }},
+		{code: http.StatusTooManyRequests, check: func(err error) bool {
+			var e RateLimitError
+			return errors.As(err, &e) && e.RetryAfter == 7*time.Second
+		}},
+		{code: http.StatusNotFound, check: func(err error) bool { var e NotFoundError; return errors.As(err, &e) }},
+		{code: http.StatusInternalServerError, check: func(err error) bool {
+			var a AuthError
+			var r RateLimitError
+			var n NotFoundError
+			return !errors.As(err, &a) && !errors.As(err, &r) && !errors.As(err, &n)
+		}},
+	}
+	for _, tt := range tests {
+		_, err := c.Get(t.Context(), strconv.Itoa(tt.code))
+		if !tt.check(err) {
+			t.Errorf("Get() error = %v (%T), want the typed error of %d", err, err, tt.code)
+		}
+		var aerr APIError
+		if !errors.As(err, &aerr) || aerr.StatusCode != tt.code {
+			t.Errorf("Get() error = %v, want an APIError with %d", err, tt.code)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rw, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ir, err := rw.Create(t.Context(), fp)
+	if err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+
+	c, err := New(srv.URL, "token", WithReadOnly())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if _, err := c.Get(t.Context(), ir.ID); err != nil {
+		t.Errorf("Get() unexpected error: %v", err)
+	}
+	if _, err := c.GetDataset(t.Context(), ir.DocumentID); err != nil {
+		t.Errorf("GetDataset() unexpected error: %v", err)
+	}
+	if _, err := c.Create(t.Context(), fp); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Create() error = %v, want ErrReadOnly", err)
+	}
+	if _, err := c.Update(t.Context(), ir.DocumentID, fp); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Update() error = %v, want ErrReadOnly", err)
+	}
+	if _, err := c.Cancel(t.Context(), ir.ID); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Cancel() error = %v, want ErrReadOnly", err)
+	}
+	if err := c.Delete(t.Context(), ir.DocumentID); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Delete() error = %v, want ErrReadOnly", err)
+	}
+	if got := IngestState(srv.State("ingest-2")); got != "" {
+		t.Errorf("read-only client created an ingest in state %q", got)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// adminFlag is the flag of long running commands that enables the admin server.
+func adminFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "admin-addr",
+		Usage: "Serve pprof profiles and runtime metrics on this localhost address, e.g. localhost:6060",
+	}
+}
+
+var publishOnce sync.Once
+
+// startAdmin serves net/http/pprof under /debug/pprof/ and the runtime metrics
+// of expvar under /debug/vars on addr until ctx is done. addr has to be a
+// loopback address, profiles must not be exposed to the network.
+func startAdmin(ctx context.Context, addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("admin address %q: %w", addr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("admin address %q: host must be localhost or a loopback address", addr)
+	}
+
+	publishOnce.Do(func() {
+		expvar.Publish("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", addr, err)
+	}
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		sctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(sctx) //nolint:errcheck
+	}()
+	go func() {
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("admin server: %v", err)
+		}
+	}()
+
+	log.Print(msg("admin.serving", ln.Addr()))
+	return nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/iwpnd/maptiler-go"
+)
+
+func backfillCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "backfill",
+		Usage: "Create a dataset for every file of a CSV manifest, e.g. for a one-time migration. Run it again to retry the failed rows, rows that succeeded are skipped and interrupted uploads are resumed",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:     "file",
+				Aliases:  []string{"f"},
+				Usage:    "Path to the CSV manifest with rows of file,name. The name is optional, relative paths are relative to the manifest",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "progress",
+				Usage: "Path to track the progress of the backfill in (defaults to the manifest path with .progress.json appended)",
+			},
+			&cli.StringFlag{
+				Name:  "report",
+				Usage: "Write a JSON summary of all rows to this file",
+			},
+		}, ingestFlags()...),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			manifest := cmd.String("file")
+			rows, err := readManifest(manifest)
+			if err != nil {
+				return err
+			}
+			progress := cmp.Or(cmd.String("progress"), manifest+".progress.json")
+			done, err := readProgress(progress)
+			if err != nil {
+				return err
+			}
+
+			c, cctx, cancel, err := newClientWithContext(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			ctl := maptiler.NewUploadControl()
+			defer pauseOnSignal(ctl)()
+			b := backfill{
+				client:   c,
+				rows:     rows,
+				entries:  done,
+				progress: progress,
+				opts:     ingestOptions(cmd, ctl),
+			}
+			s, err := b.run(cctx)
+			fmt.Fprintln(os.Stderr, msg("backfill.summary", s.succeeded, s.failed, s.skipped, s.pending, progress)) //nolint:errcheck
+			if path := cmd.String("report"); path != "" {
+				if rerr := writeReport(path, b.report()); rerr != nil {
+					return errors.Join(err, rerr)
+				}
+			}
+			if err != nil {
+				return err
+			}
+			if s.failed > 0 || s.pending > 0 {
+				return errors.New(msg("backfill.incomplete", s.failed+s.pending))
+			}
+			return nil
+		},
+	}
+}
+
+// manifestRow is a file of a backfill manifest and the name of its dataset,
+// empty for the name of the file.
+type manifestRow struct {
+	File string
+	Name string
+}
+
+// readManifest reads the rows of the CSV manifest at path. A header row of
+// file,name and lines starting with # are skipped.
+func readManifest(path string) ([]manifestRow, error) {
+	f, err := os.Open(path) //nolint:gosec // the path is given by the user.
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var rows []manifestRow
+	seen := make(map[string]int)
+	for first := true; ; first = false {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading manifest: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		if first && strings.EqualFold(strings.TrimSpace(rec[0]), "file") {
+			continue
+		}
+		if len(rec) > 2 || strings.TrimSpace(rec[0]) == "" {
+			return nil, fmt.Errorf("reading manifest: line %d: expected file,name", line)
+		}
+		row := manifestRow{File: strings.TrimSpace(rec[0])}
+		if len(rec) == 2 {
+			row.Name = strings.TrimSpace(rec[1])
+		}
+		if !filepath.IsAbs(row.File) {
+			row.File = filepath.Join(filepath.Dir(path), row.File)
+		}
+		if prev, ok := seen[row.File]; ok {
+			return nil, fmt.Errorf("reading manifest: line %d: %s is already on line %d", line, row.File, prev)
+		}
+		seen[row.File] = line
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// readProgress returns the entries of the progress file at path by file, none
+// if it does not exist yet.
+func readProgress(path string) (map[string]maptiler.ReportEntry, error) {
+	entries := make(map[string]maptiler.ReportEntry)
+	r, err := readJSONFile[maptiler.Report](path, nil)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range r.Ingests {
+		entries[e.File] = e
+	}
+	return entries, nil
+}
+
+// backfill ingests the rows of a manifest one after the other and records the
+// outcome of every row in its progress file.
+type backfill struct {
+	client   *maptiler.Client
+	rows     []manifestRow
+	entries  map[string]maptiler.ReportEntry
+	progress string
+	opts     []maptiler.IngestOption
+}
+
+// backfillSummary counts the rows of a backfill by outcome. Skipped rows
+// succeeded in an earlier run, pending rows were not reached.
+type backfillSummary struct {
+	succeeded, failed, skipped, pending int
+}
+
+// run ingests the rows that did not succeed before and prints the status of
+// every row. It stops early if ctx is done.
+func (b *backfill) run(ctx context.Context) (backfillSummary, error) {
+	var s backfillSummary
+	for i, row := range b.rows {
+		if ctx.Err() != nil {
+			s.pending = len(b.rows) - i
+			break
+		}
+		if e, ok := b.entries[row.File]; ok && e.Error == "" {
+			fmt.Println(msg("backfill.skipped", i+1, len(b.rows), row.File, e.DatasetID))
+			s.skipped++
+			continue
+		}
+
+		ir, err := b.ingest(ctx, row)
+		var ferr error
+		if err != nil {
+			ferr = maptiler.FileError{Path: row.File, Err: err}
+		}
+		b.entries[row.File] = maptiler.NewReport([]string{row.File}, []maptiler.IngestResponse{ir}, ferr).Ingests[0]
+		if err != nil {
+			fmt.Println(msg("backfill.failed", i+1, len(b.rows), row.File, withGuardrailHint(err)))
+			s.failed++
+		} else {
+			fmt.Println(msg("backfill.done", i+1, len(b.rows), row.File, ir.State, ir.DocumentID))
+			printWarnings(ir.ID, ir.Warnings)
+			s.succeeded++
+		}
+		if err := writeReport(b.progress, b.report()); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// ingest creates the dataset of row, or resumes its upload if an earlier run
+// was interrupted. A journal of an ingest that can not be resumed is replaced
+// by a new ingest.
+func (b *backfill) ingest(ctx context.Context, row manifestRow) (maptiler.IngestResponse, error) {
+	journal := b.journal(row)
+	if _, err := os.Stat(journal); err == nil {
+		ir, err := b.client.Resume(ctx, journal, b.opts...)
+		var nerr maptiler.NotFoundError
+		if !errors.Is(err, maptiler.ErrNotResumable) && !errors.As(err, &nerr) {
+			return ir, err
+		}
+		if err := os.Remove(journal); err != nil {
+			return maptiler.IngestResponse{}, fmt.Errorf("removing journal: %w", err)
+		}
+	}
+
+	opts := append(slices.Clip(b.opts), maptiler.WithJournal(journal))
+	if row.Name != "" {
+		opts = append(opts, maptiler.WithFilename(row.Name))
+	}
+	return b.client.Create(ctx, row.File, opts...)
+}
+
+// journal returns the path of the journal of row next to the progress file,
+// named by its file so that it survives reordering the manifest.
+func (b *backfill) journal(row manifestRow) string {
+	sum := sha256.Sum256([]byte(row.File))
+	return strings.TrimSuffix(b.progress, ".json") + "." + hex.EncodeToString(sum[:8]) + ".journal"
+}
+
+// report returns the entries of the rows in the order of the manifest, rows
+// that were never ingested are left out.
+func (b *backfill) report() maptiler.Report {
+	r := maptiler.Report{Created: time.Now().UTC()}
+	for _, row := range b.rows {
+		if e, ok := b.entries[row.File]; ok {
+			r.Ingests = append(r.Ingests, e)
+		}
+	}
+	return r
+}
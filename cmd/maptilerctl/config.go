@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/iwpnd/maptiler-go"
+)
+
+// daemonConfig holds the settings of long running commands that can be
+// reloaded without restarting them. Set values override the flags.
+type daemonConfig struct {
+	Token       string `json:"token,omitempty"`
+	Concurrency int    `json:"concurrency,omitempty"`
+	// Endpoints replaces the paths of endpoints by name, see --endpoint.
+	Endpoints map[string]string `json:"endpoints,omitempty"`
+}
+
+// loadDaemonConfig reads the JSON config file at path.
+func loadDaemonConfig(path string) (daemonConfig, error) {
+	var cfg daemonConfig
+	b, err := os.ReadFile(path) //nolint:gosec // the path is given by the user.
+	if err != nil {
+		return cfg, fmt.Errorf("reading config: %w", err)
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("reading config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// clientOptions returns the client options set by the global flags. Unset
+// flags default to the limits of the container the command runs in, if any.
+func clientOptions(cmd *cli.Command) []maptiler.Option {
+	limits := maptiler.DetectLimits()
+
+	concurrency := cmd.Int("concurrency")
+	if concurrency <= 0 {
+		concurrency = limits.Concurrency()
+	}
+	inFlight := cmd.Int64("max-in-flight")
+	if inFlight == 0 {
+		inFlight = limits.InFlightBytes()
+	}
+
+	opts := []maptiler.Option{
+		maptiler.WithConcurrency(concurrency),
+		maptiler.WithInFlightBytes(inFlight),
+	}
+	if v := cmd.String("api-version"); v != "" {
+		opts = append(opts, maptiler.WithAPIVersion(v))
+	}
+	if p, err := redirectPolicy(cmd.String("upload-redirects")); err == nil {
+		opts = append(opts, maptiler.WithUploadRedirects(p))
+	}
+	if n := cmd.Int("api-rate-limit"); n > 0 {
+		opts = append(opts, maptiler.WithAPIRateLimit(n))
+	}
+	if n := cmd.Int("retries"); n > 0 {
+		opts = append(opts, maptiler.WithRetryPolicy(maptiler.ExponentialBackoff{Attempts: n, Base: time.Second, Max: 30 * time.Second}))
+	}
+	if cmd.Bool("skip-unchanged") {
+		opts = append(opts, maptiler.WithIdempotentUpdates())
+	}
+	if cmd.Bool("read-only") {
+		opts = append(opts, maptiler.WithReadOnly())
+	}
+	if cmd.Bool("debug-uploads") {
+		opts = append(opts, maptiler.WithUploadDebug(true))
+	}
+	if hosts := cmd.StringSlice("upload-host"); len(hosts) > 0 {
+		opts = append(opts, maptiler.WithUploadHosts(hosts...))
+	}
+	for _, e := range cmd.StringSlice("endpoint") {
+		name, path, _ := strings.Cut(e, "=")
+		opts = append(opts, maptiler.WithEndpointPath(maptiler.Endpoint(name), path))
+	}
+	if sc := stateCipher(cmd); sc != nil {
+		opts = append(opts, maptiler.WithStateCipher(sc))
+	}
+	return opts
+}
+
+// stateCipher returns the cipher of --state-key, or nil if it is not set.
+func stateCipher(cmd *cli.Command) *maptiler.StateCipher {
+	key := cmd.String("state-key")
+	if key == "" {
+		return nil
+	}
+	sc, _ := maptiler.ParseStateKey(key) // validated by the flag.
+	return sc
+}
+
+// newDaemonClient creates a client from the global flags, overridden by the
+// config file at path if it is not empty.
+func newDaemonClient(cmd *cli.Command, path string) (*maptiler.Client, error) {
+	token := cmd.String("token")
+	opts := clientOptions(cmd)
+	if path != "" {
+		cfg, err := loadDaemonConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Token != "" {
+			token = cfg.Token
+		}
+		if cfg.Concurrency > 0 {
+			opts = append(opts, maptiler.WithConcurrency(cfg.Concurrency))
+		}
+		for name, path := range cfg.Endpoints {
+			opts = append(opts, maptiler.WithEndpointPath(maptiler.Endpoint(name), path))
+		}
+	}
+	return maptiler.New(cmd.String("host"), token, opts...)
+}
+
+// redirectPolicy parses the name of a redirect policy, an empty name is the default.
+func redirectPolicy(name string) (maptiler.RedirectPolicy, error) {
+	if name == "" {
+		return maptiler.RedirectDeny, nil
+	}
+	for _, p := range []maptiler.RedirectPolicy{maptiler.RedirectDeny, maptiler.RedirectSameHost, maptiler.RedirectFollow} {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return maptiler.RedirectDeny, fmt.Errorf("unknown redirect policy %q, expected deny, same-host or follow", name)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/iwpnd/maptiler-go"
+)
+
+func diffCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "diff",
+		Usage: "Compare the TileJSON metadata of the tilesets of two datasets",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:     "id",
+				Usage:    "Dataset ID, or the ID of an ingest of the dataset, given exactly twice",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "key",
+				Usage:    "MapTiler API key used to read the TileJSON",
+				Sources:  cli.EnvVars("MAPTILER_KEY"),
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:  "exit-code",
+				Usage: "Exit with status 1 if the tilesets differ",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ids := cmd.StringSlice("id")
+			if len(ids) != 2 {
+				return errors.New(msg("diff.id_twice", len(ids)))
+			}
+
+			c, cctx, cancel, err := newClientWithContext(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			var tjs [2]maptiler.TileJSON
+			for i, id := range ids {
+				did, err := resolveDatasetID(cctx, c, id)
+				if err != nil {
+					return err
+				}
+				if tjs[i], err = c.TileJSON(cctx, did, cmd.String("key")); err != nil {
+					return fmt.Errorf("%s: %w", id, err)
+				}
+			}
+
+			diffs := maptiler.DiffTileJSON(tjs[0], tjs[1])
+			if len(diffs) == 0 {
+				fmt.Println(msg("diff.none"))
+				return nil
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", msg("diff.header"), ids[0], ids[1]) //nolint:errcheck
+			for _, d := range diffs {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", d.Field, orDash(d.A), orDash(d.B)) //nolint:errcheck
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+
+			if cmd.Bool("exit-code") {
+				return cli.Exit("", 1)
+			}
+			return nil
+		},
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
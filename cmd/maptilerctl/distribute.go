@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/iwpnd/maptiler-go"
+)
+
+func distributeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "distribute",
+		Usage: "Upload the parts of a single ingest from several machines",
+		Commands: []*cli.Command{
+			{
+				Name:  "begin",
+				Usage: "Create an ingest and write one assignment of parts per worker",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
+						Aliases:  []string{"f"},
+						Usage:    "Path to the dataset file to ingest",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "id",
+						Usage: "Dataset ID to update, a new dataset is created if empty",
+					},
+					&cli.IntFlag{
+						Name:     "workers",
+						Usage:    "Number of workers to split the parts across",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "out",
+						Usage: "Directory to write ingest.json and assignment-<worker>.json to",
+						Value: ".",
+					},
+					&cli.StringFlag{
+						Name:    "filename",
+						Aliases: []string{"name"},
+						Usage:   "Dataset name in MapTiler Cloud instead of the name of the file, the file extension is appended if missing",
+					},
+					&cli.BoolFlag{
+						Name:  "allow-any",
+						Usage: "Skip the file extension and size checks",
+					},
+					&cli.Int64Flag{
+						Name:  "max-size",
+						Usage: "Reject files larger than this many bytes (0 = no limit)",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					c, cctx, cancel, err := newClientWithContext(ctx, cmd)
+					if err != nil {
+						return err
+					}
+					defer cancel()
+
+					ir, err := c.Begin(cctx, cmd.String("id"), cmd.String("file"), ingestOptions(cmd, nil)...)
+					if err != nil {
+						return withGuardrailHint(err)
+					}
+					assignments, err := maptiler.Assign(ir, cmd.Int("workers"))
+					if err != nil {
+						return err
+					}
+
+					out := cmd.String("out")
+					if err := writeJSONFile(filepath.Join(out, "ingest.json"), ir, stateCipher(cmd)); err != nil {
+						return err
+					}
+					for _, a := range assignments {
+						name := "assignment-" + strconv.Itoa(a.Worker) + ".json"
+						if err := writeJSONFile(filepath.Join(out, name), a, stateCipher(cmd)); err != nil {
+							return err
+						}
+					}
+					fmt.Println(ir.String())
+					fmt.Fprintln(os.Stderr, msg("distribute.begin", len(assignments), out)) //nolint:errcheck
+					return nil
+				},
+			},
+			{
+				Name:  "upload",
+				Usage: "Upload the parts of an assignment and write the report for complete",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "assignment",
+						Usage:    "Path to the assignment written by begin",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "file",
+						Aliases:  []string{"f"},
+						Usage:    "Path to this machine's copy of the dataset file",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "out",
+						Usage: "Path to write the report to (defaults to report-<worker>.json)",
+					},
+				}, append(leaseFlags(), ingestFlags()...)...),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					a, err := readJSONFile[maptiler.PartAssignment](cmd.String("assignment"), stateCipher(cmd))
+					if err != nil {
+						return err
+					}
+					c, cctx, cancel, err := newClientWithContext(ctx, cmd)
+					if err != nil {
+						return err
+					}
+					defer cancel()
+
+					upload := c.UploadAssignment
+					if co := coordinator(cmd, c); co != nil {
+						upload = co.UploadAssignment
+					}
+					ctl := maptiler.NewUploadControl()
+					defer pauseOnSignal(ctl)()
+					r, err := upload(cctx, a, cmd.String("file"), ingestOptions(cmd, ctl)...)
+					if err != nil {
+						return err
+					}
+					out := cmd.String("out")
+					if out == "" {
+						out = "report-" + strconv.Itoa(a.Worker) + ".json"
+					}
+					if err := writeJSONFile(out, r, stateCipher(cmd)); err != nil {
+						return err
+					}
+					fmt.Println(r.Stats.String())
+					return nil
+				},
+			},
+			{
+				Name:  "complete",
+				Usage: "Finalize the ingest once the reports of all workers are collected",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "ingest",
+						Usage: "Path to the ingest.json written by begin",
+						Value: "ingest.json",
+					},
+					&cli.StringSliceFlag{
+						Name:     "report",
+						Usage:    "Path to the report of a worker (repeatable)",
+						Required: true,
+					},
+				}, leaseFlags()...),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					ir, err := readJSONFile[maptiler.IngestResponse](cmd.String("ingest"), stateCipher(cmd))
+					if err != nil {
+						return err
+					}
+					var reports []maptiler.PartReport
+					for _, path := range cmd.StringSlice("report") {
+						r, err := readJSONFile[maptiler.PartReport](path, stateCipher(cmd))
+						if err != nil {
+							return err
+						}
+						reports = append(reports, r)
+					}
+					c, cctx, cancel, err := newClientWithContext(ctx, cmd)
+					if err != nil {
+						return err
+					}
+					defer cancel()
+
+					complete := c.Complete
+					if co := coordinator(cmd, c); co != nil {
+						complete = co.Complete
+					}
+					done, err := complete(cctx, ir, reports...)
+					if err != nil {
+						return err
+					}
+					fmt.Println(done.String())
+					printWarnings(done.ID, done.Warnings)
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// leaseFlags are the flags of the distribute commands that take leases.
+func leaseFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "lease-dir",
+			Usage: "Directory shared by all machines, e.g. on NFS, to hold leases in, so that every assignment is uploaded and the ingest is completed only once",
+		},
+		&cli.StringFlag{
+			Name:  "owner",
+			Usage: "Name of this machine in the leases (defaults to the hostname and process ID)",
+		},
+		&cli.DurationFlag{
+			Name:  "lease-ttl",
+			Usage: "How long a lease outlives a machine that stopped renewing it",
+			Value: maptiler.DefaultLeaseTTL,
+		},
+	}
+}
+
+// coordinator returns the coordinator of --lease-dir, or nil if it is not set.
+func coordinator(cmd *cli.Command, c *maptiler.Client) *maptiler.Coordinator {
+	dir := cmd.String("lease-dir")
+	if dir == "" {
+		return nil
+	}
+	owner := cmd.String("owner")
+	if owner == "" {
+		// without a hostname the process ID alone has to do.
+		host, _ := os.Hostname()
+		owner = host + "-" + strconv.Itoa(os.Getpid())
+	}
+	return maptiler.NewCoordinator(c, maptiler.NewFileBackend(dir), owner, cmd.Duration("lease-ttl"))
+}
+
+// readJSONFile decodes the JSON file at path, opened with sc if it is encrypted.
+func readJSONFile[T any](path string, sc *maptiler.StateCipher) (T, error) {
+	var v T
+	b, err := os.ReadFile(path) //nolint:gosec // the path is given by the user.
+	if err != nil {
+		return v, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if b, err = sc.Open(b); err != nil {
+		return v, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return v, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// writeJSONFile writes v to path, sealed by sc if it is not nil. The files of
+// distribute hold the presigned upload URLs, so they are only readable by the
+// user.
+func writeJSONFile(path string, v any, sc *maptiler.StateCipher) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if sc != nil {
+		b, err = json.Marshal(v)
+	}
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", path, err)
+	}
+	b = sc.Seal(b)
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/iwpnd/maptiler-go"
+)
+
+func estimateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "estimate",
+		Usage: "Show how a file would be uploaded, without starting an ingest",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "file",
+				Aliases:  []string{"f"},
+				Usage:    "Path to the dataset file to estimate",
+				Required: true,
+			},
+			&cli.Int64SliceFlag{
+				Name:  "part-size",
+				Usage: "Part size in bytes to plan the upload for, can be given multiple times",
+				Value: []int64{5 << 20, 16 << 20, 64 << 20, 100 << 20},
+			},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			fp := cmd.String("file")
+			st, err := maptiler.StatFile(fp)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(msg("estimate.file", fp))
+			fmt.Println(msg("estimate.size", st.Size, mib(st.Size)))
+			if ext := strings.ToLower(filepath.Ext(fp)); !slices.Contains(maptiler.SupportedExtensions(), ext) {
+				fmt.Println(msg("estimate.unsupported", ext))
+			}
+			if st.Sparse() {
+				fmt.Println(msg("estimate.sparse", st.AllocatedSize))
+			}
+			fmt.Println()
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, msg("estimate.header")) //nolint:errcheck
+			for _, p := range maptiler.PlanUpload(st.Size, cmd.Int64Slice("part-size")...) {
+				//nolint:errcheck
+				fmt.Fprintf(tw, "%.1f MiB\t%d\t%.1f MiB\t%s\n",
+					mib(p.PartSize), p.Parts, mib(p.LastPartSize), orDash(p.Problem))
+			}
+			return tw.Flush()
+		},
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/iwpnd/maptiler-go"
+)
+
+// follow prints every change of the ingest id to stderr until it ended, and
+// returns its last state.
+func follow(ctx context.Context, c *maptiler.Client, id string, interval time.Duration) (maptiler.IngestGetResponse, error) {
+	ch, err := c.Watch(ctx, id, maptiler.WithPollInterval(interval))
+	if err != nil {
+		return maptiler.IngestGetResponse{}, err
+	}
+	var last maptiler.IngestGetResponse
+	for gr := range ch {
+		fmt.Fprintln(os.Stderr, msg("get.follow", time.Now().Format(time.TimeOnly), gr.ID, gr.State, gr.Progress)) //nolint:errcheck
+		last = gr
+	}
+	if err := ctx.Err(); err != nil {
+		return last, err
+	}
+	// a failing poll closes the channel early, get the cause.
+	if !last.Ended() {
+		return c.Get(ctx, id)
+	}
+	return last, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/iwpnd/maptiler-go"
+)
+
+// hashEntry is the last successfully ingested state of a file.
+type hashEntry struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	Checksum string    `json:"checksum"`
+}
+
+// hashCache persists the hashes of ingested files keyed by dataset ID, so
+// files that are touched without changing their content are not ingested again.
+type hashCache struct {
+	path    string
+	entries map[string]hashEntry
+}
+
+// defaultHashCachePath returns the location of the hash cache in the users cache directory.
+func defaultHashCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("resolving cache directory: %w", err)
+	}
+	return filepath.Join(dir, "maptilerctl", "hashes.json"), nil
+}
+
+// loadHashCache reads the hash cache at path, a missing file yields an empty cache.
+func loadHashCache(path string) (*hashCache, error) {
+	hc := &hashCache{path: path, entries: make(map[string]hashEntry)}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return hc, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading hash cache: %w", err)
+	}
+	if err := json.Unmarshal(b, &hc.entries); err != nil {
+		return nil, fmt.Errorf("decoding hash cache: %w", err)
+	}
+	return hc, nil
+}
+
+// changed reports whether the file at fp differs from what was last ingested
+// into dataset id. Size and modification time are checked first, the file is
+// only hashed if either of them changed.
+func (hc *hashCache) changed(id, fp string) (bool, hashEntry, error) {
+	info, err := os.Stat(fp)
+	if err != nil {
+		return false, hashEntry{}, fmt.Errorf("reading file %q: %w", fp, err)
+	}
+
+	prev, ok := hc.entries[id]
+	if ok && maptiler.SamePath(prev.Path, fp) && prev.Size == info.Size() && prev.ModTime.Equal(info.ModTime()) {
+		return false, prev, nil
+	}
+
+	sum, err := maptiler.FileChecksum(fp)
+	if err != nil {
+		return false, hashEntry{}, err
+	}
+
+	cur := hashEntry{
+		Path:     fp,
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		Checksum: sum,
+	}
+	if ok && prev.Checksum == sum {
+		// content is unchanged, remember the new stat to avoid hashing again,
+		// also after a restart.
+		return false, cur, hc.store(id, cur)
+	}
+	return true, cur, nil
+}
+
+// store records entry as the last ingested state of dataset id and persists the cache.
+func (hc *hashCache) store(id string, entry hashEntry) error {
+	hc.entries[id] = entry
+
+	b, err := json.MarshalIndent(hc.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding hash cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(hc.path), 0o750); err != nil {
+		return fmt.Errorf("creating hash cache directory: %w", err)
+	}
+
+	// write to a temporary file first so a crash never leaves a truncated cache.
+	tmp := hc.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return fmt.Errorf("writing hash cache: %w", err)
+	}
+	if err := os.Rename(tmp, hc.path); err != nil {
+		return fmt.Errorf("writing hash cache: %w", err)
+	}
+	return nil
+}
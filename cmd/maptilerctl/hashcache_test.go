@@ -0,0 +1,159 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// storedCache returns a hash cache in dir that holds the file at fp as the
+// last ingested state of dataset ds-1, reloaded from disk.
+func storedCache(t *testing.T, dir, fp string) *hashCache {
+	t.Helper()
+
+	path := filepath.Join(dir, "cache", "hashes.json")
+	hc, err := loadHashCache(path)
+	if err != nil {
+		t.Fatalf("loadHashCache() unexpected error: %v", err)
+	}
+	_, entry, err := hc.changed("ds-1", fp)
+	if err != nil {
+		t.Fatalf("changed() unexpected error: %v", err)
+	}
+	if err := hc.store("ds-1", entry); err != nil {
+		t.Fatalf("store() unexpected error: %v", err)
+	}
+
+	hc, err = loadHashCache(path)
+	if err != nil {
+		t.Fatalf("loadHashCache() unexpected error: %v", err)
+	}
+	return hc
+}
+
+func TestHashCacheChanged(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, fp string)
+		want   bool
+	}{
+		{name: "hit", modify: func(*testing.T, string) {}},
+		{
+			name: "touched without changing the content",
+			modify: func(t *testing.T, fp string) {
+				later := time.Now().Add(time.Hour)
+				if err := os.Chtimes(fp, later, later); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "content changed with the mtime",
+			modify: func(t *testing.T, fp string) {
+				if err := os.WriteFile(fp, []byte("9876543210"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				later := time.Now().Add(time.Hour)
+				if err := os.Chtimes(fp, later, later); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: true,
+		},
+		{
+			name: "size changed",
+			modify: func(t *testing.T, fp string) {
+				info, err := os.Stat(fp)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(fp, []byte("01234567890"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				// keep the mtime, so only the size tells the change.
+				if err := os.Chtimes(fp, info.ModTime(), info.ModTime()); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			fp := filepath.Join(dir, "tiles.pmtiles")
+			if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			hc := storedCache(t, dir, fp)
+
+			tt.modify(t, fp)
+			got, _, err := hc.changed("ds-1", fp)
+			if err != nil {
+				t.Fatalf("changed() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("changed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashCachePersistsTouch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	hc := storedCache(t, dir, fp)
+
+	later := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(fp, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _, err := hc.changed("ds-1", fp); err != nil || changed {
+		t.Fatalf("changed() = %v, %v, want an unchanged file", changed, err)
+	}
+
+	// the new mtime survives a restart, so the file is not hashed again.
+	hc, err := loadHashCache(filepath.Join(dir, "cache", "hashes.json"))
+	if err != nil {
+		t.Fatalf("loadHashCache() unexpected error: %v", err)
+	}
+	if got := hc.entries["ds-1"].ModTime; !got.Equal(later) {
+		t.Fatalf("ModTime = %v, want %v", got, later)
+	}
+}
+
+func TestLoadHashCache(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	hc, err := loadHashCache(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatalf("loadHashCache() unexpected error for a missing cache: %v", err)
+	}
+	if changed, _, err := hc.changed("ds-1", fp); err != nil || !changed {
+		t.Fatalf("changed() = %v, %v, want a file unknown to an empty cache to be changed", changed, err)
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.json")
+	if err := os.WriteFile(corrupt, []byte(`{"ds-1":`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadHashCache(corrupt); err == nil {
+		t.Fatal("expected an error for a corrupt cache")
+	}
+}
@@ -1,11 +1,20 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
@@ -13,12 +22,15 @@ import (
 
 	"github.com/iwpnd/maptiler-go"
 	"github.com/iwpnd/maptiler-go/cmd/maptilerctl/version"
+	"github.com/iwpnd/maptiler-go/maptilertest"
 )
 
 func main() {
 	app := &cli.Command{
 		Name:  "maptilerctl",
 		Usage: "CLI for MapTiler dataset ingestion (create/update/cancel)",
+		// file paths may contain commas, repeat a flag to pass multiple values.
+		DisableSliceFlagSeparator: true,
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:    "host",
@@ -30,11 +42,89 @@ func main() {
 				Usage:   "MapTiler API token (falls back to MAPTILER_TOKEN)",
 				Sources: cli.EnvVars("MAPTILER_TOKEN"),
 			},
+			&cli.StringFlag{
+				Name:    "api-version",
+				Usage:   "Version of the service API, with the default host (defaults to v1)",
+				Sources: cli.EnvVars("MAPTILER_API_VERSION"),
+			},
+			&cli.StringSliceFlag{
+				Name:  "endpoint",
+				Usage: "Replace the path of an endpoint as name=path, e.g. ingest_create=/gateway/ingest, can be given multiple times",
+			},
+			&cli.StringSliceFlag{
+				Name:  "upload-host",
+				Usage: "Only upload parts to hosts matching this pattern, e.g. *.amazonaws.com, can be given multiple times",
+			},
+			&cli.StringFlag{
+				Name:  "upload-redirects",
+				Usage: "Whether part uploads follow redirects: deny, same-host or follow",
+				Value: maptiler.RedirectDeny.String(),
+				Validator: func(s string) error {
+					_, err := redirectPolicy(s)
+					return err
+				},
+			},
+			&cli.StringFlag{
+				Name:    "state-key",
+				Usage:   "Base64 encoded AES key to encrypt journals and distribute files with, as they hold presigned upload URLs",
+				Sources: cli.EnvVars("MAPTILER_STATE_KEY"),
+				Validator: func(s string) error {
+					if s == "" {
+						return nil
+					}
+					_, err := maptiler.ParseStateKey(s)
+					return err
+				},
+			},
+			&cli.StringFlag{
+				Name:    "log-level",
+				Usage:   "Log level of the library and daemons: debug, info, warn or error, debug logs every part",
+				Value:   "info",
+				Sources: cli.EnvVars("MAPTILERCTL_LOG_LEVEL"),
+				Validator: func(s string) error {
+					var l slog.Level
+					return l.UnmarshalText([]byte(s))
+				},
+			},
+			&cli.BoolFlag{
+				Name:    "read-only",
+				Usage:   "Only get ingests and datasets, reject commands that would change anything, e.g. for dashboards and audits",
+				Sources: cli.EnvVars("MAPTILER_READ_ONLY"),
+			},
+			&cli.BoolFlag{
+				Name:  "debug-uploads",
+				Usage: "Print the headers sent with a failing part upload and the error of the upload target",
+			},
+			&cli.IntFlag{
+				Name:    "retries",
+				Usage:   "Retry calls to the service API failing with 429 or 5xx up to this many times with backoff, honoring Retry-After, parts too unless --part-retries is given (0 = only retry 429, 502 and 503 up to 3 times)",
+				Sources: cli.EnvVars("MAPTILERCTL_RETRIES"),
+			},
+			&cli.IntFlag{
+				Name:    "api-rate-limit",
+				Usage:   "Maximum calls to the service API per second, e.g. for bulk cancels and gets (0 = unlimited)",
+				Sources: cli.EnvVars("MAPTILER_API_RATE_LIMIT"),
+			},
 			&cli.DurationFlag{
 				Name:  "timeout",
 				Usage: "Request timeout (0 = no explicit timeout)",
 				Value: 10 * time.Minute,
 			},
+			&cli.IntFlag{
+				Name:  "concurrency",
+				Usage: "Number of parts uploaded at the same time (0 = derived from the CPU limit of the container, at most 10)",
+			},
+			&cli.Int64Flag{
+				Name:  "max-in-flight",
+				Usage: "Maximum bytes of parts uploaded at the same time (0 = a quarter of the memory limit of the container, -1 = unlimited)",
+			},
+		},
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			if err := logLevel.UnmarshalText([]byte(cmd.String("log-level"))); err != nil {
+				return ctx, err
+			}
+			slog.SetLogLoggerLevel(logLevel.Level())
+			return ctx, nil
 		},
 		Commands: []*cli.Command{
 			{
@@ -59,6 +149,24 @@ func main() {
 						Usage:    "Dataset ID to get",
 						Required: true,
 					},
+					&cli.BoolFlag{
+						Name:  "stats",
+						Usage: "Include the layer statistics of the resulting tileset",
+					},
+					&cli.StringFlag{
+						Name:    "key",
+						Usage:   "MapTiler API key used to read the layer statistics",
+						Sources: cli.EnvVars("MAPTILER_KEY"),
+					},
+					&cli.BoolFlag{
+						Name:  "follow",
+						Usage: "Print every change of the state and progress to stderr until the ingest ended",
+					},
+					&cli.DurationFlag{
+						Name:  "poll-interval",
+						Usage: "How often --follow gets the state of the ingest",
+						Value: 5 * time.Second,
+					},
 				},
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					c, cctx, cancel, err := newClientWithContext(ctx, cmd)
@@ -68,25 +176,126 @@ func main() {
 					defer cancel()
 
 					id := cmd.String("id")
-					ir, err := c.Get(cctx, id)
+					var ir maptiler.IngestGetResponse
+					if cmd.Bool("follow") {
+						ir, err = follow(cctx, c, id, cmd.Duration("poll-interval"))
+					} else {
+						ir, err = c.Get(cctx, id)
+					}
 					if err != nil {
 						return err
 					}
-					fmt.Println(ir.String())
+					if !cmd.Bool("stats") {
+						fmt.Println(ir.String())
+						printWarnings(ir.ID, ir.Warnings)
+						return nil
+					}
+
+					if cmd.String("key") == "" {
+						return errors.New(msg("get.stats_key"))
+					}
+					if ir.DocumentID == "" {
+						return errors.New(msg("get.no_dataset", ir.ID))
+					}
+					stats, err := c.LayerStats(cctx, ir.DocumentID, cmd.String("key"))
+					if err != nil {
+						return err
+					}
+					b, err := json.MarshalIndent(struct {
+						maptiler.IngestGetResponse
+						Layers []maptiler.LayerStats `json:"layers"`
+					}{ir, stats}, "", "  ")
+					if err != nil {
+						return err
+					}
+					fmt.Println(string(b))
+					printWarnings(ir.ID, ir.Warnings)
 					return nil
 				},
 			},
 			{
-				Name:  "create",
-				Usage: "Create a new dataset ingestion from a file",
+				Name:  "export",
+				Usage: "export a dataset as stable resource json",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
+						Name:     "id",
+						Usage:    "Dataset ID to export",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Output format, one of json, tf-json",
+						Value: "json",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					c, cctx, cancel, err := newClientWithContext(ctx, cmd)
+					if err != nil {
+						return err
+					}
+					defer cancel()
+
+					d, err := c.GetDataset(cctx, cmd.String("id"))
+					if err != nil {
+						return err
+					}
+
+					r := maptiler.NewDatasetResource(d)
+					switch format := cmd.String("format"); format {
+					case "json":
+						fmt.Println(r.String())
+					case "tf-json":
+						b, err := r.TerraformJSON()
+						if err != nil {
+							return err
+						}
+						fmt.Println(string(b))
+					default:
+						return errors.New(msg("export.unknown_format", format))
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "create",
+				Usage: "Create a new dataset ingestion from a file, or one per file if given multiple times",
+				Flags: append([]cli.Flag{
+					&cli.StringSliceFlag{
 						Name:     "file",
 						Aliases:  []string{"f"},
-						Usage:    "Path to the dataset file to ingest",
+						Usage:    "Path to the dataset file to ingest, - reads it from stdin and requires --name",
 						Required: true,
 					},
-				},
+					&cli.StringFlag{
+						Name:  "report",
+						Usage: "Write a JSON summary of all ingests to this file",
+					},
+					&cli.StringFlag{
+						Name:  "journal",
+						Usage: "Record the upload in this file, to continue it with resume if it is interrupted",
+					},
+					&cli.StringSliceFlag{
+						Name:  "priority",
+						Usage: "Priority class critical, normal or bulk of all files, or of a single one as path=class (repeatable). Critical files are ingested first, bulk files last and with a quarter of the concurrency",
+					},
+					&cli.BoolFlag{
+						Name:  "unique-name",
+						Usage: "Fail if a dataset with the same name was created from this host before",
+					},
+					&cli.BoolFlag{
+						Name:  "upsert",
+						Usage: "Update the dataset with the same name created from this host before instead of creating another one",
+					},
+					&cli.BoolFlag{
+						Name:  "wait",
+						Usage: "Wait until the datasets are processed and print their tilesets",
+					},
+					&cli.DurationFlag{
+						Name:  "poll-interval",
+						Usage: "How often to check whether the datasets are processed, with --wait",
+						Value: 5 * time.Second,
+					},
+				}, ingestFlags()...),
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					c, cctx, cancel, err := newClientWithContext(ctx, cmd)
 					if err != nil {
@@ -94,19 +303,63 @@ func main() {
 					}
 					defer cancel()
 
-					fp := cmd.String("file")
-					ir, err := c.Create(cctx, fp)
+					fps := cmd.StringSlice("file")
+					if len(fps) > 1 && cmd.String("filename") != "" {
+						return errors.New(msg("create.name_single_file"))
+					}
+					if len(fps) > 1 && cmd.String("journal") != "" {
+						return errors.New(msg("create.journal_single"))
+					}
+					stdin := slices.Contains(fps, stdinPath)
+					if stdin && (len(fps) > 1 || cmd.String("filename") == "") {
+						return errors.New(msg("create.stdin_name"))
+					}
+					for _, fp := range fps {
+						if fp != stdinPath {
+							warnSparse(fp)
+						}
+					}
+					ctl := maptiler.NewUploadControl()
+					defer pauseOnSignal(ctl)()
+					opts := ingestOptions(cmd, ctl)
+					if upsert := cmd.Bool("upsert"); upsert || cmd.Bool("unique-name") {
+						opts = append(opts, maptiler.WithDuplicateCheck(upsert))
+					}
+					popts, err := priorityOptions(cmd.StringSlice("priority"))
 					if err != nil {
 						return err
 					}
-					fmt.Println(ir.String())
+					opts = append(opts, popts...)
+					var irs []maptiler.IngestResponse
+					if stdin {
+						irs, err = createFromStdin(cctx, c, cmd, opts)
+					} else {
+						irs, err = c.CreateAll(cctx, fps, opts...)
+					}
+					if cmd.Bool("wait") {
+						err = errors.Join(err, waitAll(cctx, c, irs, cmd.Duration("poll-interval")))
+					}
+					for _, ir := range irs {
+						if ir.ID != "" {
+							fmt.Println(ir.String())
+							printWarnings(ir.ID, ir.Warnings)
+						}
+					}
+					if path := cmd.String("report"); path != "" {
+						if rerr := writeReport(path, maptiler.NewReport(fps, irs, err)); rerr != nil {
+							return errors.Join(withGuardrailHint(err), rerr)
+						}
+					}
+					if err != nil {
+						return withGuardrailHint(err)
+					}
 					return nil
 				},
 			},
 			{
 				Name:  "update",
 				Usage: "Update an existing dataset ingestion by dataset ID using a file",
-				Flags: []cli.Flag{
+				Flags: append([]cli.Flag{
 					&cli.StringFlag{
 						Name:     "id",
 						Usage:    "Dataset ID to update",
@@ -118,7 +371,27 @@ func main() {
 						Usage:    "Path to the dataset file to ingest",
 						Required: true,
 					},
-				},
+					&cli.StringFlag{
+						Name:  "journal",
+						Usage: "Record the upload in this file, to continue it with resume if it is interrupted",
+					},
+					&cli.BoolFlag{
+						Name:  "dataset-lock",
+						Usage: "Fail if another process on this host is updating the same dataset",
+					},
+					&cli.DurationFlag{
+						Name:  "dataset-lock-wait",
+						Usage: "With --dataset-lock, wait up to this long for the other update to finish",
+					},
+					&cli.BoolFlag{
+						Name:  "skip-unchanged",
+						Usage: "Skip the update if the dataset already holds the file, by its sha256 checksum",
+					},
+					&cli.BoolFlag{
+						Name:  "force-cancel-existing",
+						Usage: "Cancel the previous ingest of the dataset if it is still in progress, instead of failing",
+					},
+				}, ingestFlags()...),
 				Action: func(ctx context.Context, cmd *cli.Command) error {
 					c, cctx, cancel, err := newClientWithContext(ctx, cmd)
 					if err != nil {
@@ -128,11 +401,99 @@ func main() {
 
 					id := cmd.String("id")
 					fp := cmd.String("file")
-					ir, err := c.Update(cctx, id, fp)
+					warnSparse(fp)
+					ctl := maptiler.NewUploadControl()
+					defer pauseOnSignal(ctl)()
+					opts := append(ingestOptions(cmd, ctl), maptiler.WithConflictCheck(cmd.Bool("force-cancel-existing")))
+					if cmd.Bool("dataset-lock") {
+						opts = append(opts, maptiler.WithDatasetLock(cmd.Duration("dataset-lock-wait")))
+					}
+					ir, err := c.Update(cctx, id, fp, opts...)
+					if err != nil {
+						return withGuardrailHint(err)
+					}
+					fmt.Println(ir.String())
+					printWarnings(ir.ID, ir.Warnings)
+					return nil
+				},
+			},
+			{
+				Name:  "upsert",
+				Usage: "Update the dataset with the given name, or create it if there is none",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
+						Aliases:  []string{"f"},
+						Usage:    "Path to the dataset file to ingest",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "skip-unchanged",
+						Usage: "Skip the update if the dataset already holds the file, by its sha256 checksum",
+					},
+				}, ingestFlags()...),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					c, cctx, cancel, err := newClientWithContext(ctx, cmd)
 					if err != nil {
 						return err
 					}
+					defer cancel()
+
+					fp := cmd.String("file")
+					warnSparse(fp)
+					ctl := maptiler.NewUploadControl()
+					defer pauseOnSignal(ctl)()
+					ir, action, err := c.Upsert(cctx, cmd.String("name"), fp, ingestOptions(cmd, ctl)...)
+					if err != nil {
+						return withGuardrailHint(err)
+					}
 					fmt.Println(ir.String())
+					printWarnings(ir.ID, ir.Warnings)
+					fmt.Fprintln(os.Stderr, msg("upsert.action", action, ir.DocumentID))
+					return nil
+				},
+			},
+			{
+				Name:  "resume",
+				Usage: "Continue an interrupted upload recorded with --journal and finalize the ingest",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:     "journal",
+						Usage:    "Path to the journal of the upload",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Finalize the ingest even if the journal records that its finalize was sent, it may be processed twice",
+					},
+					&cli.BoolFlag{
+						Name:  "prune-journals",
+						Usage: "Remove the other *.journal files next to --journal whose ingests can no longer be resumed, e.g. because they completed",
+						Value: true,
+					},
+				}, ingestFlags()...),
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					c, cctx, cancel, err := newClientWithContext(ctx, cmd)
+					if err != nil {
+						return err
+					}
+					defer cancel()
+
+					if cmd.Bool("prune-journals") {
+						pruneJournals(cctx, c, cmd.String("journal"))
+					}
+					ctl := maptiler.NewUploadControl()
+					defer pauseOnSignal(ctl)()
+					opts := ingestOptions(cmd, ctl)
+					if cmd.Bool("force") {
+						opts = append(opts, maptiler.WithForceFinalize())
+					}
+					ir, err := c.Resume(cctx, cmd.String("journal"), opts...)
+					if err != nil {
+						return withGuardrailHint(err)
+					}
+					fmt.Println(ir.String())
+					printWarnings(ir.ID, ir.Warnings)
 					return nil
 				},
 			},
@@ -163,10 +524,79 @@ func main() {
 					return nil
 				},
 			},
+			{
+				Name:  "delete",
+				Usage: "Delete datasets by dataset ID, e.g. the throwaway datasets of integration tests",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:     "id",
+						Usage:    "Dataset ID to delete, can be given multiple times",
+						Required: true,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					c, cctx, cancel, err := newClientWithContext(ctx, cmd)
+					if err != nil {
+						return err
+					}
+					defer cancel()
+
+					var errs []error
+					for _, id := range cmd.StringSlice("id") {
+						if err := c.Delete(cctx, id); err != nil {
+							errs = append(errs, fmt.Errorf("%s: %w", id, err))
+							continue
+						}
+						fmt.Fprintln(os.Stderr, msg("delete.done", id)) //nolint:errcheck
+					}
+					return errors.Join(errs...)
+				},
+			},
+			{
+				Name:  "recover",
+				Usage: "Cancel a stale ingest left behind by an interrupted update",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "id",
+						Usage:    "Dataset ID to recover",
+						Required: true,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					c, cctx, cancel, err := newClientWithContext(ctx, cmd)
+					if err != nil {
+						return err
+					}
+					defer cancel()
+
+					r, err := c.Recover(cctx, cmd.String("id"))
+					if err != nil {
+						return err
+					}
+					fmt.Println(r.String())
+					return nil
+				},
+			},
+			watchCommand(),
+			openCommand(),
+			previewCommand(),
+			diffCommand(),
+			estimateCommand(),
+			tokenCommand(),
+			distributeCommand(),
+			backfillCommand(),
+			soakCommand(),
 		},
 	}
 
+	if path := os.Getenv(messagesEnv); path != "" {
+		if err := loadMessages(app, path); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	if err := app.Run(context.Background(), os.Args); err != nil {
+		printUploadDebug(err)
 		log.Fatal(err)
 	}
 }
@@ -177,7 +607,15 @@ func newClientWithContext(parent context.Context, cmd *cli.Command) (*maptiler.C
 	host := cmd.String("host")
 	token := cmd.String("token")
 
-	c, err := maptiler.New(host, token)
+	opts := clientOptions(cmd)
+	if n := cmd.Int("fail-after-part"); n > 0 {
+		o, err := failAfterPart(host, n)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		opts = append(opts, o)
+	}
+	c, err := maptiler.New(host, token, opts...)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -194,3 +632,356 @@ func newClientWithContext(parent context.Context, cmd *cli.Command) (*maptiler.C
 
 	return c, sigCtx, stop, nil
 }
+
+// bandwidthFlags are the flags limiting the upload throughput of an ingest,
+// shared by the ingest commands and watch.
+func bandwidthFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.Int64Flag{
+			Name:  "bandwidth",
+			Usage: "Limit the combined upload throughput in bytes per second (0 = unlimited)",
+		},
+		&cli.StringFlag{
+			Name:  "bandwidth-schedule",
+			Usage: "Change --bandwidth by the time of day with windows of HH:MM-HH:MM=BPS, e.g. 01:00-06:00=0 for no limit at night",
+			Validator: func(s string) error {
+				_, err := maptiler.ParseBandwidthSchedule(s)
+				return err
+			},
+		},
+	}
+}
+
+// failAfterPart returns the option failing every part upload once n parts
+// were uploaded, wrapping the transport of the other options. It refuses the
+// default host, failures are only injected into uploads to staging environments.
+func failAfterPart(host string, n int) (maptiler.Option, error) {
+	u, err := url.Parse(host)
+	def, _ := url.Parse(maptiler.DefaultServiceHost)
+	if host == "" || err != nil || strings.EqualFold(u.Hostname(), def.Hostname()) {
+		return nil, errors.New(msg("fail_after_part.host"))
+	}
+	return maptiler.WithTransportWrapper(func(rt http.RoundTripper) http.RoundTripper {
+		tr := maptilertest.NewTransport(maptilertest.Faults{FailPartsAfter: n})
+		tr.Base = rt
+		return tr
+	}), nil
+}
+
+// ingestFlags are the flags shared by commands that ingest a file.
+func ingestFlags() []cli.Flag {
+	return append(bandwidthFlags(), []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "lock",
+			Usage: "Hold an exclusive lock on the file during the upload, fail if it is locked by another process",
+		},
+		&cli.BoolFlag{
+			Name:  "no-symlinks",
+			Usage: "Reject files that are symlinks instead of following them",
+		},
+		&cli.BoolFlag{
+			Name:  "reject-sparse",
+			Usage: "Reject sparse files instead of uploading their holes as zeros",
+		},
+		&cli.Int64Flag{
+			Name:  "max-size",
+			Usage: "Reject files larger than this many bytes (0 = no limit)",
+		},
+		&cli.BoolFlag{
+			Name:  "allow-any",
+			Usage: "Skip the file extension and size checks",
+		},
+		&cli.Int64Flag{
+			Name:  "part-size",
+			Usage: "Ask the service for parts of this many bytes (0 = chosen by the service)",
+		},
+		&cli.Int64Flag{
+			Name:  "part-bandwidth",
+			Usage: "Limit the upload throughput of each part connection in bytes per second (0 = unlimited)",
+		},
+		&cli.IntFlag{
+			Name:  "part-retries",
+			Usage: "Retry a part failing with a transient error up to this many times",
+		},
+		&cli.DurationFlag{
+			Name:  "part-backoff",
+			Usage: "With --part-retries, wait this long before the first retry of a part and double it with jitter for every further one (0 = 1s between all retries)",
+		},
+		&cli.DurationFlag{
+			Name:  "part-backoff-max",
+			Usage: "Upper bound of the wait between retries of a part with --part-backoff",
+			Value: 30 * time.Second,
+		},
+		&cli.IntFlag{
+			Name:  "retry-budget",
+			Usage: "Maximum number of part retries across the whole ingest (0 = unlimited)",
+		},
+		&cli.DurationFlag{
+			Name:  "retry-budget-time",
+			Usage: "Maximum time spent retrying parts across the whole ingest (0 = unlimited)",
+		},
+		&cli.IntFlag{
+			Name:  "circuit-breaker",
+			Usage: "Fail the ingest after this many consecutive part failures to the same host (0 = disabled)",
+		},
+		&cli.IntFlag{
+			Name:   "fail-after-part",
+			Usage:  "Fail every part upload once this many parts were uploaded, to verify cancel and resume against a staging --host",
+			Hidden: true,
+		},
+		&cli.StringSliceFlag{
+			Name:  "metadata",
+			Usage: "Attach key=value to the ingest, e.g. pipeline=nightly, can be given multiple times",
+			Validator: func(values []string) error {
+				_, err := metadata(values)
+				return err
+			},
+		},
+		&cli.BoolFlag{
+			Name:  "progress",
+			Usage: "Print the upload progress to stderr",
+		},
+		&cli.DurationFlag{
+			Name:  "drain-timeout",
+			Usage: "On interrupt, let parts in flight finish for up to this long before aborting (0 = abort immediately)",
+		},
+		&cli.StringFlag{
+			Name:    "filename",
+			Aliases: []string{"name"},
+			Usage:   "Dataset name in MapTiler Cloud instead of the name of the file, the file extension is appended if missing",
+		},
+	}...)
+}
+
+// priorityOptions returns the options of the values of --priority, a class
+// for all files or path=class for a single one.
+func priorityOptions(values []string) ([]maptiler.IngestOption, error) {
+	var opts []maptiler.IngestOption
+	files := make(map[string]maptiler.Priority)
+	for _, v := range values {
+		// paths may contain =, the class never does.
+		path, class := "", v
+		if i := strings.LastIndex(v, "="); i >= 0 {
+			path, class = v[:i], v[i+1:]
+		}
+		p, err := maptiler.ParsePriority(class)
+		if err != nil {
+			return nil, err
+		}
+		if path == "" {
+			opts = append(opts, maptiler.WithPriority(p))
+			continue
+		}
+		files[path] = p
+	}
+	if len(files) > 0 {
+		opts = append(opts, maptiler.WithFilePriorities(files))
+	}
+	return opts, nil
+}
+
+// bandwidthOptions returns the options of the bandwidthFlags.
+func bandwidthOptions(cmd *cli.Command) []maptiler.IngestOption {
+	var opts []maptiler.IngestOption
+	if n := cmd.Int64("bandwidth"); n > 0 {
+		opts = append(opts, maptiler.WithBandwidthLimit(n))
+	}
+	if v := cmd.String("bandwidth-schedule"); v != "" {
+		// validated by the flag.
+		schedule, _ := maptiler.ParseBandwidthSchedule(v)
+		opts = append(opts, maptiler.WithBandwidthSchedule(schedule))
+	}
+	return opts
+}
+
+// ingestOptions translates the ingest flags of a command into IngestOptions.
+// The uploads are paused and resumed by ctl, if it is not nil.
+func ingestOptions(cmd *cli.Command, ctl *maptiler.UploadControl) []maptiler.IngestOption {
+	opts := bandwidthOptions(cmd)
+	if ctl != nil {
+		opts = append(opts, maptiler.WithUploadControl(ctl))
+	}
+	if cmd.Bool("progress") {
+		opts = append(opts, maptiler.WithProgress(printProgress(os.Stderr)))
+	}
+	if cmd.Bool("lock") {
+		opts = append(opts, maptiler.WithFileLock())
+	}
+	if cmd.Bool("no-symlinks") {
+		opts = append(opts, maptiler.WithRejectSymlinks())
+	}
+	if cmd.Bool("reject-sparse") {
+		opts = append(opts, maptiler.WithRejectSparse())
+	}
+	if n := cmd.Int64("part-size"); n > 0 {
+		opts = append(opts, maptiler.WithPartSize(n))
+	}
+	if n := cmd.Int64("part-bandwidth"); n > 0 {
+		opts = append(opts, maptiler.WithPartBandwidthLimit(n))
+	}
+	if n := cmd.Int("part-retries"); n > 0 {
+		opts = append(opts,
+			maptiler.WithPartRetries(n),
+			maptiler.WithRetryBudget(cmd.Int("retry-budget"), cmd.Duration("retry-budget-time")),
+		)
+		if base := cmd.Duration("part-backoff"); base > 0 {
+			opts = append(opts, maptiler.WithPartRetryPolicy(maptiler.ExponentialBackoff{
+				Attempts: n,
+				Base:     base,
+				Max:      cmd.Duration("part-backoff-max"),
+			}))
+		}
+	}
+	if n := cmd.Int("circuit-breaker"); n > 0 {
+		opts = append(opts, maptiler.WithCircuitBreaker(n))
+	}
+	if d := cmd.Duration("drain-timeout"); d > 0 {
+		opts = append(opts, maptiler.WithDrainTimeout(d))
+	}
+	if name := cmd.String("filename"); name != "" {
+		opts = append(opts, maptiler.WithFilename(name))
+	}
+	if path := cmd.String("journal"); path != "" {
+		opts = append(opts, maptiler.WithJournal(path))
+	}
+	if md, err := metadata(cmd.StringSlice("metadata")); err == nil && len(md) > 0 {
+		opts = append(opts, maptiler.WithMetadata(md))
+	}
+	if !cmd.Bool("allow-any") {
+		opts = append(opts, maptiler.WithAllowedExtensions(maptiler.SupportedExtensions()...))
+		if n := cmd.Int64("max-size"); n > 0 {
+			opts = append(opts, maptiler.WithMaxSize(n))
+		}
+	}
+	return opts
+}
+
+// metadata parses key=value pairs of --metadata.
+func metadata(values []string) (map[string]string, error) {
+	md := make(map[string]string, len(values))
+	for _, v := range values {
+		k, val, ok := strings.Cut(v, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid metadata %q, expected key=value", v)
+		}
+		md[k] = val
+	}
+	return md, nil
+}
+
+// withGuardrailHint points the user to the flag that skips the check an ingest was rejected by.
+func withGuardrailHint(err error) error {
+	if errors.Is(err, maptiler.ErrUnsupportedExtension) || errors.Is(err, maptiler.ErrFileTooLarge) ||
+		errors.Is(err, maptiler.ErrSpoolLimit) {
+		return fmt.Errorf("%w (%s)", err, msg("hint.allow_any"))
+	}
+	if errors.Is(err, maptiler.ErrDatasetBusy) {
+		return fmt.Errorf("%w (%s)", err, msg("hint.force_cancel"))
+	}
+	if errors.Is(err, maptiler.ErrDuplicateName) {
+		return fmt.Errorf("%w (%s)", err, msg("hint.upsert"))
+	}
+	if errors.Is(err, maptiler.ErrJournalVersion) {
+		return fmt.Errorf("%w (%s)", err, msg("hint.journal_version"))
+	}
+	if errors.Is(err, maptiler.ErrFinalizeSent) {
+		return fmt.Errorf("%w (%s)", err, msg("hint.finalize_sent"))
+	}
+	if errors.Is(err, maptiler.ErrStateEncrypted) {
+		return fmt.Errorf("%w (%s)", err, msg("hint.state_key"))
+	}
+	var aerr maptiler.AuthError
+	if errors.As(err, &aerr) {
+		return fmt.Errorf("%w (%s)", err, msg("hint.auth"))
+	}
+	var uerr maptiler.UploadFailedError
+	if errors.As(err, &uerr) && uerr.Journal != "" {
+		return fmt.Errorf("%w (%s)", err, msg("hint.resume", uerr.Journal))
+	}
+	return err
+}
+
+// pruneJournals removes the other journals in the directory of journal whose
+// ingests can no longer be resumed, and prints what was removed. Journals that
+// can not be checked are kept with a warning, they do not stop the resume.
+func pruneJournals(ctx context.Context, c *maptiler.Client, journal string) {
+	paths, _ := filepath.Glob(filepath.Join(filepath.Dir(journal), "*.journal")) // the pattern is valid.
+	paths = slices.DeleteFunc(paths, func(p string) bool { return p == filepath.Clean(journal) })
+	res, _ := c.PruneJournals(ctx, paths...) // failures are reported per journal.
+	for _, p := range res {
+		switch {
+		case p.Removed:
+			fmt.Fprintln(os.Stderr, msg("resume.pruned", p.Path, p.IngestID, cmp.Or(p.State, "deleted")))
+		case p.Error != "":
+			fmt.Fprintln(os.Stderr, msg("resume.prune_failed", p.Path, p.Error))
+		}
+	}
+}
+
+// writeReport writes the report of a batch to path.
+func writeReport(path string, r maptiler.Report) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding report: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	return nil
+}
+
+// waitAll waits until the created ingests in irs are processed, in place. Failed
+// creates are skipped.
+func waitAll(ctx context.Context, c *maptiler.Client, irs []maptiler.IngestResponse, interval time.Duration) error {
+	var errs []error
+	for i, ir := range irs {
+		if ir.ID == "" {
+			continue
+		}
+		wr, err := c.Wait(ctx, ir, maptiler.WithPollInterval(interval))
+		irs[i] = wr
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// warnSparse prints a warning if the file at fp is sparse, as its holes are uploaded as zeros.
+func warnSparse(fp string) {
+	st, err := maptiler.StatFile(fp)
+	if err != nil || !st.Sparse() {
+		return
+	}
+	fmt.Fprintln(os.Stderr, msg("warn.sparse", fp, st.Size, st.AllocatedSize)) //nolint:errcheck
+}
+
+// printWarnings prints the processing warnings of an ingest to stderr, so they
+// stand out from the JSON response on stdout.
+func printWarnings(id string, warnings []maptiler.MapTilerError) {
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, msg("warn.ingest", id, w.Message))
+	}
+}
+
+// printUploadDebug prints the request and the S3 error document of a failing
+// part upload to stderr, for --debug-uploads.
+func printUploadDebug(err error) {
+	var aerr maptiler.APIError
+	if !errors.As(err, &aerr) {
+		return
+	}
+	if aerr.Request != nil {
+		fmt.Fprintln(os.Stderr, msg("debug.request"))
+		fmt.Fprintln(os.Stderr, aerr.Request.String())
+	}
+	if s3 := aerr.S3; s3 != nil {
+		fmt.Fprintln(os.Stderr, msg("debug.s3", s3.Code, s3.Message, s3.RequestID, s3.HostID))
+		if s3.CanonicalRequest != "" {
+			fmt.Fprintln(os.Stderr, msg("debug.canonical_request", s3.CanonicalRequest))
+		}
+		if s3.StringToSign != "" {
+			fmt.Fprintln(os.Stderr, msg("debug.string_to_sign", s3.StringToSign))
+		}
+	}
+}
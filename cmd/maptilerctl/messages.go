@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+// messagesEnv names a JSON file that replaces messages by ID, e.g.
+// {"hint.allow_any": "use --allow-any to ingest it anyway"}. Distributions use
+// it to ship translated or rebranded wording. Besides the IDs below, the usage
+// of commands and flags is replaced by "usage.<command>" and
+// "usage.<command>.<flag>", e.g. "usage.create.file", and "usage.<flag>" for
+// the global flags.
+const messagesEnv = "MAPTILERCTL_MESSAGES"
+
+// messages are the user facing strings of maptilerctl by stable ID, formatted
+// with fmt. IDs must not change, wording may.
+var messages = map[string]string{
+	"hint.allow_any":          "use --allow-any to skip this check",
+	"hint.force_cancel":       "use --force-cancel-existing to cancel it",
+	"hint.upsert":             "use --upsert to update it",
+	"hint.resume":             "the ingest was kept, continue it with maptilerctl resume --journal %s",
+	"hint.journal_version":    "the journal was written by a newer maptilerctl, resume it with that version",
+	"hint.state_key":          "pass the key it was written with as --state-key or MAPTILER_STATE_KEY",
+	"hint.finalize_sent":      "check the ingest with maptilerctl get, use --force to finalize it again",
+	"hint.auth":               "check the token, maptilerctl token inspect shows what it may do",
+	"warn.sparse":             "warning: %s is sparse (size %d, allocated %d), holes are uploaded as zeros",
+	"warn.ingest":             "WARNING: ingest %s: %s",
+	"upsert.action":           "%s dataset %s",
+	"get.stats_key":           "--stats requires --key or MAPTILER_KEY",
+	"get.no_dataset":          "ingest %s has no dataset yet",
+	"get.follow":              "%s %s %s %.0f%%",
+	"create.name_single_file": "--name can only be used with a single --file",
+	"create.journal_single":   "--journal can only be used with a single --file",
+	"create.stdin_name":       "--file - has to be the only --file and requires --name with the file extension of the format, e.g. --name tiles.mbtiles",
+	"fail_after_part.host":    "--fail-after-part requires the --host of a staging environment",
+	"delete.done":             "deleted dataset %s",
+	"resume.pruned":           "removed journal %s, ingest %s is %s",
+	"resume.prune_failed":     "warning: keeping journal %s: %s",
+	"export.unknown_format":   "unknown format %q",
+	"diff.id_twice":           "expected --id exactly twice, got %d",
+	"diff.none":               "no differences",
+	"diff.header":             "FIELD",
+	"estimate.file":           "file:   %s",
+	"estimate.size":           "size:   %d bytes (%.1f MiB)",
+	"estimate.unsupported":    "format: %q is not supported by MapTiler, the ingest would be rejected",
+	"estimate.sparse":         "sparse: %d bytes allocated, holes would be uploaded as zeros",
+	"estimate.header":         "PART SIZE\tPARTS\tLAST PART\tPROBLEM",
+	"open.not_found":          "%s is neither a dataset nor an ingest",
+	"open.no_dataset":         "ingest %s has no dataset yet (state %s)",
+	"open.browser":            "opening browser, use --print-url instead",
+	"token.header":            "CAPABILITY\tENDPOINT\tACCESS\tSTATUS\tERROR",
+	"token.denied":            "the token was rejected, check that it is valid and has the scopes of the denied capabilities",
+	"preview.serving":         "serving preview of %s on http://%s/",
+	"admin.serving":           "serving pprof and metrics on http://%s/debug/",
+	"backfill.done":           "%d/%d %s: %s dataset %s",
+	"backfill.skipped":        "%d/%d %s: skipped, dataset %s was created before",
+	"backfill.failed":         "%d/%d %s: failed: %v",
+	"backfill.summary":        "backfill: %d succeeded, %d failed, %d skipped, %d pending, progress in %s",
+	"backfill.incomplete":     "%d rows were not ingested, run backfill again to retry them",
+	"distribute.begin":        "wrote %d assignments to %s, run distribute upload for each of them",
+	"debug.request":           "part upload request, without payload:",
+	"debug.s3":                "upload target error: code=%s message=%q request_id=%s host_id=%s",
+	"debug.canonical_request": "canonical request of the upload target:\n%s",
+	"debug.string_to_sign":    "string to sign of the upload target:\n%s",
+}
+
+// msg formats the message id with args. Unknown IDs are returned as is, so a
+// missing message is visible instead of silently empty.
+func msg(id string, args ...any) string {
+	format, ok := messages[id]
+	if !ok {
+		return id
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// loadMessages replaces messages and the usage of the commands and flags of
+// app with the catalog in the file at path. Unknown IDs fail, so that typos in
+// a catalog do not go unnoticed.
+func loadMessages(app *cli.Command, path string) error {
+	b, err := os.ReadFile(path) //nolint:gosec // the path is given by the user.
+	if err != nil {
+		return fmt.Errorf("reading messages: %w", err)
+	}
+	var catalog map[string]string
+	if err := json.Unmarshal(b, &catalog); err != nil {
+		return fmt.Errorf("reading messages %s: %w", path, err)
+	}
+
+	usage := make(map[string]string)
+	for id, m := range catalog {
+		if strings.HasPrefix(id, "usage.") {
+			usage[strings.TrimPrefix(id, "usage.")] = m
+			continue
+		}
+		if _, ok := messages[id]; !ok {
+			return fmt.Errorf("reading messages %s: unknown message %q", path, id)
+		}
+		messages[id] = m
+	}
+
+	localizeUsage(app, "", usage)
+	if len(usage) > 0 {
+		unknown := slices.Sorted(maps.Keys(usage))
+		return fmt.Errorf("reading messages %s: unknown command or flag usage.%s", path, strings.Join(unknown, ", usage."))
+	}
+	return nil
+}
+
+// localizeUsage sets the usage of cmd, its flags and its subcommands from usage,
+// keyed by the path of the command below the root, and removes the used keys.
+func localizeUsage(cmd *cli.Command, prefix string, usage map[string]string) {
+	if u, ok := usage[strings.TrimSuffix(prefix, ".")]; ok && prefix != "" {
+		cmd.Usage = u
+		delete(usage, strings.TrimSuffix(prefix, "."))
+	}
+	for _, f := range cmd.Flags {
+		key := prefix + f.Names()[0]
+		if u, ok := usage[key]; ok {
+			setFlagUsage(f, u)
+			delete(usage, key)
+		}
+	}
+	for _, sub := range cmd.Commands {
+		localizeUsage(sub, prefix+sub.Name+".", usage)
+	}
+}
+
+// setFlagUsage sets the usage of the flag types used by maptilerctl.
+func setFlagUsage(f cli.Flag, usage string) {
+	switch f := f.(type) {
+	case *cli.StringFlag:
+		f.Usage = usage
+	case *cli.StringSliceFlag:
+		f.Usage = usage
+	case *cli.BoolFlag:
+		f.Usage = usage
+	case *cli.IntFlag:
+		f.Usage = usage
+	case *cli.Int64Flag:
+		f.Usage = usage
+	case *cli.Int64SliceFlag:
+		f.Usage = usage
+	case *cli.Float64Flag:
+		f.Usage = usage
+	case *cli.DurationFlag:
+		f.Usage = usage
+	}
+}
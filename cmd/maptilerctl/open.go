@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"runtime"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/iwpnd/maptiler-go"
+)
+
+func openCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "open",
+		Usage: "Open the MapTiler Cloud page of a dataset in the default browser",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "id",
+				Usage:    "Dataset ID, or the ID of an ingest of the dataset",
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:  "print-url",
+				Usage: "Print the URL instead of opening a browser",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			c, cctx, cancel, err := newClientWithContext(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			defer cancel()
+
+			id, err := resolveDatasetID(cctx, c, cmd.String("id"))
+			if err != nil {
+				return err
+			}
+
+			u := maptiler.AdminURL(id)
+			if cmd.Bool("print-url") {
+				fmt.Println(u)
+				return nil
+			}
+			return openBrowser(u)
+		},
+	}
+}
+
+// resolveDatasetID returns id if it is a dataset, or the dataset of the ingest id.
+func resolveDatasetID(ctx context.Context, c *maptiler.Client, id string) (string, error) {
+	d, err := c.GetDataset(ctx, id)
+	if err == nil {
+		return d.ID, nil
+	}
+
+	var aerr maptiler.APIError
+	if !errors.As(err, &aerr) || aerr.StatusCode != http.StatusNotFound {
+		return "", err
+	}
+
+	ir, ierr := c.Get(ctx, id)
+	if ierr != nil {
+		return "", fmt.Errorf("%s: %w", msg("open.not_found", id), err)
+	}
+	if ir.DocumentID == "" {
+		return "", errors.New(msg("open.no_dataset", id, ir.State))
+	}
+	return ir.DocumentID, nil
+}
+
+// openBrowser opens u in the default browser of the platform. The opener is not
+// bound to a context, as it must outlive the command.
+func openBrowser(u string) error {
+	var name string
+	var args []string
+	switch runtime.GOOS {
+	case "darwin":
+		name = "open"
+	case "windows":
+		name, args = "rundll32", []string{"url.dll,FileProtocolHandler"}
+	default:
+		name = "xdg-open"
+	}
+
+	//nolint:gosec // u is built from a MapTiler URL and a path escaped ID.
+	if err := exec.Command(name, append(args, u)...).Start(); err != nil {
+		return fmt.Errorf("%s: %w", msg("open.browser"), err)
+	}
+	return nil
+}
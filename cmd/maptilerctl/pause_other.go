@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "github.com/iwpnd/maptiler-go"
+
+// pauseOnSignal does nothing, there are no signals to pause uploads with.
+func pauseOnSignal(*maptiler.UploadControl) func() { return func() {} }
@@ -0,0 +1,35 @@
+//go:build unix
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/iwpnd/maptiler-go"
+)
+
+// pauseOnSignal pauses the uploads of ctl on SIGUSR1 and resumes them on
+// SIGUSR2, until the returned function is called. It restores the default
+// handling of the signals.
+func pauseOnSignal(ctl *maptiler.UploadControl) func() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for s := range sig {
+			if s == syscall.SIGUSR1 {
+				ctl.Pause()
+				slog.Info("upload paused, parts in flight are finished, send SIGUSR2 to resume")
+				continue
+			}
+			ctl.Resume()
+			slog.Info("upload resumed")
+		}
+	}()
+	return func() {
+		signal.Stop(sig)
+		close(sig)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/iwpnd/maptiler-go"
+)
+
+// previewPage renders a full screen MapLibre map of a tileset. The TileJSON is
+// fetched in the browser to tell vector from raster tilesets.
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.ID}} - maptilerctl preview</title>
+<link href="https://unpkg.com/maplibre-gl@4/dist/maplibre-gl.css" rel="stylesheet">
+<script src="https://unpkg.com/maplibre-gl@4/dist/maplibre-gl.js"></script>
+<style>body{margin:0}#map{position:absolute;top:0;bottom:0;width:100%}</style>
+</head>
+<body>
+<div id="map"></div>
+<script>
+const tilejson = {{.TileJSON}};
+const map = new maplibregl.Map({container: "map", style: {version: 8, sources: {}, layers: []}});
+map.addControl(new maplibregl.NavigationControl());
+map.on("load", async () => {
+  const tj = await (await fetch(tilejson)).json();
+  if (tj.bounds) map.fitBounds([[tj.bounds[0], tj.bounds[1]], [tj.bounds[2], tj.bounds[3]]], {animate: false});
+  if (tj.format !== "pbf") {
+    map.addSource("preview", {type: "raster", url: tilejson, tileSize: 256});
+    map.addLayer({id: "preview", type: "raster", source: "preview"});
+    return;
+  }
+  map.addSource("preview", {type: "vector", url: tilejson});
+  for (const l of tj.vector_layers || []) {
+    map.addLayer({id: l.id + "-fill", type: "fill", source: "preview", "source-layer": l.id,
+      filter: ["==", "$type", "Polygon"], paint: {"fill-color": "#3887be", "fill-opacity": 0.3}});
+    map.addLayer({id: l.id + "-line", type: "line", source: "preview", "source-layer": l.id,
+      filter: ["!=", "$type", "Point"], paint: {"line-color": "#3887be"}});
+    map.addLayer({id: l.id + "-point", type: "circle", source: "preview", "source-layer": l.id,
+      filter: ["==", "$type", "Point"], paint: {"circle-color": "#3887be", "circle-radius": 3}});
+  }
+});
+</script>
+</body>
+</html>
+`))
+
+func previewCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "preview",
+		Usage: "Serve a local map preview of the tileset of a dataset",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "id",
+				Usage:    "Dataset ID, or the ID of an ingest of the dataset",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "key",
+				Usage:    "MapTiler API key used by the map to load the tiles",
+				Sources:  cli.EnvVars("MAPTILER_KEY"),
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "listen",
+				Usage: "Address to serve the preview on",
+				Value: "localhost:8081",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			c, cctx, cancel, err := newClientWithContext(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			id, err := resolveDatasetID(cctx, c, cmd.String("id"))
+			cancel()
+			if err != nil {
+				return err
+			}
+
+			sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return servePreview(sigCtx, cmd.String("listen"), id, cmd.String("key"))
+		},
+	}
+}
+
+// servePreview serves the preview page of the dataset id on addr until ctx is done.
+func servePreview(ctx context.Context, addr, id, key string) error {
+	data := struct {
+		ID       string
+		TileJSON string
+	}{
+		ID:       id,
+		TileJSON: maptiler.TilesetURL(id) + "?key=" + url.QueryEscape(key),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := previewPage.Execute(w, data); err != nil {
+			log.Printf("rendering preview: %v", err)
+		}
+	})
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", addr, err)
+	}
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		sctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(sctx) //nolint:errcheck
+	}()
+
+	log.Print(msg("preview.serving", id, ln.Addr()))
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
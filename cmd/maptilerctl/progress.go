@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/iwpnd/maptiler-go"
+)
+
+// printProgress renders progress as a single line that is rewritten in place.
+// For a batch of files the line shows the combined progress.
+func printProgress(w io.Writer) maptiler.ProgressFunc {
+	tracker := maptiler.NewProgressTracker(0)
+	return func(p maptiler.Progress) {
+		tracker.Update(p)
+		s := tracker.Snapshot()
+
+		eta := "-"
+		if s.ETA > 0 {
+			eta = s.ETA.Round(time.Second).String()
+		}
+		//nolint:errcheck
+		fmt.Fprintf(w, "\r%-8s %5.1f%%  %d/%d parts  %.1f/%.1f MiB  %.1f MiB/s  ETA %-10s",
+			p.Phase, p.Percent(), p.PartsDone, p.PartsTotal,
+			mib(p.BytesDone+p.BytesInFlight), mib(p.BytesTotal), s.Rate/(1<<20), eta)
+		if p.Phase == maptiler.PhaseDone {
+			fmt.Fprintln(w) //nolint:errcheck
+		}
+	}
+}
+
+func mib(n int64) float64 {
+	return float64(n) / (1 << 20)
+}
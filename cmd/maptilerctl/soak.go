@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/iwpnd/maptiler-go"
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func soakCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "soak",
+		Usage:  "Run synthetic ingests for a long time and report error rates and memory",
+		Hidden: true,
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:  "duration",
+				Usage: "How long to run ingests for",
+				Value: time.Hour,
+			},
+			&cli.Int64Flag{
+				Name:  "size",
+				Usage: "Size of the synthetic file in bytes",
+				Value: 12 << 20,
+			},
+			&cli.IntFlag{
+				Name:  "workers",
+				Usage: "Number of ingests running at the same time, each updating its own dataset",
+				Value: 1,
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Usage: "How often to report",
+				Value: time.Minute,
+			},
+			&cli.BoolFlag{
+				Name:  "fake",
+				Usage: "Run against an in-process fake of the service API, use --fake=false for --host and --token",
+				Value: true,
+			},
+			&cli.Float64Flag{
+				Name:  "fault-rate",
+				Usage: "Probability of connection resets and truncated responses injected into every request",
+			},
+			adminFlag(),
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			host, token := cmd.String("host"), cmd.String("token")
+			if cmd.Bool("fake") {
+				srv := maptilertest.NewServer()
+				defer srv.Close()
+				host, token = srv.URL, "soak"
+			}
+
+			opts := append(clientOptions(cmd),
+				maptiler.WithRetryPolicy(maptiler.ExponentialBackoff{Attempts: 5, Base: 100 * time.Millisecond, Max: 5 * time.Second}),
+			)
+			if rate := cmd.Float64("fault-rate"); rate > 0 {
+				faults := maptilertest.Faults{ResetRate: rate, TruncateRate: rate, Seed: uint64(time.Now().UnixNano())} //nolint:gosec
+				opts = append(opts, maptiler.WithTransportWrapper(func(rt http.RoundTripper) http.RoundTripper {
+					tr := maptilertest.NewTransport(faults)
+					tr.Base = rt
+					return tr
+				}))
+			}
+			c, err := maptiler.New(host, token, opts...)
+			if err != nil {
+				return err
+			}
+
+			fp, err := writeSoakFile(cmd.Int64("size"))
+			if err != nil {
+				return err
+			}
+			defer os.Remove(fp) //nolint:errcheck
+
+			sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			sctx, cancel := context.WithTimeout(sigCtx, cmd.Duration("duration"))
+			defer cancel()
+
+			if addr := cmd.String("admin-addr"); addr != "" {
+				if err := startAdmin(sctx, addr); err != nil {
+					return err
+				}
+			}
+
+			s := &soak{c: c, fp: fp, timeout: cmd.Duration("timeout")}
+			s.run(sctx, cmd.Int("workers"), cmd.Duration("interval"))
+			if s.failed.Load() > 0 {
+				return cli.Exit("", 1)
+			}
+			return nil
+		},
+	}
+}
+
+// soak runs ingests until its context is done and counts their outcome.
+type soak struct {
+	c       *maptiler.Client
+	fp      string
+	timeout time.Duration
+
+	ok, failed atomic.Int64
+}
+
+func (s *soak) run(ctx context.Context, workers int, interval time.Duration) {
+	start := time.Now()
+	s.report(start)
+
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Go(func() { s.work(ctx) })
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for {
+		select {
+		case <-ticker.C:
+			s.report(start)
+		case <-done:
+			s.report(start)
+			return
+		}
+	}
+}
+
+// work creates a dataset and updates it until ctx is done.
+func (s *soak) work(ctx context.Context) {
+	var datasetID string
+	for ctx.Err() == nil {
+		ir, err := s.ingest(ctx, datasetID)
+		switch {
+		case ctx.Err() != nil:
+			// ingests interrupted by the end of the soak do not count.
+		case err != nil:
+			s.failed.Add(1)
+			fmt.Fprintf(os.Stderr, "ingest failed: %v\n", err)
+		default:
+			s.ok.Add(1)
+			datasetID = ir.DocumentID
+		}
+	}
+}
+
+// ingest creates a dataset, or updates it if datasetID is set.
+func (s *soak) ingest(ctx context.Context, datasetID string) (maptiler.IngestResponse, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	if datasetID == "" {
+		return s.c.Create(ctx, s.fp)
+	}
+	return s.c.Update(ctx, datasetID, s.fp)
+}
+
+// report prints the ingests so far and the memory in use after a collection.
+func (s *soak) report(start time.Time) {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	ok, failed := s.ok.Load(), s.failed.Load()
+	var rate float64
+	if total := ok + failed; total > 0 {
+		rate = float64(failed) / float64(total) * 100
+	}
+	fmt.Fprintf(os.Stderr, "%s ingests=%d failed=%d error_rate=%.2f%% heap=%.1fMiB goroutines=%d\n",
+		time.Since(start).Round(time.Second), ok+failed, failed, rate, mib(int64(m.HeapAlloc)), runtime.NumGoroutine()) //nolint:gosec
+}
+
+// writeSoakFile writes a synthetic file of size bytes.
+func writeSoakFile(size int64) (string, error) {
+	f, err := os.CreateTemp("", "maptilerctl-soak-*.pmtiles")
+	if err != nil {
+		return "", fmt.Errorf("creating soak file: %w", err)
+	}
+	pattern := bytes.Repeat([]byte("maptilerctl soak "), 4096)
+	_, err = io.CopyN(f, &repeatReader{b: pattern}, size)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("writing soak file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// repeatReader endlessly repeats b.
+type repeatReader struct {
+	b   []byte
+	off int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		c := copy(p[n:], r.b[r.off:])
+		n += c
+		r.off = (r.off + c) % len(r.b)
+	}
+	return n, nil
+}
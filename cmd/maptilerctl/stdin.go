@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/iwpnd/maptiler-go"
+)
+
+// stdinPath is the --file that reads the dataset from stdin.
+const stdinPath = "-"
+
+// createFromStdin spools stdin, as parts are read concurrently and again on
+// retries, and creates a dataset named --name from it. Spooling stops once
+// --max-size is exceeded.
+func createFromStdin(ctx context.Context, c *maptiler.Client, cmd *cli.Command, opts []maptiler.IngestOption) ([]maptiler.IngestResponse, error) {
+	var spoolOpts []maptiler.SpoolOption
+	if !cmd.Bool("allow-any") {
+		spoolOpts = append(spoolOpts, maptiler.WithSpoolMaxSize(cmd.Int64("max-size")))
+	}
+	spool, err := maptiler.NewSpool(spoolOpts...)
+	if err != nil {
+		return nil, err
+	}
+	name := cmd.String("filename")
+	sf, err := spool.Spool(os.Stdin, name)
+	if err != nil {
+		return nil, err
+	}
+	defer sf.Close() //nolint:errcheck
+
+	f, err := os.Open(sf.Path)
+	if err != nil {
+		return nil, fmt.Errorf("reading spooled stdin: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	ir, err := c.CreateFrom(ctx, name, sf.Size, f, opts...)
+	return []maptiler.IngestResponse{ir}, err
+}
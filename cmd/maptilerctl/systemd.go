@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sdNotify sends state, e.g. READY=1, to the service manager. It does nothing
+// unless the command runs as a systemd service of Type=notify.
+func sdNotify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	// abstract sockets are given with a leading @.
+	if strings.HasPrefix(addr, "@") {
+		addr = "\x00" + addr[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("notifying systemd: %w", err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("notifying systemd: %w", err)
+	}
+	return nil
+}
+
+// notify sends state to the service manager and logs failures.
+func notify(state string) {
+	if err := sdNotify(state); err != nil {
+		slog.Warn("systemd notification failed", "state", state, "error", err)
+	}
+}
+
+// sdWatchdog pings the watchdog of the service manager at half of its timeout
+// until ctx is done. It does nothing unless WatchdogSec is set for the service.
+func sdWatchdog(ctx context.Context) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(usec) * time.Microsecond / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			notify("WATCHDOG=1")
+		}
+	}
+}
+
+// logLevel is the level of --log-level, for the default logger and the journal.
+var logLevel = new(slog.LevelVar)
+
+// useJournalLogging switches the default logger to journal friendly output if
+// stderr is connected to the journal.
+func useJournalLogging() {
+	if os.Getenv("JOURNAL_STREAM") == "" {
+		return
+	}
+	slog.SetDefault(slog.New(newJournalHandler(os.Stderr)))
+}
+
+// journalHandler writes records as logfmt lines prefixed with their syslog
+// priority, e.g. <4> for warnings, which the journal parses into the PRIORITY
+// field. The journal records the time itself.
+type journalHandler struct {
+	slog.Handler
+	w *priorityWriter
+}
+
+func newJournalHandler(w io.Writer) journalHandler {
+	pw := &priorityWriter{w: w}
+	return journalHandler{
+		Handler: slog.NewTextHandler(pw, &slog.HandlerOptions{
+			Level: logLevel,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if len(groups) == 0 && a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}),
+		w: pw,
+	}
+}
+
+func (h journalHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.w.mu.Lock()
+	defer h.w.mu.Unlock()
+	h.w.priority = syslogPriority(r.Level)
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h journalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return journalHandler{Handler: h.Handler.WithAttrs(attrs), w: h.w}
+}
+
+func (h journalHandler) WithGroup(name string) slog.Handler {
+	return journalHandler{Handler: h.Handler.WithGroup(name), w: h.w}
+}
+
+// priorityWriter prefixes every write with the priority of the record being
+// handled.
+type priorityWriter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	priority int
+}
+
+func (p *priorityWriter) Write(b []byte) (int, error) {
+	if _, err := fmt.Fprintf(p.w, "<%d>", p.priority); err != nil {
+		return 0, err
+	}
+	return p.w.Write(b)
+}
+
+// syslogPriority maps a level to the syslog priorities of sd-daemon(3).
+func syslogPriority(l slog.Level) int {
+	switch {
+	case l >= slog.LevelError:
+		return 3
+	case l >= slog.LevelWarn:
+		return 4
+	case l >= slog.LevelInfo:
+		return 6
+	default:
+		return 7
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/iwpnd/maptiler-go"
+)
+
+func tokenCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "token",
+		Usage: "Inspect the MapTiler API token",
+		Commands: []*cli.Command{
+			{
+				Name:  "inspect",
+				Usage: "Probe read-only endpoints and print what the token may do, without changing anything",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					c, cctx, cancel, err := newClientWithContext(ctx, cmd)
+					if err != nil {
+						return err
+					}
+					defer cancel()
+
+					caps, err := c.InspectToken(cctx)
+					if err != nil {
+						return err
+					}
+
+					tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+					fmt.Fprintln(tw, msg("token.header")) //nolint:errcheck
+					for _, cp := range caps {
+						status := "-"
+						if cp.StatusCode != 0 {
+							status = strconv.Itoa(cp.StatusCode)
+						}
+						//nolint:errcheck
+						fmt.Fprintf(tw, "%s\t%s %s\t%s\t%s\t%s\n",
+							cp.Name, cp.Method, cp.Path, cp.Access, status, orDash(cp.Error))
+					}
+					if err := tw.Flush(); err != nil {
+						return err
+					}
+
+					for _, cp := range caps {
+						if cp.Access == maptiler.AccessDenied {
+							fmt.Fprintln(os.Stderr, msg("token.denied")) //nolint:errcheck
+							break
+						}
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "smoke-test",
+				Usage: "Start an ingest, upload its first part and cancel it, to verify write access and the upload targets without a full upload",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
+						Usage:    "Path to the file to test with",
+						Required: true,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					c, cctx, cancel, err := newClientWithContext(ctx, cmd)
+					if err != nil {
+						return err
+					}
+					defer cancel()
+
+					res, err := c.SmokeTest(cctx, cmd.String("file"))
+					if len(res.Phases) > 0 {
+						fmt.Println(res.String())
+					}
+					return err
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/iwpnd/maptiler-go"
+)
+
+func watchCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "watch",
+		Usage: "Watch a file and update a dataset whenever its content changes",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:     "id",
+				Usage:    "Dataset ID to update",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "file",
+				Aliases:  []string{"f"},
+				Usage:    "Path to the dataset file to watch",
+				Required: true,
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Usage: "How often to check the file for changes",
+				Value: time.Minute,
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Update once on start, even if the file did not change since the last update",
+			},
+			&cli.StringFlag{
+				Name:  "cache",
+				Usage: "Path to the hash cache (defaults to the user cache directory)",
+			},
+			&cli.StringFlag{
+				Name:  "config",
+				Usage: "Path to a JSON file with token, concurrency and endpoints, reloaded on SIGHUP",
+			},
+			adminFlag(),
+		}, bandwidthFlags()...),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			useJournalLogging()
+
+			configPath := cmd.String("config")
+			c, err := newDaemonClient(cmd, configPath)
+			if err != nil {
+				return err
+			}
+
+			cachePath := cmd.String("cache")
+			if cachePath == "" {
+				cachePath, err = defaultHashCachePath()
+				if err != nil {
+					return err
+				}
+			}
+			hc, err := loadHashCache(cachePath)
+			if err != nil {
+				return err
+			}
+
+			// the timeout applies to each update, not to the watch itself.
+			sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if addr := cmd.String("admin-addr"); addr != "" {
+				if err := startAdmin(sigCtx, addr); err != nil {
+					return err
+				}
+			}
+
+			w := &watcher{
+				c:       c,
+				hc:      hc,
+				id:      cmd.String("id"),
+				fp:      maptiler.OSPath(cmd.String("file")),
+				timeout: cmd.Duration("timeout"),
+				force:   cmd.Bool("force"),
+				opts:    bandwidthOptions(cmd),
+			}
+			if configPath != "" {
+				w.reload = func() (*maptiler.Client, error) {
+					return newDaemonClient(cmd, configPath)
+				}
+			}
+
+			go sdWatchdog(sigCtx)
+			notify("READY=1")
+			defer notify("STOPPING=1")
+			return w.run(sigCtx, cmd.Duration("interval"))
+		},
+	}
+}
+
+// watcher polls a file and updates a dataset when the content of the file changed.
+type watcher struct {
+	c       *maptiler.Client
+	hc      *hashCache
+	id      string
+	fp      string
+	timeout time.Duration
+	force   bool
+	opts    []maptiler.IngestOption
+	// reload creates a client from the reloaded configuration on SIGHUP.
+	reload func() (*maptiler.Client, error)
+}
+
+// run checks the file every interval until ctx is done. Failed checks are
+// logged and retried on the next tick instead of stopping the watch.
+func (w *watcher) run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive, got %s", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// a reload takes effect between checks, so updates in flight keep their client.
+	var hup chan os.Signal
+	if w.reload != nil {
+		hup = make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+	}
+
+	for {
+		if err := w.check(ctx); err != nil {
+			slog.Error("checking file failed", "file", w.fp, "dataset_id", w.id, "error", err)
+		}
+
+		if w.wait(ctx, ticker.C, hup) {
+			return nil
+		}
+	}
+}
+
+// wait blocks until the next tick and reloads the configuration on SIGHUP in
+// the meantime. It reports whether ctx is done.
+func (w *watcher) wait(ctx context.Context, tick <-chan time.Time, hup <-chan os.Signal) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return true
+		case <-tick:
+			return false
+		case <-hup:
+			c, err := w.reload()
+			if err != nil {
+				slog.Error("reloading configuration failed, keeping the previous one", "error", err)
+				continue
+			}
+			w.c = c
+			slog.Info("reloaded configuration")
+		}
+	}
+}
+
+// check updates the dataset if the file changed since the last successful update.
+func (w *watcher) check(ctx context.Context) error {
+	changed, entry, err := w.hc.changed(w.id, w.fp)
+	if err != nil {
+		return err
+	}
+	if !changed && !w.force {
+		return nil
+	}
+
+	uctx := ctx
+	if w.timeout > 0 {
+		var cancel context.CancelFunc
+		uctx, cancel = context.WithTimeout(ctx, w.timeout)
+		defer cancel()
+	}
+
+	ir, err := w.c.Update(uctx, w.id, w.fp, w.opts...)
+	if err != nil {
+		return err
+	}
+	w.force = false
+	fmt.Println(ir.String())
+	printWarnings(ir.ID, ir.Warnings)
+
+	return w.hc.store(w.id, entry)
+}
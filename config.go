@@ -0,0 +1,292 @@
+package maptiler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config configures a Client declaratively, e.g. from a file and the
+// environment with LoadConfig, see NewFromConfig. Zero values keep the
+// defaults of New. The part size is requested per ingest with WithPartSize.
+type Config struct {
+	// Host is the address of the service API, it defaults to the MapTiler service.
+	Host string `json:"host,omitempty"`
+	// Token authenticates with the service API. TokenProvider is called once
+	// by NewFromConfig if Token is empty, e.g. to read it from a secret store.
+	// Without either the token is read from MAPTILER_TOKEN.
+	Token         string                 `json:"token,omitempty"`
+	TokenProvider func() (string, error) `json:"-"`
+	APIVersion    string                 `json:"api_version,omitempty"`
+	// Endpoints replaces the path templates of endpoints, see WithEndpointPath.
+	Endpoints map[Endpoint]string `json:"endpoints,omitempty"`
+	// Timeout limits requests to the service API, see WithTimeout.
+	Timeout Duration `json:"timeout,omitzero"`
+	// Retries retries calls failing with 429 or 5xx up to this many times with
+	// exponential backoff. RetryPolicy replaces it if set.
+	Retries     int         `json:"retries,omitempty"`
+	RetryPolicy RetryPolicy `json:"-"`
+	// APIRateLimit limits the calls to the service API per second, see
+	// WithAPIRateLimit.
+	APIRateLimit int `json:"api_rate_limit,omitempty"`
+	// Concurrency and InFlightBytes limit the parts of an ingest uploaded at
+	// the same time, see WithConcurrency and WithInFlightBytes.
+	Concurrency   int      `json:"concurrency,omitempty"`
+	InFlightBytes int64    `json:"in_flight_bytes,omitempty"`
+	UploadHosts   []string `json:"upload_hosts,omitempty"`
+	// Metadata is attached to every ingest, see WithDefaultMetadata.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// IdempotentUpdates skips updates with the file of the dataset, see
+	// WithIdempotentUpdates.
+	IdempotentUpdates bool `json:"idempotent_updates,omitempty"`
+	// ReadOnly rejects calls that would change anything, see WithReadOnly.
+	ReadOnly bool `json:"read_only,omitempty"`
+	// StateKey is the base64 encoded key journals are encrypted with, see
+	// ParseStateKey and WithStateCipher.
+	StateKey string `json:"state_key,omitempty"`
+	// LogLevel logs to stderr at debug, info, warn or error level. Logger
+	// replaces it if set, see WithLogger.
+	LogLevel string       `json:"log_level,omitempty"`
+	Logger   *slog.Logger `json:"-"`
+}
+
+// Duration is a time.Duration that is read from and written to JSON as a
+// string, e.g. "30s".
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// ErrInvalidConfig is returned by Config.Validate and NewFromConfig for a
+// Config with invalid values.
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Validate reports all invalid values of cfg at once, wrapped in
+// ErrInvalidConfig. A missing token is invalid unless MAPTILER_TOKEN is set.
+func (cfg Config) Validate() error {
+	var errs []error
+	if cfg.Host != "" {
+		if u, err := url.Parse(cfg.Host); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("host %q is not an absolute URL", cfg.Host))
+		}
+	}
+	if cfg.Token == "" && cfg.TokenProvider == nil && os.Getenv("MAPTILER_TOKEN") == "" {
+		errs = append(errs, errors.New("token is empty and MAPTILER_TOKEN is not set"))
+	}
+	version := cfg.APIVersion
+	if version == "" {
+		version = DefaultAPIVersion
+	}
+	if _, err := pathsOf(version, cfg.Endpoints); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("timeout %s is negative", time.Duration(cfg.Timeout)))
+	}
+	if cfg.Retries < 0 {
+		errs = append(errs, fmt.Errorf("retries %d is negative", cfg.Retries))
+	}
+	if cfg.APIRateLimit < 0 {
+		errs = append(errs, fmt.Errorf("api rate limit %d is negative", cfg.APIRateLimit))
+	}
+	if cfg.Concurrency < 0 {
+		errs = append(errs, fmt.Errorf("concurrency %d is negative", cfg.Concurrency))
+	}
+	if cfg.InFlightBytes < 0 {
+		errs = append(errs, fmt.Errorf("in flight bytes %d is negative", cfg.InFlightBytes))
+	}
+	if cfg.StateKey != "" {
+		if _, err := ParseStateKey(cfg.StateKey); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg.LogLevel != "" {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+			errs = append(errs, fmt.Errorf("log level: %w", err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+	}
+	return nil
+}
+
+// NewFromConfig validates cfg and creates a Client from it. options are
+// applied after the values of cfg, e.g. for a custom transport.
+func NewFromConfig(cfg Config, options ...Option) (*Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("initializing maptiler client: %w", err)
+	}
+
+	token := cfg.Token
+	if token == "" && cfg.TokenProvider != nil {
+		t, err := cfg.TokenProvider()
+		if err != nil {
+			return nil, fmt.Errorf("initializing maptiler client, providing token: %w", err)
+		}
+		token = t
+	}
+
+	var opts []Option
+	if cfg.APIVersion != "" {
+		opts = append(opts, WithAPIVersion(cfg.APIVersion))
+	}
+	for e, path := range cfg.Endpoints {
+		opts = append(opts, WithEndpointPath(e, path))
+	}
+	if cfg.Timeout > 0 {
+		opts = append(opts, WithTimeout(time.Duration(cfg.Timeout)))
+	}
+	switch {
+	case cfg.RetryPolicy != nil:
+		opts = append(opts, WithRetryPolicy(cfg.RetryPolicy))
+	case cfg.Retries > 0:
+		opts = append(opts, WithRetryPolicy(ExponentialBackoff{Attempts: cfg.Retries, Base: time.Second, Max: 30 * time.Second}))
+	}
+	if cfg.APIRateLimit > 0 {
+		opts = append(opts, WithAPIRateLimit(cfg.APIRateLimit))
+	}
+	if cfg.Concurrency > 0 {
+		opts = append(opts, WithConcurrency(cfg.Concurrency))
+	}
+	if cfg.InFlightBytes > 0 {
+		opts = append(opts, WithInFlightBytes(cfg.InFlightBytes))
+	}
+	if len(cfg.UploadHosts) > 0 {
+		opts = append(opts, WithUploadHosts(cfg.UploadHosts...))
+	}
+	if len(cfg.Metadata) > 0 {
+		opts = append(opts, WithDefaultMetadata(cfg.Metadata))
+	}
+	if cfg.IdempotentUpdates {
+		opts = append(opts, WithIdempotentUpdates())
+	}
+	if cfg.ReadOnly {
+		opts = append(opts, WithReadOnly())
+	}
+	if cfg.StateKey != "" {
+		sc, _ := ParseStateKey(cfg.StateKey) // validated above.
+		opts = append(opts, WithStateCipher(sc))
+	}
+	switch {
+	case cfg.Logger != nil:
+		opts = append(opts, WithLogger(cfg.Logger))
+	case cfg.LogLevel != "":
+		var l slog.Level
+		_ = l.UnmarshalText([]byte(cfg.LogLevel)) // validated above.
+		opts = append(opts, WithLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: l}))))
+	}
+	return New(cfg.Host, token, append(opts, options...)...)
+}
+
+// NewFromEnv creates a Client configured by the environment alone, for
+// deployments without flags or config files:
+//
+//	MAPTILER_HOST             address of the service API
+//	MAPTILER_TOKEN            token of the service API, required
+//	MAPTILER_API_VERSION      version of the service API, e.g. v1
+//	MAPTILER_TIMEOUT          timeout of requests, e.g. 30s
+//	MAPTILER_RETRIES          retries of calls failing with 429 or 5xx
+//	MAPTILER_API_RATE_LIMIT   calls to the service API per second
+//	MAPTILER_CONCURRENCY      parts of an ingest uploaded at the same time
+//	MAPTILER_IN_FLIGHT_BYTES  bytes of parts uploaded at the same time
+//	MAPTILER_UPLOAD_HOSTS     comma separated hosts parts may be uploaded to
+//	MAPTILER_LOG_LEVEL        debug, info, warn or error
+//	MAPTILER_STATE_KEY        base64 encoded key journals are encrypted with
+//	MAPTILER_READ_ONLY        true to only get ingests and datasets
+//
+// Unset variables keep the defaults of New. options are applied last.
+func NewFromEnv(options ...Option) (*Client, error) {
+	var cfg Config
+	if err := cfg.applyEnv(); err != nil {
+		return nil, fmt.Errorf("initializing maptiler client: %w", err)
+	}
+	return NewFromConfig(cfg, options...)
+}
+
+// LoadConfig reads the JSON config file at path, if path is not empty, and
+// overrides its values with the environment variables of NewFromEnv that are
+// set. The Config is not validated.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	if path != "" {
+		b, err := os.ReadFile(path) //nolint:gosec // the path is given by the caller.
+		if err != nil {
+			return cfg, fmt.Errorf("reading config: %w", err)
+		}
+		if err := json.Unmarshal(b, &cfg); err != nil {
+			return cfg, fmt.Errorf("reading config %s: %w", path, err)
+		}
+	}
+	return cfg, cfg.applyEnv()
+}
+
+// applyEnv overrides cfg with the environment variables of NewFromEnv that are set.
+func (cfg *Config) applyEnv() error {
+	for name, dst := range map[string]*string{
+		"MAPTILER_HOST":        &cfg.Host,
+		"MAPTILER_TOKEN":       &cfg.Token,
+		"MAPTILER_API_VERSION": &cfg.APIVersion,
+		"MAPTILER_LOG_LEVEL":   &cfg.LogLevel,
+		"MAPTILER_STATE_KEY":   &cfg.StateKey,
+	} {
+		if v := os.Getenv(name); v != "" {
+			*dst = v
+		}
+	}
+	if v := os.Getenv("MAPTILER_TIMEOUT"); v != "" {
+		if err := cfg.Timeout.UnmarshalText([]byte(v)); err != nil {
+			return fmt.Errorf("reading MAPTILER_TIMEOUT: %w", err)
+		}
+	}
+	for name, dst := range map[string]*int{
+		"MAPTILER_RETRIES":        &cfg.Retries,
+		"MAPTILER_API_RATE_LIMIT": &cfg.APIRateLimit,
+		"MAPTILER_CONCURRENCY":    &cfg.Concurrency,
+	} {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", name, err)
+		}
+		*dst = n
+	}
+	if v := os.Getenv("MAPTILER_IN_FLIGHT_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("reading MAPTILER_IN_FLIGHT_BYTES: %w", err)
+		}
+		cfg.InFlightBytes = n
+	}
+	if v := os.Getenv("MAPTILER_READ_ONLY"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("reading MAPTILER_READ_ONLY: %w", err)
+		}
+		cfg.ReadOnly = b
+	}
+	if v := os.Getenv("MAPTILER_UPLOAD_HOSTS"); v != "" {
+		cfg.UploadHosts = strings.Split(v, ",")
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package maptiler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestConfigValidate(t *testing.T) {
+	t.Setenv("MAPTILER_TOKEN", "")
+
+	if err := (Config{Token: "token"}).Validate(); err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+
+	cfg := Config{
+		Host:        "service.example.com",
+		APIVersion:  "v0",
+		Timeout:     Duration(-time.Second),
+		Concurrency: -1,
+		StateKey:    "c2hvcnQ=",
+		LogLevel:    "loud",
+	}
+	err := cfg.Validate()
+	if !errors.Is(err, ErrInvalidConfig) || !errors.Is(err, ErrUnsupportedAPIVersion) || !errors.Is(err, ErrInvalidStateKey) {
+		t.Fatalf("Validate() error = %v, want ErrInvalidConfig, ErrUnsupportedAPIVersion and ErrInvalidStateKey", err)
+	}
+	for _, want := range []string{"host", "token", "timeout", "concurrency", "log level"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error = %v, want it to report the %s", err, want)
+		}
+	}
+
+	t.Setenv("MAPTILER_TOKEN", "token")
+	if err := (Config{}).Validate(); err != nil {
+		t.Errorf("Validate() unexpected error with MAPTILER_TOKEN: %v", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "maptiler.json")
+	data := `{"host":"https://file.example.com","token":"file","timeout":"30s","concurrency":4,"endpoints":{"ingest_create":"/gateway/ingest"}}`
+	if err := os.WriteFile(fp, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MAPTILER_HOST", "")
+	t.Setenv("MAPTILER_TOKEN", "env")
+	t.Setenv("MAPTILER_CONCURRENCY", "2")
+
+	cfg, err := LoadConfig(fp)
+	if err != nil {
+		t.Fatalf("LoadConfig() unexpected error: %v", err)
+	}
+	if cfg.Host != "https://file.example.com" || cfg.Token != "env" || cfg.Concurrency != 2 {
+		t.Errorf("LoadConfig() = %+v, want the file overridden by the environment", cfg)
+	}
+	if time.Duration(cfg.Timeout) != 30*time.Second || cfg.Endpoints[EndpointIngestCreate] != "/gateway/ingest" {
+		t.Errorf("LoadConfig() = %+v, want the values of the file", cfg)
+	}
+
+	t.Setenv("MAPTILER_RETRIES", "many")
+	if _, err := LoadConfig(fp); err == nil || !strings.Contains(err.Error(), "MAPTILER_RETRIES") {
+		t.Errorf("LoadConfig() error = %v, want MAPTILER_RETRIES to be rejected", err)
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	var calls int
+	c, err := NewFromConfig(Config{
+		Host: srv.URL,
+		TokenProvider: func() (string, error) {
+			calls++
+			return "token", nil
+		},
+		Concurrency: 2,
+		Timeout:     Duration(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig() unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("TokenProvider called %d times, want 1", calls)
+	}
+	if c.concurrency != 2 {
+		t.Errorf("NewFromConfig() concurrency = %d, want 2", c.concurrency)
+	}
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Create(t.Context(), fp); err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+
+	failing := errors.New("vault is sealed")
+	_, err = NewFromConfig(Config{TokenProvider: func() (string, error) { return "", failing }})
+	if !errors.Is(err, failing) {
+		t.Errorf("NewFromConfig() error = %v, want the error of the TokenProvider", err)
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	t.Setenv("MAPTILER_HOST", srv.URL)
+	t.Setenv("MAPTILER_TOKEN", "token")
+	t.Setenv("MAPTILER_CONCURRENCY", "3")
+	t.Setenv("MAPTILER_API_RATE_LIMIT", "100")
+	t.Setenv("MAPTILER_IN_FLIGHT_BYTES", "1024")
+	t.Setenv("MAPTILER_UPLOAD_HOSTS", "127.0.0.1,localhost")
+
+	c, err := NewFromEnv()
+	if err != nil {
+		t.Fatalf("NewFromEnv() unexpected error: %v", err)
+	}
+	if c.concurrency != 3 || c.inFlight != 1024 {
+		t.Errorf("NewFromEnv() concurrency %d and in flight %d, want 3 and 1024", c.concurrency, c.inFlight)
+	}
+	if c.apiLimit == nil {
+		t.Error("NewFromEnv() expected the API rate limit to be set")
+	}
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Create(t.Context(), fp); err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+
+	t.Setenv("MAPTILER_TIMEOUT", "soon")
+	if _, err := NewFromEnv(); err == nil || !strings.Contains(err.Error(), "MAPTILER_TIMEOUT") {
+		t.Errorf("NewFromEnv() error = %v, want MAPTILER_TIMEOUT to be rejected", err)
+	}
+}
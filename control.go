@@ -0,0 +1,71 @@
+package maptiler
+
+import (
+	"context"
+	"sync"
+)
+
+// UploadControl pauses and resumes the uploads it is passed to with
+// WithUploadControl, e.g. while on a metered connection. A paused upload
+// finishes the parts in flight and sends no further parts until it is
+// resumed, the ingest is kept. A single UploadControl may control several
+// uploads at once. The zero value is ready to use and not paused.
+type UploadControl struct {
+	mu sync.Mutex
+	// resumed is closed by Resume, it is nil while not paused.
+	resumed chan struct{}
+}
+
+// NewUploadControl returns an UploadControl that is not paused.
+func NewUploadControl() *UploadControl {
+	return &UploadControl{}
+}
+
+// Pause stops the uploads from sending further parts until Resume is called.
+func (c *UploadControl) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resumed == nil {
+		c.resumed = make(chan struct{})
+	}
+}
+
+// Resume continues the paused uploads.
+func (c *UploadControl) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resumed != nil {
+		close(c.resumed)
+		c.resumed = nil
+	}
+}
+
+// Paused reports whether the uploads are paused.
+func (c *UploadControl) Paused() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.resumed != nil
+}
+
+// wait blocks while the uploads are paused or until ctx is done. A nil
+// UploadControl never blocks.
+func (c *UploadControl) wait(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	resumed := c.resumed
+	c.mu.Unlock()
+	if resumed == nil {
+		return nil
+	}
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
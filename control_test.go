@@ -0,0 +1,93 @@
+package maptiler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestUploadControl(t *testing.T) {
+	t.Parallel()
+
+	var nilCtl *UploadControl
+	if nilCtl.Paused() || nilCtl.wait(t.Context()) != nil {
+		t.Fatal("nil control should never pause")
+	}
+
+	ctl := NewUploadControl()
+	if err := ctl.wait(t.Context()); err != nil {
+		t.Fatalf("wait() unexpected error: %v", err)
+	}
+
+	ctl.Pause()
+	ctl.Pause()
+	if !ctl.Paused() {
+		t.Fatal("expected control to be paused")
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if err := ctl.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait() error = %v, want to block until the deadline", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- ctl.wait(t.Context()) }()
+	ctl.Resume()
+	ctl.Resume()
+	if err := <-done; err != nil {
+		t.Fatalf("wait() unexpected error after resume: %v", err)
+	}
+	if ctl.Paused() {
+		t.Fatal("expected control to be resumed")
+	}
+}
+
+func TestCreatePaused(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctl := NewUploadControl()
+	ctl.Pause()
+	var partsDone atomic.Int32
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Create(t.Context(), fp, WithUploadControl(ctl), WithProgress(func(p Progress) {
+			partsDone.Store(int32(p.PartsDone)) //nolint:gosec
+		}))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Create() returned while paused: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if n := partsDone.Load(); n != 0 {
+		t.Fatalf("%d parts uploaded while paused", n)
+	}
+
+	ctl.Resume()
+	if err := <-done; err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+	if n := partsDone.Load(); n != 3 {
+		t.Errorf("%d parts uploaded, want 3", n)
+	}
+}
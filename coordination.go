@@ -0,0 +1,253 @@
+package maptiler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ErrLeaseHeld is returned when a lease is held by another owner.
+var ErrLeaseHeld = errors.New("lease is held by another owner")
+
+// ErrLeaseLost is the cause of the cancellation of an upload whose lease could
+// not be renewed.
+var ErrLeaseLost = errors.New("lease lost")
+
+// DefaultLeaseTTL is the lease duration of a Coordinator created without one.
+const DefaultLeaseTTL = time.Minute
+
+// Lease is the right of Owner to work on Key until Expires. A zero Expires
+// never expires, it marks work that is done.
+type Lease struct {
+	Key     string    `json:"key"`
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+// expired reports whether the lease ran out at now.
+func (l Lease) expired(now time.Time) bool {
+	return !l.Expires.IsZero() && !now.Before(l.Expires)
+}
+
+// CoordinationBackend stores the leases of distributed uploads, shared by all
+// machines taking part. Backends on key value stores map Acquire to a compare
+// and set with expiry, e.g. SET NX PX of Redis or a transaction on a lease of
+// etcd.
+type CoordinationBackend interface {
+	// Acquire takes the lease of key for owner for ttl, or renews it if owner
+	// holds it already. A ttl of 0 never expires and is final, only the same
+	// call succeeds again. It fails with ErrLeaseHeld if another owner holds a
+	// lease that has not expired, or if the lease is final.
+	Acquire(ctx context.Context, key, owner string, ttl time.Duration) (Lease, error)
+	// Release gives up the lease of key if owner holds it.
+	Release(ctx context.Context, key, owner string) error
+}
+
+// Coordinator runs the steps of a distributed upload, see Assign, under the
+// leases of a CoordinationBackend. An assignment is uploaded by one worker at a
+// time and only once, and an ingest is completed by a single coordinator.
+type Coordinator struct {
+	c       *Client
+	backend CoordinationBackend
+	owner   string
+	ttl     time.Duration
+}
+
+// NewCoordinator returns a Coordinator acting as owner, which has to be unique
+// among the machines, e.g. the hostname. Leases are renewed every third of ttl
+// while they are held, DefaultLeaseTTL is used if ttl is not positive.
+func NewCoordinator(c *Client, b CoordinationBackend, owner string, ttl time.Duration) *Coordinator {
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+	return &Coordinator{c: c, backend: b, owner: owner, ttl: ttl}
+}
+
+// UploadAssignment uploads a like Client.UploadAssignment while holding its
+// lease. The upload is canceled with ErrLeaseLost if the lease can not be
+// renewed. The lease of an uploaded assignment is kept, so that it is not
+// uploaded again, which would replace the ETags of the report. The lease of a
+// failed upload is released for another worker to take over.
+func (co *Coordinator) UploadAssignment(ctx context.Context, a PartAssignment, fp string, opts ...IngestOption) (PartReport, error) {
+	key := "ingest/" + a.IngestID + "/assignment/" + strconv.Itoa(a.Worker)
+	if _, err := co.backend.Acquire(ctx, key, co.owner, co.ttl); err != nil {
+		return PartReport{}, fmt.Errorf("leasing assignment %d of %s: %w", a.Worker, a.IngestID, err)
+	}
+
+	uctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stop := co.renew(uctx, key, cancel)
+	r, err := co.c.UploadAssignment(uctx, a, fp, opts...)
+	stop()
+	if err != nil {
+		co.release(ctx, key)
+		if cause := context.Cause(uctx); errors.Is(cause, ErrLeaseLost) {
+			return PartReport{}, fmt.Errorf("%w: %w", err, cause)
+		}
+		return PartReport{}, err
+	}
+
+	if _, err := co.backend.Acquire(ctx, key, co.owner, 0); err != nil {
+		return r, fmt.Errorf("marking assignment %d of %s as uploaded: %w", a.Worker, a.IngestID, err)
+	}
+	return r, nil
+}
+
+// Complete completes ir like Client.Complete while holding the lease of its
+// finalization. A second coordinator fails with ErrLeaseHeld, also once ir is
+// completed. The lease is released if completing fails, e.g. with
+// ErrPartsMissing.
+func (co *Coordinator) Complete(ctx context.Context, ir IngestResponse, reports ...PartReport) (IngestResponse, error) {
+	key := "ingest/" + ir.ID + "/complete"
+	if _, err := co.backend.Acquire(ctx, key, co.owner, co.ttl); err != nil {
+		return IngestResponse{}, fmt.Errorf("leasing completion of %s: %w", ir.ID, err)
+	}
+
+	done, err := co.c.Complete(ctx, ir, reports...)
+	if err != nil {
+		co.release(ctx, key)
+		return IngestResponse{}, err
+	}
+	if _, err := co.backend.Acquire(ctx, key, co.owner, 0); err != nil {
+		return done, fmt.Errorf("marking %s as completed: %w", ir.ID, err)
+	}
+	return done, nil
+}
+
+// renew renews the lease of key until the returned function is called, and
+// cancels with ErrLeaseLost if it fails.
+func (co *Coordinator) renew(ctx context.Context, key string, cancel context.CancelCauseFunc) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(co.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := co.backend.Acquire(ctx, key, co.owner, co.ttl); err != nil {
+					cancel(fmt.Errorf("renewing %s: %w: %w", key, ErrLeaseLost, err))
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// release gives up the lease of key, failures are logged as the lease expires
+// on its own.
+func (co *Coordinator) release(ctx context.Context, key string) {
+	if err := co.backend.Release(context.WithoutCancel(ctx), key, co.owner); err != nil {
+		logger(co.c.log).Warn("releasing lease failed", "key", key, "error", err)
+	}
+}
+
+// leaseLockPoll is how often FileBackend retries the lock of a lease file.
+const leaseLockPoll = 10 * time.Millisecond
+
+// FileBackend keeps leases as files in a directory shared by all machines,
+// e.g. on NFS. Lease files are updated under an advisory lock, which is not
+// supported on all platforms.
+type FileBackend struct {
+	dir string
+}
+
+// NewFileBackend returns a FileBackend keeping its leases in dir.
+func NewFileBackend(dir string) *FileBackend {
+	return &FileBackend{dir: dir}
+}
+
+func (b *FileBackend) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (Lease, error) {
+	var l Lease
+	err := b.update(ctx, key, func(cur Lease, now time.Time) (Lease, error) {
+		final := cur.Owner != "" && cur.Expires.IsZero()
+		if (cur.Owner != "" && cur.Owner != owner && !cur.expired(now)) || (final && ttl > 0) {
+			return cur, fmt.Errorf("%s held by %s: %w", key, cur.Owner, ErrLeaseHeld)
+		}
+		l = Lease{Key: key, Owner: owner}
+		if ttl > 0 {
+			l.Expires = now.Add(ttl)
+		}
+		return l, nil
+	})
+	return l, err
+}
+
+func (b *FileBackend) Release(ctx context.Context, key, owner string) error {
+	return b.update(ctx, key, func(cur Lease, _ time.Time) (Lease, error) {
+		if cur.Owner != owner {
+			return cur, nil
+		}
+		return Lease{}, nil
+	})
+}
+
+// update replaces the lease of key with the result of fn while holding the lock
+// of its file.
+func (b *FileBackend) update(ctx context.Context, key string, fn func(cur Lease, now time.Time) (Lease, error)) error {
+	if err := os.MkdirAll(b.dir, 0o700); err != nil {
+		return fmt.Errorf("creating lease directory: %w", err)
+	}
+	// lease files are never removed, removing them would race with other processes.
+	fp := filepath.Join(b.dir, url.PathEscape(key)+".lease")
+	f, err := os.OpenFile(fp, os.O_RDONLY|os.O_CREATE, 0o600) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("creating lease file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("creating lease file: %w", err)
+	}
+
+	unlock, err := lockFile(fp)
+	for errors.Is(err, ErrFileLocked) {
+		if serr := sleep(ctx, leaseLockPoll); serr != nil {
+			return fmt.Errorf("waiting for lease %s: %w", key, serr)
+		}
+		unlock, err = lockFile(fp)
+	}
+	if err != nil {
+		return err
+	}
+	defer unlock() //nolint:errcheck
+
+	var cur Lease
+	data, err := os.ReadFile(fp) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("reading lease %s: %w", key, err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &cur); err != nil {
+			return fmt.Errorf("reading lease %s: %w", key, err)
+		}
+	}
+
+	next, err := fn(cur, time.Now())
+	if err != nil {
+		return err
+	}
+	var out []byte
+	if next.Owner != "" {
+		if out, err = json.Marshal(next); err != nil {
+			return fmt.Errorf("encoding lease %s: %w", key, err)
+		}
+	}
+	if err := os.WriteFile(fp, out, 0o600); err != nil {
+		return fmt.Errorf("writing lease %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package maptiler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyBackend grants the first lease and fails every renewal.
+type flakyBackend struct {
+	calls atomic.Int32
+}
+
+func (b *flakyBackend) Acquire(_ context.Context, key, owner string, ttl time.Duration) (Lease, error) {
+	if b.calls.Add(1) > 1 {
+		return Lease{}, errors.New("backend unavailable")
+	}
+	return Lease{Key: key, Owner: owner, Expires: time.Now().Add(ttl)}, nil
+}
+
+func (b *flakyBackend) Release(context.Context, string, string) error { return nil }
+
+func TestCoordinatorCancelsOnLostLease(t *testing.T) {
+	t.Parallel()
+
+	// the part upload hangs until it is canceled, or the test is done.
+	stop := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-stop:
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(stop) })
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	a := PartAssignment{IngestID: "ingest", Size: 4, PartSize: 4, Parts: []AssignedPart{{PartID: 1, URL: srv.URL + "/part"}}}
+
+	co := NewCoordinator(c, &flakyBackend{}, "host-1", 30*time.Millisecond)
+	if _, err := co.UploadAssignment(t.Context(), a, fp); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("UploadAssignment() error = %v, want %v", err, ErrLeaseLost)
+	}
+}
@@ -0,0 +1,117 @@
+//go:build unix
+
+package maptiler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestFileBackend(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	b := NewFileBackend(t.TempDir())
+
+	if _, err := b.Acquire(ctx, "ingest/1/complete", "a", time.Hour); err != nil {
+		t.Fatalf("Acquire() unexpected error: %v", err)
+	}
+	if _, err := b.Acquire(ctx, "ingest/1/complete", "b", time.Hour); !errors.Is(err, ErrLeaseHeld) {
+		t.Fatalf("Acquire() of a held lease: error = %v, want %v", err, ErrLeaseHeld)
+	}
+	if _, err := b.Acquire(ctx, "ingest/1/complete", "a", time.Hour); err != nil {
+		t.Fatalf("renewing Acquire() unexpected error: %v", err)
+	}
+	if err := b.Release(ctx, "ingest/1/complete", "b"); err != nil {
+		t.Fatalf("Release() by another owner unexpected error: %v", err)
+	}
+	if _, err := b.Acquire(ctx, "ingest/1/complete", "b", time.Hour); !errors.Is(err, ErrLeaseHeld) {
+		t.Fatalf("Acquire() after a release by another owner: error = %v, want %v", err, ErrLeaseHeld)
+	}
+	if err := b.Release(ctx, "ingest/1/complete", "a"); err != nil {
+		t.Fatalf("Release() unexpected error: %v", err)
+	}
+
+	// an expired lease is taken over.
+	if _, err := b.Acquire(ctx, "ingest/1/complete", "b", time.Millisecond); err != nil {
+		t.Fatalf("Acquire() of a released lease unexpected error: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	l, err := b.Acquire(ctx, "ingest/1/complete", "a", 0)
+	if err != nil {
+		t.Fatalf("Acquire() of an expired lease unexpected error: %v", err)
+	}
+	if l.Owner != "a" || !l.Expires.IsZero() {
+		t.Fatalf("unexpected lease %+v", l)
+	}
+
+	// a lease that never expires is final.
+	if _, err := b.Acquire(ctx, "ingest/1/complete", "a", time.Hour); !errors.Is(err, ErrLeaseHeld) {
+		t.Fatalf("Acquire() with a ttl of a final lease: error = %v, want %v", err, ErrLeaseHeld)
+	}
+	if _, err := b.Acquire(ctx, "ingest/1/complete", "a", 0); err != nil {
+		t.Fatalf("repeated final Acquire() unexpected error: %v", err)
+	}
+}
+
+func TestCoordinator(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ir, err := c.Begin(t.Context(), "", fp)
+	if err != nil {
+		t.Fatalf("Begin() unexpected error: %v", err)
+	}
+	assignments, err := Assign(ir, 2)
+	if err != nil {
+		t.Fatalf("Assign() unexpected error: %v", err)
+	}
+
+	backend := NewFileBackend(t.TempDir())
+	first := NewCoordinator(c, backend, "host-1", time.Minute)
+	second := NewCoordinator(c, backend, "host-2", time.Minute)
+
+	r0, err := first.UploadAssignment(t.Context(), assignments[0], fp)
+	if err != nil {
+		t.Fatalf("UploadAssignment() unexpected error: %v", err)
+	}
+	// an uploaded assignment is not uploaded again, by any worker.
+	for _, co := range []*Coordinator{first, second} {
+		if _, err := co.UploadAssignment(t.Context(), assignments[0], fp); !errors.Is(err, ErrLeaseHeld) {
+			t.Fatalf("UploadAssignment() of an uploaded assignment: error = %v, want %v", err, ErrLeaseHeld)
+		}
+	}
+	r1, err := second.UploadAssignment(t.Context(), assignments[1], fp)
+	if err != nil {
+		t.Fatalf("UploadAssignment() unexpected error: %v", err)
+	}
+
+	// a failed completion releases its lease for another coordinator.
+	if _, err := first.Complete(t.Context(), ir, r0); !errors.Is(err, ErrPartsMissing) {
+		t.Fatalf("Complete() error = %v, want %v", err, ErrPartsMissing)
+	}
+	if _, err := second.Complete(t.Context(), ir, r0, r1); err != nil {
+		t.Fatalf("Complete() unexpected error: %v", err)
+	}
+	if _, err := first.Complete(t.Context(), ir, r0, r1); !errors.Is(err, ErrLeaseHeld) {
+		t.Fatalf("Complete() of a completed ingest: error = %v, want %v", err, ErrLeaseHeld)
+	}
+	if IngestState(srv.State(ir.ID)) != StateCompleted {
+		t.Fatalf("ingest is %s, want %s", IngestState(srv.State(ir.ID)), StateCompleted)
+	}
+}
@@ -0,0 +1,143 @@
+package maptiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrIngestInProgress is returned when WithDatasetLock is used and another
+// process is ingesting into the same dataset.
+var ErrIngestInProgress = errors.New("another ingest of the dataset is in progress")
+
+// ErrDatasetBusy is returned when WithConflictCheck is used and the last ingest
+// of the dataset is still uploading or processing.
+var ErrDatasetBusy = errors.New("dataset has an ingest in progress")
+
+// datasetLockPoll is how often a held dataset lock is retried while waiting.
+const datasetLockPoll = 500 * time.Millisecond
+
+// defaultLockDir returns the directory dataset lock files are kept in by default.
+func defaultLockDir() string {
+	return filepath.Join(os.TempDir(), "maptiler-go", "locks")
+}
+
+// lockDataset acquires the local lock of dataset id in dir. If the lock is held
+// by another process it is retried for up to wait, before failing with
+// ErrIngestInProgress. The returned function releases the lock.
+func lockDataset(ctx context.Context, dir, id string, wait time.Duration) (func() error, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating lock directory: %w", err)
+	}
+
+	// lock files are never removed, removing them would race with other processes.
+	fp := datasetFile(dir, id, ".lock")
+	f, err := os.OpenFile(fp, os.O_RDONLY|os.O_CREATE, 0o600) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("creating lock file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("creating lock file: %w", err)
+	}
+
+	start := time.Now()
+	for {
+		unlock, err := lockFile(fp)
+		if err == nil {
+			return unlock, nil
+		}
+		if !errors.Is(err, ErrFileLocked) {
+			return nil, err
+		}
+		if time.Since(start) >= wait {
+			return nil, fmt.Errorf("dataset %s: %w", id, ErrIngestInProgress)
+		}
+		if err := sleep(ctx, datasetLockPoll); err != nil {
+			return nil, fmt.Errorf("waiting for dataset lock: %w", err)
+		}
+	}
+}
+
+// recordIngest stores ingestID as the last ingest of dataset id in dir.
+func recordIngest(dir, id, ingestID string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("creating lock directory: %w", err)
+	}
+	if err := os.WriteFile(datasetFile(dir, id, ".ingest"), []byte(ingestID), 0o600); err != nil {
+		return fmt.Errorf("recording ingest: %w", err)
+	}
+	return nil
+}
+
+// lastIngest returns the last ingest of dataset id recorded in dir, if any.
+func lastIngest(dir, id string) (string, error) {
+	b, err := os.ReadFile(datasetFile(dir, id, ".ingest"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading last ingest: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// datasetFile returns the path of the file with extension ext of dataset id in dir.
+func datasetFile(dir, id, ext string) string {
+	return filepath.Join(dir, url.PathEscape(id)+ext)
+}
+
+// guardDataset applies the dataset level protections of cfg before dataset id
+// is updated. The returned function releases them.
+func (c *Client) guardDataset(ctx context.Context, id string, cfg ingestConfig) (func(), error) {
+	release := func() {}
+	if cfg.datasetLock {
+		unlock, err := lockDataset(ctx, c.lockDir, id, cfg.datasetWait)
+		if err != nil {
+			return nil, err
+		}
+		release = func() { unlock() } //nolint:errcheck
+	}
+
+	if cfg.conflictCheck {
+		if err := c.checkConflict(ctx, id, cfg.cancelExisting); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// checkConflict fails with ErrDatasetBusy if the last recorded ingest of dataset
+// id is still uploading or processing, or cancels it if cancelExisting is set.
+func (c *Client) checkConflict(ctx context.Context, id string, cancelExisting bool) error {
+	last, err := lastIngest(c.lockDir, id)
+	if err != nil || last == "" {
+		return err
+	}
+
+	ir, err := c.Get(ctx, last)
+	var aerr APIError
+	if errors.As(err, &aerr) && aerr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("checking for conflicting ingests: %w", err)
+	}
+	if ir.State != StateUpload && ir.State != StateProcessing {
+		return nil
+	}
+
+	if !cancelExisting {
+		return fmt.Errorf("dataset %s, ingest %s is in state %s: %w", id, last, ir.State, ErrDatasetBusy)
+	}
+	if _, err := c.cancel(ctx, last); err != nil {
+		return fmt.Errorf("canceling conflicting ingest %s: %w", last, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package maptiler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUpdateConflictCheck(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		state          IngestState
+		cancelExisting bool
+		wantBusy       bool
+		wantCancel     int32
+	}{
+		{name: "busy", state: StateUpload, wantBusy: true},
+		{name: "processing", state: StateProcessing, wantBusy: true},
+		{name: "cancel existing", state: StateUpload, cancelExisting: true, wantCancel: 1},
+		{name: "finished", state: StateCompleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cancels, ingests atomic.Int32
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /datasets/ingest/ing-old", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"id":"ing-old","state":"` + string(tt.state) + `"}`))
+			})
+			mux.HandleFunc("POST /datasets/ingest/ing-old/cancel", func(w http.ResponseWriter, r *http.Request) {
+				cancels.Add(1)
+				_, _ = w.Write([]byte(`{"id":"ing-old","state":"canceled"}`))
+			})
+			mux.HandleFunc("POST /datasets/ds-1/ingest", func(w http.ResponseWriter, r *http.Request) {
+				// stop the update right after the conflict check.
+				ingests.Add(1)
+				http.Error(w, "boom", http.StatusInternalServerError)
+			})
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			dir := t.TempDir()
+			if err := recordIngest(dir, "ds-1", "ing-old"); err != nil {
+				t.Fatal(err)
+			}
+			fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+			if err := os.WriteFile(fp, []byte("abc"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := New(srv.URL, "token", WithLockDir(dir))
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+
+			_, err = c.Update(t.Context(), "ds-1", fp, WithConflictCheck(tt.cancelExisting))
+			if got := errors.Is(err, ErrDatasetBusy); got != tt.wantBusy {
+				t.Fatalf("Update() error = %v, want busy %t", err, tt.wantBusy)
+			}
+			if got := cancels.Load(); got != tt.wantCancel {
+				t.Fatalf("cancel called %d times, want %d", got, tt.wantCancel)
+			}
+			if tt.wantBusy && ingests.Load() != 0 {
+				t.Fatalf("ingest should not be started for a busy dataset")
+			}
+		})
+	}
+}
+
+func TestRecordIngest(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "locks")
+	if got, err := lastIngest(dir, "ds-1"); err != nil || got != "" {
+		t.Fatalf("lastIngest() = %q, %v, want nothing recorded", got, err)
+	}
+	if err := recordIngest(dir, "ds-1", "ing-1"); err != nil {
+		t.Fatalf("recordIngest() unexpected error: %v", err)
+	}
+	if got, err := lastIngest(dir, "ds-1"); err != nil || got != "ing-1" {
+		t.Fatalf("lastIngest() = %q, %v, want ing-1", got, err)
+	}
+}
@@ -0,0 +1,58 @@
+//go:build unix
+
+package maptiler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLockDataset(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	unlock, err := lockDataset(t.Context(), dir, "ds/1", 0)
+	if err != nil {
+		t.Fatalf("lockDataset() unexpected error: %v", err)
+	}
+
+	if _, err := lockDataset(t.Context(), dir, "ds/1", 0); !errors.Is(err, ErrIngestInProgress) {
+		t.Fatalf("lockDataset() error = %v, want %v", err, ErrIngestInProgress)
+	}
+
+	other, err := lockDataset(t.Context(), dir, "ds/2", 0)
+	if err != nil {
+		t.Fatalf("lockDataset() of another dataset unexpected error: %v", err)
+	}
+	_ = other()
+
+	time.AfterFunc(100*time.Millisecond, func() { _ = unlock() })
+	again, err := lockDataset(t.Context(), dir, "ds/1", 5*time.Second)
+	if err != nil {
+		t.Fatalf("lockDataset() with wait unexpected error: %v", err)
+	}
+	_ = again()
+}
+
+func TestRecoverFailsWhileLocked(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := recordIngest(dir, "ds-1", "ing-1"); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := lockDataset(t.Context(), dir, "ds-1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock() //nolint:errcheck
+
+	c, err := New("http://localhost", "token", WithLockDir(dir))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if _, err := c.Recover(t.Context(), "ds-1"); !errors.Is(err, ErrIngestInProgress) {
+		t.Fatalf("Recover() error = %v, want %v", err, ErrIngestInProgress)
+	}
+}
@@ -0,0 +1,198 @@
+package maptiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrPartsMissing is returned by Complete if not every part of the ingest was
+// reported as uploaded.
+var ErrPartsMissing = errors.New("parts missing")
+
+// PartAssignment is the share of the parts of an ingest one worker of a
+// distributed upload uploads, see Assign. It is serializable, so a coordinator
+// can hand it to workers on other machines.
+type PartAssignment struct {
+	IngestID string `json:"ingest_id"`
+	// Worker is the position of the assignment in the result of Assign.
+	Worker int `json:"worker"`
+	// Size and PartSize are those of the ingest, parts are cut from the file by
+	// their position.
+	Size     int64 `json:"size"`
+	PartSize int64 `json:"part_size"`
+	// First is the position of the first part of the assignment in the ingest.
+	First int            `json:"first"`
+	Parts []AssignedPart `json:"parts"`
+}
+
+// AssignedPart is a part of a PartAssignment, URL is the presigned upload target.
+type AssignedPart struct {
+	PartID int64  `json:"part_id"`
+	URL    string `json:"url"`
+}
+
+// PartReport is the outcome of UploadAssignment a worker sends back to the
+// coordinator.
+type PartReport struct {
+	IngestID string          `json:"ingest_id"`
+	Worker   int             `json:"worker"`
+	Parts    []CompletedPart `json:"parts"`
+	Stats    UploadStats     `json:"stats"`
+}
+
+// CompletedPart is an uploaded part and the ETag the upload target returned for it.
+type CompletedPart struct {
+	PartID int64  `json:"part_id"`
+	ETag   string `json:"etag"`
+}
+
+func (a PartAssignment) String() string { return toJSONString(a) }
+func (r PartReport) String() string     { return toJSONString(r) }
+
+// Begin creates an ingest of the file at fp without uploading it, to split the
+// upload across machines with Assign, UploadAssignment and Complete. The ingest
+// updates dataset id, or creates a new dataset if id is empty.
+//
+// Only the file checks and WithFilename of opts apply, the duplicate and
+// conflict checks and the locks of a single host do not. An ingest that is not
+// completed has to be canceled with Cancel.
+func (c *Client) Begin(ctx context.Context, id, fp string, opts ...IngestOption) (IngestResponse, error) {
+	cfg := newIngestConfig(opts...)
+	info, err := checkFile(osPath(fp), cfg)
+	if err != nil {
+		return IngestResponse{}, err
+	}
+	req := newIngestRequest(id, cfg.filename(info.Name()), info.Size())
+	req.Metadata = c.metadataOf(cfg)
+	req.PartSize = cfg.partSize
+	return c.ingest(ctx, req)
+}
+
+// Assign splits the parts of ir into workers assignments of consecutive parts,
+// so every worker reads a single range of the file. Assignments differ in size
+// by at most one part, workers beyond the number of parts get none.
+func Assign(ir IngestResponse, workers int) ([]PartAssignment, error) {
+	if workers < 1 {
+		return nil, fmt.Errorf("assigning parts of %s: expected at least one worker, got %d", ir.ID, workers)
+	}
+	parts := ir.Upload.Parts
+	workers = min(workers, len(parts))
+
+	assignments := make([]PartAssignment, 0, workers)
+	first := 0
+	for w := range workers {
+		n := len(parts) / workers
+		if w < len(parts)%workers {
+			n++
+		}
+		a := PartAssignment{
+			IngestID: ir.ID,
+			Worker:   w,
+			Size:     ir.Size,
+			PartSize: ir.Upload.PartSize,
+			First:    first,
+			Parts:    make([]AssignedPart, 0, n),
+		}
+		for _, p := range parts[first : first+n] {
+			a.Parts = append(a.Parts, AssignedPart(p))
+		}
+		assignments = append(assignments, a)
+		first += n
+	}
+	return assignments, nil
+}
+
+// UploadAssignment uploads the parts of a to their upload targets and reports
+// their ETags. fp has to be a copy of the file the ingest was begun with. The
+// upload options of opts, e.g. WithProgress, WithBandwidthLimit and
+// WithPartRetries, apply to the assignment alone.
+func (c *Client) UploadAssignment(ctx context.Context, a PartAssignment, fp string, opts ...IngestOption) (PartReport, error) {
+	cfg := newIngestConfig(opts...)
+	fp = osPath(fp)
+	info, err := os.Stat(fp)
+	if err != nil {
+		return PartReport{}, fmt.Errorf("uploading assignment %d of %s: %w: %w", a.Worker, a.IngestID, ErrInvalidFile, err)
+	}
+	if info.Size() != a.Size {
+		return PartReport{}, fmt.Errorf("uploading assignment %d of %s: %w: size %d, expected %d",
+			a.Worker, a.IngestID, ErrInvalidFile, info.Size(), a.Size)
+	}
+
+	parts := make(uploadParts, 0, len(a.Parts))
+	var size int64
+	for i, p := range a.Parts {
+		_, length := getRange(int64(a.First+i), a.PartSize, a.Size)
+		if length <= 0 {
+			return PartReport{}, fmt.Errorf("uploading assignment %d of %s: part %d is beyond the end of the file",
+				a.Worker, a.IngestID, p.PartID)
+		}
+		parts = append(parts, uploadPart(p))
+		size += length
+	}
+
+	ir := IngestResponse{
+		ID:     a.IngestID,
+		Size:   a.Size,
+		Upload: upload{PartSize: a.PartSize, Parts: parts, Type: ingestUploadTypeS3MultiPart},
+	}
+	pt := newProgressTracker(cfg.progress)
+	pt.start(a.IngestID, len(parts), size)
+
+	opt := c.uploadOptions(cfg, pt)
+	opt.first = a.First
+	ur, err := c.upload(ctx, ir, fp, opt)
+	if err != nil {
+		return PartReport{}, fmt.Errorf("uploading assignment %d of %s: %w", a.Worker, a.IngestID, err)
+	}
+	pt.phase(PhaseDone)
+
+	r := PartReport{
+		IngestID: a.IngestID,
+		Worker:   a.Worker,
+		Parts:    make([]CompletedPart, 0, len(ur.Parts)),
+		Stats:    ur.Stats,
+	}
+	for _, p := range ur.Parts {
+		r.Parts = append(r.Parts, CompletedPart{PartID: p.PartID, ETag: p.ETag})
+	}
+	return r, nil
+}
+
+// Complete finalizes the distributed upload of ir with the reports of all of
+// its workers. It fails with ErrPartsMissing before finalizing if a part of ir
+// is in none of the reports, so a failed worker can be run again.
+func (c *Client) Complete(ctx context.Context, ir IngestResponse, reports ...PartReport) (IngestResponse, error) {
+	etags := make(map[int64]string, len(ir.Upload.Parts))
+	for _, r := range reports {
+		if r.IngestID != ir.ID {
+			return IngestResponse{}, fmt.Errorf("completing %s: report of worker %d is for ingest %s", ir.ID, r.Worker, r.IngestID)
+		}
+		for _, p := range r.Parts {
+			etags[p.PartID] = p.ETag
+		}
+	}
+
+	parts := make([]uploadTaskResponse, 0, len(ir.Upload.Parts))
+	var missing []int64
+	for _, p := range ir.Upload.Parts {
+		etag, ok := etags[p.PartID]
+		if !ok {
+			missing = append(missing, p.PartID)
+			continue
+		}
+		parts = append(parts, uploadTaskResponse{PartID: p.PartID, ETag: etag})
+	}
+	if len(missing) > 0 {
+		return IngestResponse{}, fmt.Errorf("completing %s: %w: %d of %d, e.g. part %d",
+			ir.ID, ErrPartsMissing, len(missing), len(ir.Upload.Parts), missing[0])
+	}
+
+	resp, err := c.finalize(ctx, newUploadResult(ir.ID, parts))
+	if err != nil {
+		return IngestResponse{}, fmt.Errorf("completing %s: %w", ir.ID, err)
+	}
+	resp.Tileset = c.tileset(resp.State, resp.DocumentID)
+	return resp, nil
+}
@@ -0,0 +1,134 @@
+package maptiler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestAssign(t *testing.T) {
+	t.Parallel()
+
+	parts := make(uploadParts, 5)
+	for i := range parts {
+		parts[i] = uploadPart{PartID: int64(i + 1), URL: "u"}
+	}
+	ir := IngestResponse{ID: "ingest", Size: 50, Upload: upload{PartSize: 10, Parts: parts}}
+
+	tests := []struct {
+		name      string
+		workers   int
+		wantSizes []int
+		wantErr   bool
+	}{
+		{name: "one worker", workers: 1, wantSizes: []int{5}},
+		{name: "uneven", workers: 2, wantSizes: []int{3, 2}},
+		{name: "one part each", workers: 5, wantSizes: []int{1, 1, 1, 1, 1}},
+		{name: "more workers than parts", workers: 8, wantSizes: []int{1, 1, 1, 1, 1}},
+		{name: "no workers", workers: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Assign(ir, tt.workers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Assign() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.wantSizes) {
+				t.Fatalf("got %d assignments, want %d", len(got), len(tt.wantSizes))
+			}
+			next := int64(1)
+			for i, a := range got {
+				if a.Worker != i || a.IngestID != "ingest" || len(a.Parts) != tt.wantSizes[i] {
+					t.Fatalf("assignment %d: unexpected %+v", i, a)
+				}
+				if a.First != int(next-1) {
+					t.Fatalf("assignment %d: first = %d, want %d", i, a.First, next-1)
+				}
+				for _, p := range a.Parts {
+					if p.PartID != next {
+						t.Fatalf("assignment %d: part %d, want %d", i, p.PartID, next)
+					}
+					next++
+				}
+			}
+		})
+	}
+}
+
+func TestDistributedUpload(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	coordinator, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ir, err := coordinator.Begin(t.Context(), "", fp)
+	if err != nil {
+		t.Fatalf("Begin() unexpected error: %v", err)
+	}
+	assignments, err := Assign(ir, 2)
+	if err != nil {
+		t.Fatalf("Assign() unexpected error: %v", err)
+	}
+
+	// every worker has its own client and copy of the file.
+	reports := make([]PartReport, 0, len(assignments))
+	for _, a := range assignments {
+		worker, err := New(srv.URL, "token")
+		if err != nil {
+			t.Fatalf("New() failed: %v", err)
+		}
+		copyPath := filepath.Join(t.TempDir(), "tiles.pmtiles")
+		if err := os.WriteFile(copyPath, []byte("0123456789"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		r, err := worker.UploadAssignment(t.Context(), a, copyPath)
+		if err != nil {
+			t.Fatalf("UploadAssignment(%d) unexpected error: %v", a.Worker, err)
+		}
+		if len(r.Parts) != len(a.Parts) {
+			t.Fatalf("worker %d reported %d parts, want %d", a.Worker, len(r.Parts), len(a.Parts))
+		}
+		reports = append(reports, r)
+	}
+
+	if _, err := coordinator.Complete(t.Context(), ir, reports[0]); !errors.Is(err, ErrPartsMissing) {
+		t.Fatalf("Complete() with a missing report: error = %v, want %v", err, ErrPartsMissing)
+	}
+
+	done, err := coordinator.Complete(t.Context(), ir, reports...)
+	if err != nil {
+		t.Fatalf("Complete() unexpected error: %v", err)
+	}
+	if done.State != StateCompleted || IngestState(srv.State(ir.ID)) != StateCompleted {
+		t.Fatalf("unexpected response %+v", done)
+	}
+}
+
+func TestUploadAssignmentRejectsOtherFile(t *testing.T) {
+	t.Parallel()
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("short"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a := PartAssignment{IngestID: "ingest", Size: 10, PartSize: 4, Parts: []AssignedPart{{PartID: 1, URL: "u1"}}}
+
+	c := newClientWithPool(t, &fakeProcessor{}, 1)
+	if _, err := c.UploadAssignment(t.Context(), a, fp); !errors.Is(err, ErrInvalidFile) {
+		t.Fatalf("UploadAssignment() error = %v, want %v", err, ErrInvalidFile)
+	}
+}
@@ -0,0 +1,86 @@
+package maptiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ErrDuplicateName is returned when WithDuplicateCheck is used without upsert
+// and a dataset with the same name was created before.
+var ErrDuplicateName = errors.New("dataset with the same name exists")
+
+// nameFile returns the path of the file recording the dataset created as name.
+func nameFile(dir, name string) string {
+	return datasetFile(dir, "name:"+name, ".dataset")
+}
+
+// recordName records that dataset id was created as name.
+func recordName(dir, name, id string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("creating lock directory: %w", err)
+	}
+	if err := os.WriteFile(nameFile(dir, name), []byte(id), 0o600); err != nil {
+		return fmt.Errorf("recording dataset name: %w", err)
+	}
+	return nil
+}
+
+// datasetByName returns the dataset recorded for name in the lock directory if
+// it still exists. Records of deleted datasets are removed.
+func (c *Client) datasetByName(ctx context.Context, name string) (string, error) {
+	b, err := os.ReadFile(nameFile(c.lockDir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading dataset name: %w", err)
+	}
+	id := strings.TrimSpace(string(b))
+	if id == "" {
+		return "", nil
+	}
+
+	_, err = c.GetDataset(ctx, id)
+	var aerr APIError
+	if errors.As(err, &aerr) && aerr.StatusCode == http.StatusNotFound {
+		if rerr := os.Remove(nameFile(c.lockDir, name)); rerr != nil && !errors.Is(rerr, os.ErrNotExist) {
+			return "", fmt.Errorf("removing dataset name: %w", rerr)
+		}
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("checking for duplicate dataset names: %w", err)
+	}
+	return id, nil
+}
+
+// checkDuplicate returns the dataset a Create of name has to update instead, or
+// fails with ErrDuplicateName if a dataset was created as name before.
+func (c *Client) checkDuplicate(ctx context.Context, name string, cfg ingestConfig) (string, error) {
+	if !cfg.duplicateCheck {
+		return "", nil
+	}
+	id, err := c.datasetByName(ctx, name)
+	if err != nil || id == "" {
+		return "", err
+	}
+	if !cfg.upsert {
+		return "", fmt.Errorf("dataset %s is named %q: %w", id, name, ErrDuplicateName)
+	}
+	return id, nil
+}
+
+// recordCreate records the name of dataset id for the duplicate check of later
+// creates. A failure is logged, it must not fail the finished ingest.
+func (c *Client) recordCreate(name, id string, cfg ingestConfig) {
+	if !cfg.duplicateCheck || id == "" {
+		return
+	}
+	if err := recordName(c.lockDir, name, id); err != nil {
+		logger(c.log).Warn("duplicate check of the next create will be skipped", "name", name, "error", err)
+	}
+}
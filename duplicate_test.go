@@ -0,0 +1,87 @@
+package maptiler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestClientDuplicateCheck(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer()
+	defer srv.Close()
+
+	lockDir := t.TempDir()
+	c, err := New(srv.URL, "token", WithLockDir(lockDir))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	fp := filepath.Join(t.TempDir(), "roads.pmtiles")
+	if err := os.WriteFile(fp, []byte("tiles"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := c.Create(t.Context(), fp, WithDuplicateCheck(false))
+	if err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+
+	if _, err := c.Create(t.Context(), fp, WithDuplicateCheck(false)); !errors.Is(err, ErrDuplicateName) {
+		t.Fatalf("expected ErrDuplicateName, got %v", err)
+	}
+
+	// other names and creates without the check are not affected.
+	if _, err := c.Create(t.Context(), fp, WithDuplicateCheck(false), WithFilename("rivers")); err != nil {
+		t.Fatalf("Create() of another name unexpected error: %v", err)
+	}
+	if _, err := c.Create(t.Context(), fp); err != nil {
+		t.Fatalf("Create() without check unexpected error: %v", err)
+	}
+
+	upserted, err := c.Create(t.Context(), fp, WithDuplicateCheck(true))
+	if err != nil {
+		t.Fatalf("Create() with upsert unexpected error: %v", err)
+	}
+	if upserted.DocumentID != first.DocumentID || upserted.ID == first.ID {
+		t.Fatalf("expected a new ingest of dataset %s, got ingest %s of %s", first.DocumentID, upserted.ID, upserted.DocumentID)
+	}
+}
+
+func TestClientDuplicateCheckDeletedDataset(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer()
+	defer srv.Close()
+
+	lockDir := t.TempDir()
+	c, err := New(srv.URL, "token", WithLockDir(lockDir))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	fp := filepath.Join(t.TempDir(), "roads.pmtiles")
+	if err := os.WriteFile(fp, []byte("tiles"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordName(lockDir, "roads.pmtiles", "deleted-dataset"); err != nil {
+		t.Fatal(err)
+	}
+
+	ir, err := c.Create(t.Context(), fp, WithDuplicateCheck(false))
+	if err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+
+	id, err := c.datasetByName(t.Context(), "roads.pmtiles")
+	if err != nil {
+		t.Fatalf("datasetByName() unexpected error: %v", err)
+	}
+	if id != ir.DocumentID {
+		t.Fatalf("expected the name to be recorded for %s, got %q", ir.DocumentID, id)
+	}
+}
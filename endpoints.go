@@ -0,0 +1,104 @@
+package maptiler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrReadOnly is returned for calls that would change anything, e.g. create,
+// update, cancel or delete, by a Client created with WithReadOnly. They are
+// rejected before they are sent.
+var ErrReadOnly = errors.New("client is read-only")
+
+// endpoint declares an endpoint of the service API. Req is the JSON body that is
+// sent, none for endpoints without one, and Resp the type the response is
+// decoded into. The path depends on the API version, see apiVersions.
+type endpoint[Req, Resp any] struct {
+	id     Endpoint
+	method string
+	// cache is the prefix of the response cache key of GET endpoints, which
+	// are then revalidated with If-None-Match.
+	cache string
+}
+
+// none is the request type of endpoints without a body.
+type none = struct{}
+
+// The endpoints of the service API. A new endpoint is declared here, gets a
+// path in every version of apiVersions and is added to endpoints.
+var (
+	ingestCreate  = endpoint[ingestRequest, IngestResponse]{id: EndpointIngestCreate, method: http.MethodPost}
+	ingestUpdate  = endpoint[ingestRequest, IngestResponse]{id: EndpointIngestUpdate, method: http.MethodPost}
+	ingestGet     = endpoint[none, IngestGetResponse]{id: EndpointIngestGet, method: http.MethodGet, cache: "ingest"}
+	ingestCancel  = endpoint[none, IngestResponse]{id: EndpointIngestCancel, method: http.MethodPost}
+	ingestProcess = endpoint[uploadResultRequest, IngestResponse]{id: EndpointIngestProcess, method: http.MethodPost}
+	datasetGet    = endpoint[none, Dataset]{id: EndpointDatasetGet, method: http.MethodGet, cache: "dataset"}
+	datasetDelete = endpoint[none, none]{id: EndpointDatasetDelete, method: http.MethodDelete}
+)
+
+// anyEndpoint is an endpoint regardless of its types, to handle all of them alike.
+type anyEndpoint interface {
+	spec() (Endpoint, string)
+	send(ctx context.Context, c *Client, id string) ([]byte, error)
+}
+
+// endpoints lists every declared endpoint.
+var endpoints = []anyEndpoint{ingestCreate, ingestUpdate, ingestGet, ingestCancel, ingestProcess, datasetGet, datasetDelete}
+
+func (e endpoint[Req, Resp]) spec() (Endpoint, string) { return e.id, e.method }
+
+func (e endpoint[Req, Resp]) send(ctx context.Context, c *Client, id string) ([]byte, error) {
+	return e.do(ctx, c, id, nil)
+}
+
+// call sends body to the endpoint with :id replaced by id and decodes the response.
+func (e endpoint[Req, Resp]) call(ctx context.Context, c *Client, id string, body *Req) (Resp, error) {
+	b, err := e.do(ctx, c, id, body)
+	if err != nil {
+		var zero Resp
+		return zero, err
+	}
+	return e.decode(b)
+}
+
+// do sends body to the endpoint with :id replaced by id and returns the body of
+// the response. It waits for the API rate limit, error responses are returned
+// as APIError, see serviceError. A read-only Client only sends GET requests.
+func (e endpoint[Req, Resp]) do(ctx context.Context, c *Client, id string, body *Req) ([]byte, error) {
+	if c.readOnly && e.method != http.MethodGet {
+		return nil, fmt.Errorf("%s %s: %w", e.method, e.id, ErrReadOnly)
+	}
+	if err := c.apiLimit.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	req := request{method: e.method, path: c.paths[e.id], id: id}
+	if body != nil {
+		req.body = *body
+	}
+
+	if e.method == http.MethodGet && e.cache != "" {
+		return c.cache.get(ctx, c.h, req, e.cache+":"+id)
+	}
+
+	resp, err := c.h.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if isError(resp) {
+		return nil, serviceError(newAPIError(resp))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// decode decodes the body of a response of the endpoint.
+func (e endpoint[Req, Resp]) decode(b []byte) (Resp, error) {
+	var r Resp
+	err := json.Unmarshal(b, &r)
+	return r, err
+}
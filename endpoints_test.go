@@ -0,0 +1,59 @@
+package maptiler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEndpoints(t *testing.T) {
+	t.Parallel()
+
+	for _, e := range endpoints {
+		id, method := e.spec()
+		t.Run(string(id), func(t *testing.T) {
+			t.Parallel()
+
+			var gotMethod, gotPath, gotAuth string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotMethod, gotPath, gotAuth = r.Method, r.URL.Path, r.Header.Get("Authorization")
+				if strings.Contains(r.URL.Path, "missing") {
+					http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{}`)) //nolint:errcheck
+			}))
+			defer srv.Close()
+
+			c, err := New(srv.URL+"/v1", "token")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := e.send(t.Context(), c, "abc"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := "/v1" + strings.ReplaceAll(apiVersions[DefaultAPIVersion][id], ":id", "abc")
+			if gotMethod != method || gotPath != want {
+				t.Fatalf("expected %s %s, got %s %s", method, want, gotMethod, gotPath)
+			}
+			if gotAuth != "Token token" {
+				t.Fatalf("expected the token, got %q", gotAuth)
+			}
+
+			// error responses are APIErrors for every endpoint, paths without
+			// :id do not carry the id and are not expected to fail.
+			if !strings.Contains(apiVersions[DefaultAPIVersion][id], ":id") {
+				return
+			}
+			_, err = e.send(t.Context(), c, "missing")
+			var aerr APIError
+			if !errors.As(err, &aerr) || aerr.StatusCode != http.StatusNotFound {
+				t.Fatalf("expected an APIError with 404, got %v", err)
+			}
+		})
+	}
+}
@@ -1,12 +1,268 @@
 package maptiler
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidFile is returned when the file to ingest does not exist or is not a regular file.
+var ErrInvalidFile = errors.New("invalid file")
+
+// ErrUploadMismatch is returned when the parts of a new ingest do not cover
+// the file, e.g. as the service returned too few parts or no part size. The
+// ingest is canceled before anything is uploaded.
+var ErrUploadMismatch = errors.New("upload does not match the file")
+
+// ErrFileLocked is returned when WithFileLock is used and another process holds a lock on the file.
+var ErrFileLocked = errors.New("file is locked by another process")
 
 type UploadFailedError struct {
 	ID  string
 	Err error
+	// Journal is the path of the journal to resume the upload with, see
+	// WithJournal. The ingest is not canceled if it is set.
+	Journal string
 }
 
 func (e UploadFailedError) Error() string {
 	return fmt.Sprintf("upload %s failed, err: %s", e.ID, e.Err)
 }
+
+func (e UploadFailedError) Unwrap() error { return e.Err }
+
+// PhaseTimeoutError is returned when a Phase of an ingest runs out of time:
+// a request of the phase exceeded the timeout of WithTimeout, e.g. as the
+// service API is slow, or the deadline of the context passed, e.g. as the file
+// is too large to upload before it. It wraps the timeout error.
+type PhaseTimeoutError struct {
+	Phase Phase
+	// Elapsed is the time the phase ran.
+	Elapsed time.Duration
+	// Configured is the timeout of the requests of the phase, or the time left
+	// until the deadline of the context when the phase started.
+	Configured time.Duration
+	Err        error
+}
+
+func (e PhaseTimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s of %s: %s",
+		e.Phase, e.Elapsed.Round(time.Millisecond), e.Configured.Round(time.Millisecond), e.Err)
+}
+
+func (e PhaseTimeoutError) Unwrap() error { return e.Err }
+
+// phaseTimeout returns err as a PhaseTimeoutError of phase, which started at
+// start, if the deadline of ctx passed or err is the timeout of a request
+// limited to timeout. Other errors are returned as they are.
+func phaseTimeout(ctx context.Context, phase Phase, start time.Time, timeout time.Duration, err error) error {
+	var terr PhaseTimeoutError
+	if err == nil || errors.As(err, &terr) {
+		return err
+	}
+	var nerr net.Error
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		deadline, _ := ctx.Deadline()
+		timeout = deadline.Sub(start)
+	case timeout > 0 && errors.As(err, &nerr) && nerr.Timeout():
+	default:
+		return err
+	}
+	return PhaseTimeoutError{Phase: phase, Elapsed: time.Since(start), Configured: timeout, Err: err}
+}
+
+// FileError is returned by batch operations for every file that failed.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e FileError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+func (e FileError) Unwrap() error { return e.Err }
+
+// APIError is returned when the MapTiler service API responds with a non-2xx status code.
+type APIError struct {
+	StatusCode int
+	Body       []byte
+	// S3 is the parsed error document of a failing part upload, if the upload
+	// target returned one.
+	S3 *S3Error
+	// Request is the request of a failing part upload with WithUploadDebug.
+	Request *PartRequest
+	// RetryAfter is how long the server asked to wait before the next attempt,
+	// from its Retry-After header. Retries wait at least this long.
+	RetryAfter time.Duration
+}
+
+// newAPIError returns the APIError of a failed response, its body is read as
+// far as possible.
+func newAPIError(resp *http.Response) APIError {
+	body, _ := io.ReadAll(resp.Body) // the status is the error, the body only details it.
+	return APIError{
+		StatusCode: resp.StatusCode,
+		Body:       body,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// AuthError is returned when the service API rejects the token, with 401 or
+// 403, e.g. as it is invalid or lacks a scope.
+type AuthError struct{ APIError }
+
+// RateLimitError is returned when the service API rate limits the client with
+// 429. RetryAfter is the delay the server asked for, if any.
+type RateLimitError struct{ APIError }
+
+// NotFoundError is returned when the service API responds with 404, e.g. as
+// the dataset or ingest does not exist.
+type NotFoundError struct{ APIError }
+
+func (e AuthError) Error() string      { return "unauthorized: " + e.APIError.Error() }
+func (e RateLimitError) Error() string { return "rate limited: " + e.APIError.Error() }
+func (e NotFoundError) Error() string  { return "not found: " + e.APIError.Error() }
+
+func (e AuthError) Unwrap() error      { return e.APIError }
+func (e RateLimitError) Unwrap() error { return e.APIError }
+func (e NotFoundError) Unwrap() error  { return e.APIError }
+
+// serviceError returns aerr of a response of the service API as AuthError,
+// RateLimitError or NotFoundError by its status. errors.As finds the APIError
+// in all of them.
+func serviceError(aerr APIError) error {
+	switch aerr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return AuthError{aerr}
+	case http.StatusTooManyRequests:
+		return RateLimitError{aerr}
+	case http.StatusNotFound:
+		return NotFoundError{aerr}
+	default:
+		return aerr
+	}
+}
+
+// parseRetryAfter parses a Retry-After header of delay seconds or an HTTP date.
+// Invalid values and dates in the past are 0.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs <= 0 || secs > math.MaxInt64/int64(time.Second) {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// retryAfter returns the delay requested by the server of err, if any.
+func retryAfter(err error) time.Duration {
+	var aerr APIError
+	if errors.As(err, &aerr) {
+		return aerr.RetryAfter
+	}
+	return 0
+}
+
+// isRejected reports whether the service rejected a call without handling it,
+// because it was rate limited or unavailable. Calls that are not safe to repeat
+// are retried in this case only. A 502 of a gateway may hide a call that was
+// handled after all.
+func isRejected(err error) bool {
+	var aerr APIError
+	if !errors.As(err, &aerr) || aerr.S3 != nil {
+		return false
+	}
+	switch aerr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable:
+		return true
+	default:
+		return false
+	}
+}
+
+func (e APIError) Error() string {
+	if e.S3 != nil {
+		return fmt.Sprintf("request failed with %d: %s", e.StatusCode, e.S3)
+	}
+	return fmt.Sprintf("request failed with %d", e.StatusCode)
+}
+
+func (e APIError) Unwrap() error {
+	if e.S3 == nil {
+		return nil
+	}
+	return *e.S3
+}
+
+// RetryBudgetExhaustedError is returned when a part fails after the retry budget
+// of its ingest is used up. It reports the consumed budget and wraps the last error.
+type RetryBudgetExhaustedError struct {
+	Retries   int
+	RetryTime time.Duration
+	Err       error
+}
+
+func (e RetryBudgetExhaustedError) Error() string {
+	return fmt.Sprintf("retry budget exhausted after %d retries in %s: %s", e.Retries, e.RetryTime, e.Err)
+}
+
+func (e RetryBudgetExhaustedError) Unwrap() error { return e.Err }
+
+// CircuitOpenError is returned for parts that are not sent because the upload
+// host failed too many times in a row. It wraps the last failure of the host.
+type CircuitOpenError struct {
+	Host     string
+	Failures int
+	Err      error
+}
+
+func (e CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for host %s after %d consecutive failures: %s", e.Host, e.Failures, e.Err)
+}
+
+func (e CircuitOpenError) Unwrap() error { return e.Err }
+
+// IsRetryable reports whether an error returned by the client is worth retrying.
+// Invalid input, canceled contexts, redirects that are not allowed and client
+// errors (4xx except 408 and 429) are considered permanent, everything else is
+// considered transient. S3 reports idle connections with 400 RequestTimeout,
+// which is transient as well.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrInvalidFile) || errors.Is(err, context.Canceled) || errors.Is(err, ErrRedirectNotAllowed) {
+		return false
+	}
+
+	var aerr APIError
+	if errors.As(err, &aerr) {
+		switch {
+		case aerr.S3 != nil && aerr.S3.Code == "RequestTimeout":
+			return true
+		case aerr.StatusCode == http.StatusRequestTimeout,
+			aerr.StatusCode == http.StatusTooManyRequests,
+			aerr.StatusCode >= http.StatusInternalServerError:
+			return true
+		default:
+			return false
+		}
+	}
+
+	return true
+}
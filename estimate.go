@@ -0,0 +1,66 @@
+package maptiler
+
+import "fmt"
+
+const (
+	// minPartSize is the smallest size of all but the last part of a multipart upload.
+	minPartSize = 5 << 20
+	// maxParts is the largest number of parts of a multipart upload.
+	maxParts = 10000
+)
+
+// UploadPlan describes how a file is split into parts at a given part size.
+type UploadPlan struct {
+	PartSize     int64 `json:"part_size"`
+	Parts        int64 `json:"parts"`
+	LastPartSize int64 `json:"last_part_size"`
+	// Problem is set if the plan violates a limit of multipart uploads.
+	Problem string `json:"problem,omitempty"`
+}
+
+func (p UploadPlan) String() string { return toJSONString(p) }
+
+// checkUpload returns ErrUploadMismatch if the parts of ir are too few to cover
+// the file of req at the part size returned by the service. Surplus parts are
+// left out by the upload. A response without an upload is not checked.
+func checkUpload(req ingestRequest, ir IngestResponse) error {
+	ps := ir.Upload.PartSize
+	if ps == 0 && len(ir.Upload.Parts) == 0 {
+		return nil
+	}
+	if ps <= 0 {
+		return fmt.Errorf("%w: part size %d", ErrUploadMismatch, ps)
+	}
+	if want := (req.Size + ps - 1) / ps; int64(len(ir.Upload.Parts)) < want {
+		return fmt.Errorf("%w: %d parts of %s for %s, want %d",
+			ErrUploadMismatch, len(ir.Upload.Parts), formatBytes(ps), formatBytes(req.Size), want)
+	}
+	return nil
+}
+
+// PlanUpload returns the upload plan of a file of size bytes for every part size.
+// The part size is chosen by the MapTiler service on ingest, WithPartSize asks for
+// one, the plans show what to expect before an upload is started.
+func PlanUpload(size int64, partSizes ...int64) []UploadPlan {
+	plans := make([]UploadPlan, 0, len(partSizes))
+	for _, ps := range partSizes {
+		p := UploadPlan{PartSize: ps}
+		switch {
+		case ps <= 0:
+			p.Problem = "part size must be positive"
+		case size > 0:
+			p.Parts = (size + ps - 1) / ps
+			_, p.LastPartSize = getRange(p.Parts-1, ps, size)
+		}
+
+		switch {
+		case p.Problem != "":
+		case p.Parts > maxParts:
+			p.Problem = fmt.Sprintf("more than %d parts", maxParts)
+		case p.Parts > 1 && ps < minPartSize:
+			p.Problem = "parts other than the last must be at least " + formatBytes(minPartSize)
+		}
+		plans = append(plans, p)
+	}
+	return plans
+}
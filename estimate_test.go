@@ -0,0 +1,168 @@
+package maptiler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestPlanUpload(t *testing.T) {
+	t.Parallel()
+
+	const mib = 1 << 20
+
+	tests := []struct {
+		name      string
+		size      int64
+		partSizes []int64
+		want      []UploadPlan
+	}{
+		{
+			name:      "even and uneven",
+			size:      20 * mib,
+			partSizes: []int64{10 * mib, 8 * mib},
+			want: []UploadPlan{
+				{PartSize: 10 * mib, Parts: 2, LastPartSize: 10 * mib},
+				{PartSize: 8 * mib, Parts: 3, LastPartSize: 4 * mib},
+			},
+		},
+		{
+			name:      "single small part",
+			size:      100,
+			partSizes: []int64{mib},
+			want:      []UploadPlan{{PartSize: mib, Parts: 1, LastPartSize: 100}},
+		},
+		{
+			name:      "parts too small",
+			size:      2 * mib,
+			partSizes: []int64{mib},
+			want: []UploadPlan{
+				{PartSize: mib, Parts: 2, LastPartSize: mib, Problem: "parts other than the last must be at least 5.0 MiB"},
+			},
+		},
+		{
+			name:      "too many parts",
+			size:      10001 * 5 * mib,
+			partSizes: []int64{5 * mib},
+			want: []UploadPlan{
+				{PartSize: 5 * mib, Parts: 10001, LastPartSize: 5 * mib, Problem: "more than 10000 parts"},
+			},
+		},
+		{
+			name:      "invalid part size",
+			size:      1,
+			partSizes: []int64{0},
+			want:      []UploadPlan{{Problem: "part size must be positive"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := PlanUpload(tt.size, tt.partSizes...); !slices.Equal(got, tt.want) {
+				t.Fatalf("PlanUpload() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithPartSize(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var parts atomic.Int32
+	_, err = c.Create(t.Context(), fp, WithPartSize(5), WithProgress(func(p Progress) {
+		parts.Store(int32(p.PartsTotal)) //nolint:gosec
+	}))
+	if err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+	if n := parts.Load(); n != 2 {
+		t.Fatalf("uploaded %d parts, want 2 of the requested part size", n)
+	}
+}
+
+func TestCreateUploadMismatch(t *testing.T) {
+	t.Parallel()
+
+	var canceled atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/cancel") {
+			canceled.Store(true)
+			_, _ = w.Write([]byte(`{"id":"ingest-1","state":"canceled"}`))
+			return
+		}
+		// a single part of 4 bytes does not cover the file.
+		_, _ = w.Write([]byte(`{"id":"ingest-1","state":"upload","upload":{"part_size":4,"parts":[{"part_id":1}]}}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Create(t.Context(), fp)
+	if !errors.Is(err, ErrUploadMismatch) {
+		t.Fatalf("Create() error = %v, want ErrUploadMismatch", err)
+	}
+	if !canceled.Load() {
+		t.Fatal("expected the ingest to be canceled")
+	}
+}
+
+func TestCheckUpload(t *testing.T) {
+	t.Parallel()
+
+	parts := func(n int) uploadParts {
+		ps := make(uploadParts, n)
+		for i := range ps {
+			ps[i] = uploadPart{PartID: int64(i + 1)}
+		}
+		return ps
+	}
+	tests := []struct {
+		name    string
+		size    int64
+		upload  upload
+		wantErr bool
+	}{
+		{name: "exact", size: 10, upload: upload{PartSize: 4, Parts: parts(3)}},
+		{name: "more parts than needed", size: 10, upload: upload{PartSize: 4, Parts: parts(4)}},
+		{name: "empty file with one part", size: 0, upload: upload{PartSize: 4, Parts: parts(1)}},
+		{name: "no upload", size: 10},
+		{name: "too few parts", size: 10, upload: upload{PartSize: 4, Parts: parts(2)}, wantErr: true},
+		{name: "no part size", size: 10, upload: upload{Parts: parts(3)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := checkUpload(newIngestRequest("", "tiles.pmtiles", tt.size), IngestResponse{Upload: tt.upload})
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrUploadMismatch)) {
+				t.Fatalf("checkUpload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
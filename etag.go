@@ -0,0 +1,38 @@
+package maptiler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrEmptyETag is returned when an upload target responds without an ETag.
+var ErrEmptyETag = errors.New("empty etag in response header")
+
+// ETagExtractor returns the part identifier from the response of a part upload.
+// The response body may be read, it is closed by the client.
+type ETagExtractor func(resp *http.Response) (string, error)
+
+// HeaderETag is the default ETagExtractor, it returns the ETag response header.
+func HeaderETag(resp *http.Response) (string, error) {
+	return resp.Header.Get("ETag"), nil
+}
+
+// ETagNormalizer turns the ETag returned by an upload target into the value
+// that is sent to the MapTiler service when the upload is finalized.
+type ETagNormalizer func(etag string) (string, error)
+
+// NormalizeETag is the default ETagNormalizer. S3 compatible backends differ in
+// whether they quote ETags or return weak ETags, so it trims whitespace, drops a
+// weak validator prefix (W/) and returns the ETag quoted the way S3 does.
+func NormalizeETag(etag string) (string, error) {
+	e := strings.TrimSpace(etag)
+	if len(e) > 1 && (e[0] == 'W' || e[0] == 'w') && e[1] == '/' {
+		e = e[2:]
+	}
+	e = strings.Trim(e, `"`)
+	if e == "" {
+		return "", ErrEmptyETag
+	}
+	return `"` + e + `"`, nil
+}
@@ -0,0 +1,38 @@
+package maptiler
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeETag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr error
+	}{
+		{in: `"abc123"`, want: `"abc123"`},
+		{in: `abc123`, want: `"abc123"`},
+		{in: ` "abc123" `, want: `"abc123"`},
+		{in: `W/"abc123"`, want: `"abc123"`},
+		{in: `w/abc123`, want: `"abc123"`},
+		{in: `"d41d8cd98f00b204e9800998ecf8427e-3"`, want: `"d41d8cd98f00b204e9800998ecf8427e-3"`},
+		{in: `""`, wantErr: ErrEmptyETag},
+		{in: `W/""`, wantErr: ErrEmptyETag},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeETag(tt.in)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NormalizeETag(%q) error=%v want %v", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Fatalf("NormalizeETag(%q)=%q, %v want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package maptiler
+
+import "time"
+
+// taskEventKind is the step in the lifecycle of a task a taskEvent reports.
+type taskEventKind int
+
+const (
+	// taskEnqueued is emitted once a task was added to the queue.
+	taskEnqueued taskEventKind = iota
+	// taskStarted is emitted when a worker picks up a task.
+	taskStarted
+	// taskRetried is emitted by the processor before it tries a task again.
+	taskRetried
+	// taskFinished is emitted when a task was processed successfully.
+	taskFinished
+	// taskFailed is emitted when processing a task returned an error.
+	taskFailed
+)
+
+func (k taskEventKind) String() string {
+	switch k {
+	case taskEnqueued:
+		return "enqueued"
+	case taskStarted:
+		return "started"
+	case taskRetried:
+		return "retried"
+	case taskFinished:
+		return "finished"
+	case taskFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// taskEvent describes a change in the lifecycle of a task.
+type taskEvent[T any] struct {
+	Kind taskEventKind
+	Task task[T]
+	// Attempt is the number of the retry for taskRetried events.
+	Attempt int
+	// Err is the cause of taskRetried and taskFailed events.
+	Err error
+	// Duration is the time since the task was started for taskFinished and taskFailed events.
+	Duration time.Duration
+}
+
+// taskListener receives the lifecycle events of the tasks of a pool. Events are
+// delivered synchronously from the workers, implementations must be safe for
+// concurrent use and should return quickly.
+type taskListener[T any] interface {
+	OnTaskEvent(e taskEvent[T])
+}
+
+// taskListenerFunc adapts a function to a taskListener.
+type taskListenerFunc[T any] func(e taskEvent[T])
+
+func (f taskListenerFunc[T]) OnTaskEvent(e taskEvent[T]) { f(e) }
+
+// taskListeners forwards events to every listener in order.
+type taskListeners[T any] []taskListener[T]
+
+func (ls taskListeners[T]) OnTaskEvent(e taskEvent[T]) {
+	for _, l := range ls {
+		l.OnTaskEvent(e)
+	}
+}
+
+// joinListeners returns a listener forwarding to all listeners that are not nil.
+func joinListeners[T any](listeners ...taskListener[T]) taskListener[T] {
+	var ls taskListeners[T]
+	for _, l := range listeners {
+		if l != nil {
+			ls = append(ls, l)
+		}
+	}
+	if len(ls) == 0 {
+		return nil
+	}
+	return ls
+}
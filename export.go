@@ -0,0 +1,80 @@
+package maptiler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const resourceTypeDataset = "maptiler_dataset"
+
+// Resource is a stable, resource shaped representation of a MapTiler object.
+// The checksum covers type, id and attributes, so two exports of an unchanged
+// object always compare equal and drift can be detected by comparing checksums.
+type Resource struct {
+	Type       string            `json:"type"`
+	ID         string            `json:"id"`
+	Attributes map[string]string `json:"attributes"`
+	Checksum   string            `json:"checksum"`
+}
+
+func (r Resource) String() string { return toJSONString(r) }
+
+// NewDatasetResource returns the Resource representation of a dataset.
+func NewDatasetResource(d Dataset) Resource {
+	return newResource(resourceTypeDataset, d.ID, map[string]string{
+		"title":       d.Title,
+		"description": d.Description,
+		"attribution": d.Attribution,
+	})
+}
+
+func newResource(typ, id string, attrs map[string]string) Resource {
+	r := Resource{
+		Type:       typ,
+		ID:         id,
+		Attributes: attrs,
+	}
+	r.Checksum = r.checksum()
+	return r
+}
+
+// checksum is the hex encoded sha256 of the canonical json encoding of the resource.
+// encoding/json sorts map keys, which keeps the encoding stable.
+func (r Resource) checksum() string {
+	b, _ := json.Marshal(struct {
+		Type       string            `json:"type"`
+		ID         string            `json:"id"`
+		Attributes map[string]string `json:"attributes"`
+	}{r.Type, r.ID, r.Attributes})
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+var nonIdentifierRe = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// TerraformJSON returns the resource in Terraform JSON configuration syntax.
+// The checksum is added as a "//" comment property, which Terraform ignores.
+func (r Resource) TerraformJSON() ([]byte, error) {
+	name := nonIdentifierRe.ReplaceAllString(r.ID, "_")
+	if name == "" || strings.ContainsAny(name[:1], "0123456789-") {
+		name = "r_" + name
+	}
+
+	body := make(map[string]string, len(r.Attributes)+2)
+	for k, v := range r.Attributes {
+		body[k] = v
+	}
+	body["//"] = r.Checksum
+
+	tf := map[string]map[string]map[string]map[string]string{
+		"resource": {
+			r.Type: {
+				name: body,
+			},
+		},
+	}
+	return json.MarshalIndent(tf, "", "  ")
+}
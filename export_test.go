@@ -0,0 +1,48 @@
+package maptiler
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDatasetResourceChecksum(t *testing.T) {
+	t.Parallel()
+
+	d := Dataset{ID: "ds-1", Title: "roads", Description: "europe roads"}
+
+	a := NewDatasetResource(d)
+	b := NewDatasetResource(d)
+	if a.Checksum != b.Checksum {
+		t.Fatalf("checksum not stable: %q != %q", a.Checksum, b.Checksum)
+	}
+
+	d.Title = "roads v2"
+	if c := NewDatasetResource(d); c.Checksum == a.Checksum {
+		t.Fatalf("checksum did not change after attribute change")
+	}
+}
+
+func TestResourceTerraformJSON(t *testing.T) {
+	t.Parallel()
+
+	r := NewDatasetResource(Dataset{ID: "0a1b-c2", Title: "roads"})
+	b, err := r.TerraformJSON()
+	if err != nil {
+		t.Fatalf("TerraformJSON() unexpected error: %v", err)
+	}
+
+	var got map[string]map[string]map[string]map[string]string
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	body, ok := got["resource"]["maptiler_dataset"]["r_0a1b-c2"]
+	if !ok {
+		t.Fatalf("missing resource block in %s", b)
+	}
+	if body["title"] != "roads" {
+		t.Fatalf("title=%q want roads", body["title"])
+	}
+	if body["//"] != r.Checksum {
+		t.Fatalf("checksum comment=%q want %q", body["//"], r.Checksum)
+	}
+}
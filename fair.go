@@ -0,0 +1,100 @@
+package maptiler
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// fairSemaphore limits the parts in flight across all ingests of a Client. A
+// freed slot goes to the ingests waiting for one in turn, so an ingest with
+// many parts does not starve the others.
+type fairSemaphore struct {
+	mu   sync.Mutex
+	free int
+	// queues are the waiting parts of every ingest, ring the ingests with
+	// waiting parts in the order they are served.
+	queues map[string][]chan struct{}
+	ring   []string
+}
+
+// newFairSemaphore returns a semaphore of n slots, or nil if n is not positive.
+func newFairSemaphore(n int) *fairSemaphore {
+	if n <= 0 {
+		return nil
+	}
+	return &fairSemaphore{free: n, queues: make(map[string][]chan struct{})}
+}
+
+// acquire blocks until a part of ingest id may be sent or ctx is done. A nil
+// semaphore never blocks.
+func (s *fairSemaphore) acquire(ctx context.Context, id string) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	if s.free > 0 && len(s.ring) == 0 {
+		s.free--
+		s.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	if len(s.queues[id]) == 0 {
+		s.ring = append(s.ring, id)
+	}
+	s.queues[id] = append(s.queues[id], ch)
+	s.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.queues[id]
+	i := slices.Index(q, ch)
+	if i < 0 {
+		// the slot was granted in the meantime, pass it on.
+		s.releaseLocked()
+		return context.Cause(ctx)
+	}
+	q = slices.Delete(q, i, i+1)
+	if len(q) > 0 {
+		s.queues[id] = q
+	} else {
+		delete(s.queues, id)
+		s.ring = slices.DeleteFunc(s.ring, func(r string) bool { return r == id })
+	}
+	return context.Cause(ctx)
+}
+
+// release frees the slot of a part.
+func (s *fairSemaphore) release() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked()
+}
+
+// releaseLocked hands the slot to the next ingest of the ring, which moves to
+// its end if more of its parts are waiting.
+func (s *fairSemaphore) releaseLocked() {
+	if len(s.ring) == 0 {
+		s.free++
+		return
+	}
+	id := s.ring[0]
+	s.ring = s.ring[1:]
+	q := s.queues[id]
+	close(q[0])
+	if len(q) > 1 {
+		s.queues[id] = q[1:]
+		s.ring = append(s.ring, id)
+	} else {
+		delete(s.queues, id)
+	}
+}
@@ -0,0 +1,121 @@
+package maptiler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+// waiting returns the number of parts waiting for s.
+func (s *fairSemaphore) waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int
+	for _, q := range s.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// enqueue starts acquire for id and waits until it is queued. The id is sent to
+// served once the slot is granted.
+func enqueue(t *testing.T, s *fairSemaphore, id string, served chan<- string) {
+	t.Helper()
+	n := s.waiting()
+	go func() {
+		if err := s.acquire(t.Context(), id); err == nil {
+			served <- id
+		}
+	}()
+	for s.waiting() == n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFairSemaphoreServesIngestsInTurn(t *testing.T) {
+	t.Parallel()
+
+	s := newFairSemaphore(1)
+	if err := s.acquire(t.Context(), "a"); err != nil {
+		t.Fatalf("acquire() unexpected error: %v", err)
+	}
+
+	served := make(chan string, 4)
+	for _, id := range []string{"a", "a", "a", "b"} {
+		enqueue(t, s, id, served)
+	}
+
+	var got []string
+	for range 4 {
+		s.release()
+		got = append(got, <-served)
+	}
+	want := []string{"a", "b", "a", "a"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("served %v, want %v", got, want)
+		}
+	}
+	s.release()
+	if s.free != 1 {
+		t.Fatalf("expected the slot to be free, got %d", s.free)
+	}
+}
+
+func TestFairSemaphoreCanceled(t *testing.T) {
+	t.Parallel()
+
+	s := newFairSemaphore(1)
+	if err := s.acquire(t.Context(), "a"); err != nil {
+		t.Fatalf("acquire() unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.acquire(ctx, "b"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire() error = %v, want context.DeadlineExceeded", err)
+	}
+	if n := s.waiting(); n != 0 || len(s.ring) != 0 {
+		t.Fatalf("expected the canceled part to be removed, %d waiting in %v", n, s.ring)
+	}
+
+	s.release()
+	if err := s.acquire(t.Context(), "c"); err != nil {
+		t.Fatalf("acquire() unexpected error: %v", err)
+	}
+}
+
+func TestSharedConcurrency(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token", WithSharedConcurrency(1), WithSharedBandwidthLimit(1<<20))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	var g errgroup.Group
+	for _, name := range []string{"a.pmtiles", "b.pmtiles", "c.pmtiles"} {
+		fp := filepath.Join(dir, name)
+		if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		g.Go(func() error {
+			_, err := c.Create(t.Context(), fp)
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package maptiler
+
+import (
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeFilename returns name as it is sent to the service, which shows the
+// filename as the name of the dataset: leading directories, separated by / or
+// \, are stripped, surrounding whitespace is trimmed and the name is converted
+// to Unicode NFC. File systems like the one of macOS store names decomposed, so
+// the same name would otherwise differ in bytes depending on where it came from.
+func NormalizeFilename(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	return norm.NFC.String(strings.TrimSpace(name))
+}
+
+// filename returns the filename of the ingest of a file named name. The name of
+// the file is kept if renaming it leaves nothing.
+func (cfg ingestConfig) filename(name string) string {
+	renamed := name
+	if cfg.filenameOverride != "" {
+		renamed = withExtension(cfg.filenameOverride, filepath.Ext(name))
+	}
+	if cfg.rename != nil {
+		renamed = cfg.rename(renamed)
+	}
+	if n := NormalizeFilename(renamed); n != "" {
+		return n
+	}
+	return NormalizeFilename(name)
+}
+
+// withExtension appends ext to name unless name ends with it already, compared
+// case insensitive, e.g. europe-roads becomes europe-roads.pmtiles.
+func withExtension(name, ext string) string {
+	if ext == "" || strings.HasSuffix(strings.ToLower(strings.TrimSpace(name)), strings.ToLower(ext)) {
+		return name
+	}
+	return strings.TrimSpace(name) + ext
+}
@@ -0,0 +1,102 @@
+package maptiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestNormalizeFilename(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		"tiles.mbtiles":                "tiles.mbtiles",
+		"Sta\u0308dte.mbtiles":         "St\u00e4dte.mbtiles",
+		"St\u00e4dte.mbtiles":          "St\u00e4dte.mbtiles",
+		"exports/tiles.pmtiles":        "tiles.pmtiles",
+		`C:\exports\tiles.pmtiles`:     "tiles.pmtiles",
+		`\\server\share\tiles.mbtiles`: "tiles.mbtiles",
+		"  tiles.mbtiles \n":           "tiles.mbtiles",
+		"exports/":                     "",
+	} {
+		if got := NormalizeFilename(in); got != want {
+			t.Errorf("NormalizeFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestIngestFilename(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []IngestOption
+		want string
+	}{
+		{name: "file name", want: "St\u00e4dte.mbtiles"},
+		{name: "override", opts: []IngestOption{WithFilename("regions/Gemeinden.mbtiles")}, want: "Gemeinden.mbtiles"},
+		{name: "override without extension", opts: []IngestOption{WithFilename("europe-roads")}, want: "europe-roads.mbtiles"},
+		{name: "override with other extension", opts: []IngestOption{WithFilename("europe-roads.v2")}, want: "europe-roads.v2.mbtiles"},
+		{name: "override with upper case extension", opts: []IngestOption{WithFilename("Roads.MBTiles ")}, want: "Roads.MBTiles"},
+		{
+			name: "rename",
+			opts: []IngestOption{WithFilenameFunc(func(name string) string { return "prod-" + name })},
+			want: "prod-St\u00e4dte.mbtiles",
+		},
+		{
+			name: "rename override",
+			opts: []IngestOption{
+				WithFilename("Gemeinden.mbtiles"),
+				WithFilenameFunc(strings.ToUpper),
+			},
+			want: "GEMEINDEN.MBTILES",
+		},
+		{name: "empty rename keeps name", opts: []IngestOption{WithFilenameFunc(func(string) string { return " " })}, want: "St\u00e4dte.mbtiles"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := newIngestConfig(tt.opts...).filename("Sta\u0308dte.mbtiles"); got != tt.want {
+				t.Errorf("filename() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientSendsNormalizedFilename(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer()
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	fp := filepath.Join(t.TempDir(), "Sta\u0308dte.pmtiles")
+	if err := os.WriteFile(fp, []byte("tiles"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ir, err := c.Create(t.Context(), fp)
+	if err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+	if ir.Filename != "St\u00e4dte.pmtiles" {
+		t.Errorf("expected NFC filename, got %q", ir.Filename)
+	}
+
+	ur, err := c.Update(t.Context(), ir.DocumentID, fp, WithFilename("Gemeinden.pmtiles"))
+	if err != nil {
+		t.Fatalf("Update() unexpected error: %v", err)
+	}
+	if ur.Filename != "Gemeinden.pmtiles" {
+		t.Errorf("expected filename of WithFilename, got %q", ur.Filename)
+	}
+}
@@ -0,0 +1,87 @@
+package maptiler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FileStat describes a file that is about to be ingested.
+type FileStat struct {
+	Path string `json:"path"`
+	// Target is the resolved path if Path is a symlink.
+	Target  string `json:"target,omitempty"`
+	Symlink bool   `json:"symlink"`
+	// Size is the logical size of the file, which is what gets uploaded.
+	Size int64 `json:"size"`
+	// AllocatedSize is the space the file occupies on disk. It equals Size on
+	// platforms that do not report allocated blocks.
+	AllocatedSize int64 `json:"allocated_size"`
+}
+
+func (s FileStat) String() string { return toJSONString(s) }
+
+// Sparse reports whether the file occupies less space on disk than its logical size.
+// The holes of a sparse file are uploaded as zeros. Note that filesystems with
+// transparent compression report sparse files as well.
+func (s FileStat) Sparse() bool {
+	return s.AllocatedSize < s.Size
+}
+
+// StatFile inspects the file at fp without following a symlink blindly.
+func StatFile(fp string) (FileStat, error) {
+	fp = osPath(fp)
+	linfo, err := os.Lstat(fp)
+	if os.IsNotExist(err) {
+		return FileStat{}, fmt.Errorf("expected file %q to exist, but it does not: %w", fp, ErrInvalidFile)
+	}
+	if err != nil {
+		return FileStat{}, fmt.Errorf("reading file %q: %w", fp, err)
+	}
+
+	st := FileStat{Path: fp}
+	if linfo.Mode()&os.ModeSymlink != 0 {
+		st.Symlink = true
+		st.Target, err = filepath.EvalSymlinks(fp)
+		if err != nil {
+			return FileStat{}, fmt.Errorf("resolving symlink %q: %w", fp, err)
+		}
+	}
+
+	info, err := fileInfo(fp)
+	if err != nil {
+		return FileStat{}, err
+	}
+	st.Size = info.Size()
+	st.AllocatedSize = allocatedSize(info)
+
+	return st, nil
+}
+
+// checkFile validates fp against the file policies and guardrails of an ingest.
+func checkFile(fp string, cfg ingestConfig) (os.FileInfo, error) {
+	if cfg.rejectSymlinks || cfg.rejectSparse {
+		st, err := StatFile(fp)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.rejectSymlinks && st.Symlink {
+			return nil, fmt.Errorf("expected file %q to be a regular file, but it is a symlink to %q: %w", fp, st.Target, ErrInvalidFile)
+		}
+		if cfg.rejectSparse && st.Sparse() {
+			return nil, fmt.Errorf(
+				"expected file %q to be dense, but it is sparse (size %d, allocated %d): %w",
+				fp, st.Size, st.AllocatedSize, ErrInvalidFile,
+			)
+		}
+	}
+
+	info, err := fileInfo(fp)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGuardrails(fp, info.Size(), cfg); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
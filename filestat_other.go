@@ -0,0 +1,10 @@
+//go:build !unix
+
+package maptiler
+
+import "os"
+
+// allocatedSize returns the logical size, allocated blocks are not reported on this platform.
+func allocatedSize(info os.FileInfo) int64 {
+	return info.Size()
+}
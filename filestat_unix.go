@@ -0,0 +1,16 @@
+//go:build unix
+
+package maptiler
+
+import (
+	"os"
+	"syscall"
+)
+
+// allocatedSize returns the number of bytes allocated on disk for a file.
+func allocatedSize(info os.FileInfo) int64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int64(st.Blocks) * 512 //nolint:unconvert
+	}
+	return info.Size()
+}
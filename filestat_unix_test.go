@@ -0,0 +1,67 @@
+//go:build unix
+
+package maptiler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatFileSymlink(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "tiles.pmtiles")
+	if err := os.WriteFile(target, []byte("abc"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.pmtiles")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := StatFile(link)
+	if err != nil {
+		t.Fatalf("StatFile() failed: %v", err)
+	}
+	if !st.Symlink || st.Target != target || st.Size != 3 {
+		t.Fatalf("unexpected stat: %+v", st)
+	}
+
+	if _, err := checkFile(link, ingestConfig{}); err != nil {
+		t.Fatalf("expected symlink to be followed by default, got %v", err)
+	}
+	if _, err := checkFile(link, ingestConfig{rejectSymlinks: true}); !errors.Is(err, ErrInvalidFile) {
+		t.Fatalf("expected ErrInvalidFile, got %v", err)
+	}
+}
+
+func TestStatFileSparse(t *testing.T) {
+	t.Parallel()
+
+	fp := filepath.Join(t.TempDir(), "sparse.pmtiles")
+	f, err := os.Create(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(64 << 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := StatFile(fp)
+	if err != nil {
+		t.Fatalf("StatFile() failed: %v", err)
+	}
+	if !st.Sparse() {
+		t.Skipf("filesystem does not support sparse files: %+v", st)
+	}
+
+	if _, err := checkFile(fp, ingestConfig{rejectSparse: true}); !errors.Is(err, ErrInvalidFile) {
+		t.Fatalf("expected ErrInvalidFile, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package maptiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// getResult is the outcome of fetching a single ingest of GetMany.
+type getResult struct {
+	ID   string
+	Resp IngestGetResponse
+	Err  error
+}
+
+// getProcessor fetches ingests by ID. Failing ingests are reported in their
+// result, so they do not stop the pool.
+type getProcessor struct {
+	c *Client
+}
+
+func (p getProcessor) Process(ctx context.Context, t task[string]) (getResult, error) {
+	if err := ctx.Err(); err != nil {
+		return getResult{}, err
+	}
+	ir, err := p.c.Get(ctx, t.Body)
+	return getResult{ID: t.Body, Resp: ir, Err: err}, nil
+}
+
+func (getProcessor) Close() {}
+
+// GetMany fetches the ingests with the given IDs concurrently. It returns the
+// ingests that could be fetched by ID, along with the errors of the others.
+func (c *Client) GetMany(ctx context.Context, ids []string) (map[string]IngestGetResponse, error) {
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	out := make(map[string]IngestGetResponse, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+
+	// the queue holds all ids, so enqueueing never blocks on a failed pool.
+	wp := newPool(
+		processor[string, getResult](getProcessor{c: c}),
+		withPoolConcurrency(min(c.concurrency, len(ids))),
+		withPoolQueueSize(len(ids)),
+	)
+
+	var errs []error
+	eg, gctx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		if err := wp.Start(gctx); err != nil {
+			return fmt.Errorf("getting ingests: %w", err)
+		}
+		return nil
+	})
+	eg.Go(func() error {
+		defer wp.Stop()
+		for _, id := range ids {
+			if err := wp.Enqueue(newTask(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	eg.Go(func() error {
+		for r := range wp.Results() {
+			if r.Body.Err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", r.Body.ID, r.Body.Err))
+				continue
+			}
+			out[r.Body.ID] = r.Body.Resp
+		}
+		return nil
+	})
+
+	if err := eg.Wait(); err != nil {
+		return out, err
+	}
+	return out, errors.Join(errs...)
+}
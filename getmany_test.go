@@ -0,0 +1,46 @@
+package maptiler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientGetMany(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/datasets/ingest/")
+		if id == "missing" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"` + id + `","state":"processing"}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	got, err := c.GetMany(t.Context(), []string{"ing-1", "ing-2", "missing", "ing-1"})
+
+	var aerr APIError
+	if !errors.As(err, &aerr) || aerr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected APIError 404 for the missing ingest, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("expected the error to name the ingest, got %v", err)
+	}
+	if len(got) != 2 || got["ing-1"].ID != "ing-1" || got["ing-2"].ID != "ing-2" {
+		t.Fatalf("unexpected ingests %+v", got)
+	}
+
+	got, err = c.GetMany(t.Context(), nil)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("GetMany(nil) = %v, %v", got, err)
+	}
+}
@@ -7,4 +7,5 @@ require (
 	github.com/segmentio/ksuid v1.0.4
 	github.com/urfave/cli/v3 v3.6.1
 	golang.org/x/sync v0.19.0
+	golang.org/x/text v0.33.0
 )
@@ -0,0 +1,73 @@
+package maptiler
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedExtension is returned when the file extension is not in the allowed list.
+	ErrUnsupportedExtension = fmt.Errorf("%w: unsupported extension", ErrInvalidFile)
+	// ErrFileTooLarge is returned when the file exceeds the configured maximum size.
+	ErrFileTooLarge = fmt.Errorf("%w: file too large", ErrInvalidFile)
+)
+
+// supportedExtensions are the file extensions of the formats the MapTiler service ingests.
+var supportedExtensions = []string{
+	".csv",
+	".geojson",
+	".gpkg",
+	".gpx",
+	".json",
+	".kml",
+	".kmz",
+	".mbtiles",
+	".pmtiles",
+	".tif",
+	".tiff",
+	".zip",
+}
+
+// SupportedExtensions returns the file extensions of the formats the MapTiler service ingests.
+func SupportedExtensions() []string {
+	return slices.Clone(supportedExtensions)
+}
+
+// checkGuardrails validates the file fp of size bytes against the configured
+// size and extension limits.
+func checkGuardrails(fp string, size int64, cfg ingestConfig) error {
+	if cfg.maxSize > 0 && size > cfg.maxSize {
+		return fmt.Errorf(
+			"file %q is %s, which exceeds the maximum of %s: %w",
+			fp, formatBytes(size), formatBytes(cfg.maxSize), ErrFileTooLarge,
+		)
+	}
+
+	if len(cfg.extensions) > 0 {
+		ext := strings.ToLower(filepath.Ext(fp))
+		if !slices.Contains(cfg.extensions, ext) {
+			return fmt.Errorf(
+				"file %q has extension %q, expected one of %s: %w",
+				fp, ext, strings.Join(cfg.extensions, ", "), ErrUnsupportedExtension,
+			)
+		}
+	}
+
+	return nil
+}
+
+// formatBytes formats n in binary units, e.g. 1.5 GiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
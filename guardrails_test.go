@@ -0,0 +1,92 @@
+package maptiler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckGuardrails(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name string, size int) string {
+		fp := filepath.Join(dir, name)
+		if err := os.WriteFile(fp, make([]byte, size), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return fp
+	}
+
+	tests := []struct {
+		name    string
+		fp      string
+		opts    []IngestOption
+		wantErr error
+	}{
+		{
+			name: "no guardrails",
+			fp:   write("notes.txt", 10),
+		},
+		{
+			name: "supported extension, case insensitive",
+			fp:   write("TILES.PMTILES", 10),
+			opts: []IngestOption{WithAllowedExtensions(SupportedExtensions()...)},
+		},
+		{
+			name: "allowed extension given in upper case",
+			fp:   write("tiles.mbtiles", 10),
+			opts: []IngestOption{WithAllowedExtensions(".MBTILES")},
+		},
+		{
+			name:    "unsupported extension",
+			fp:      write("notes.txt", 10),
+			opts:    []IngestOption{WithAllowedExtensions(SupportedExtensions()...)},
+			wantErr: ErrUnsupportedExtension,
+		},
+		{
+			name:    "too large",
+			fp:      write("big.mbtiles", 2048),
+			opts:    []IngestOption{WithMaxSize(1024)},
+			wantErr: ErrFileTooLarge,
+		},
+		{
+			name: "at max size",
+			fp:   write("exact.mbtiles", 1024),
+			opts: []IngestOption{WithMaxSize(1024)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := checkFile(tt.fp, newIngestConfig(tt.opts...))
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) || !errors.Is(err, ErrInvalidFile) {
+				t.Fatalf("expected %v wrapping ErrInvalidFile, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	t.Parallel()
+
+	for n, want := range map[int64]string{
+		512:           "512 B",
+		1536:          "1.5 KiB",
+		10 << 30:      "10.0 GiB",
+		5<<40 + 1<<39: "5.5 TiB",
+	} {
+		if got := formatBytes(n); got != want {
+			t.Fatalf("formatBytes(%d)=%q want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,463 @@
+package maptiler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrNotResumable is returned by Resume if the ingest of a journal is no longer
+// waiting for its upload, e.g. because it was canceled.
+var ErrNotResumable = errors.New("ingest can not be resumed")
+
+// ErrJournalVersion is returned by Resume if the journal was written by a newer
+// version of this package, which has to resume it.
+var ErrJournalVersion = errors.New("unsupported journal version")
+
+// ErrInvalidJournal is returned by Resume if the journal is malformed.
+var ErrInvalidJournal = errors.New("invalid journal")
+
+// ErrFinalizeSent is returned by Resume if the journal records that the ingest
+// was finalized before, but it still waits for its upload. Sending the
+// finalize again may process the ingest twice, see WithForceFinalize.
+var ErrFinalizeSent = errors.New("finalize already sent")
+
+// journalVersion is the version of the journals written by this package.
+// Journals of older versions are migrated when read, see journalMigrations.
+const journalVersion = 4
+
+// journalMigrations upgrade the header of a journal of version i+1 to the next
+// version. A new version gets a migration here, so that uploads started by an
+// older client can be resumed by a newer one.
+var journalMigrations = []func(h *journalHeader){
+	// version 1 predates the upload type, all its uploads were S3 multipart.
+	func(h *journalHeader) { h.UploadType = ingestUploadTypeS3MultiPart },
+	// version 2 predates the finalize entries, its header is the same.
+	func(*journalHeader) {},
+	// version 3 predates the rejected finalize entries, its header is the same.
+	func(*journalHeader) {},
+}
+
+// journalEntry is a line of a journal after the header, an uploaded part, a
+// finalize that was sent or a finalize the service rejected without handling it.
+type journalEntry struct {
+	CompletedPart
+	Finalized        time.Time `json:"finalized,omitzero"`
+	FinalizeRejected time.Time `json:"finalize_rejected,omitzero"`
+}
+
+// journalHeader is the first line of a journal, the ingest and the file it
+// uploads. Every further line is a journalEntry.
+type journalHeader struct {
+	Version    int         `json:"version"`
+	IngestID   string      `json:"ingest_id"`
+	File       string      `json:"file"`
+	Size       int64       `json:"size"`
+	ModTime    time.Time   `json:"mod_time"`
+	PartSize   int64       `json:"part_size"`
+	Parts      uploadParts `json:"parts"`
+	UploadType string      `json:"upload_type"`
+	// Finalized is the time of the last finalize recorded in the entries of
+	// the journal, it is not part of the header.
+	Finalized time.Time `json:"-"`
+}
+
+// migrate upgrades h of an older version to journalVersion.
+func (h *journalHeader) migrate() error {
+	if h.Version < 1 {
+		return fmt.Errorf("%w: missing version", ErrInvalidJournal)
+	}
+	for ; h.Version < journalVersion; h.Version++ {
+		journalMigrations[h.Version-1](h)
+	}
+	return nil
+}
+
+// validate reports all fields of h that can not be resumed.
+func (h journalHeader) validate() error {
+	var errs []error
+	if h.IngestID == "" {
+		errs = append(errs, errors.New("missing ingest_id"))
+	}
+	if h.File == "" {
+		errs = append(errs, errors.New("missing file"))
+	}
+	if h.Size < 0 {
+		errs = append(errs, fmt.Errorf("negative size %d", h.Size))
+	}
+	if h.PartSize <= 0 {
+		errs = append(errs, fmt.Errorf("part_size must be positive, got %d", h.PartSize))
+	}
+	if len(h.Parts) == 0 {
+		errs = append(errs, errors.New("missing parts"))
+	}
+	for _, p := range h.Parts {
+		if p.PartID <= 0 || p.URL == "" {
+			errs = append(errs, fmt.Errorf("part %d: missing id or url", p.PartID))
+		}
+	}
+	if h.UploadType != ingestUploadTypeS3MultiPart {
+		errs = append(errs, fmt.Errorf("unsupported upload_type %q", h.UploadType))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidJournal, err)
+	}
+	return nil
+}
+
+// journal records the parts of an upload as they finish, so that the upload
+// can be resumed after the process died, see WithJournal.
+type journal struct {
+	path string
+	f    *os.File
+	sc   *StateCipher
+	log  *slog.Logger
+}
+
+// createJournal writes the header of a new journal to path, sealed by sc. It
+// fails if path exists, which would be the journal of another upload.
+func createJournal(path string, h journalHeader, sc *StateCipher, log *slog.Logger) (*journal, error) {
+	h.Version = journalVersion
+	line, err := json.Marshal(h)
+	if err != nil {
+		return nil, fmt.Errorf("encoding journal: %w", err)
+	}
+	// the journal holds the presigned upload URLs, so it is only readable by the user.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("creating journal: %w", err)
+	}
+	j := &journal{path: path, f: f, sc: sc, log: log}
+	if err := j.write(line); err != nil {
+		f.Close()       //nolint:errcheck,gosec
+		os.Remove(path) //nolint:errcheck,gosec
+		return nil, fmt.Errorf("creating journal: %w", err)
+	}
+	return j, nil
+}
+
+// readJournal reads the journal at path and returns its header, migrated to
+// journalVersion, and the ETags of the parts recorded as uploaded. A partial
+// last line, written when the process died, is ignored. Lines sealed by a
+// StateCipher are opened with sc. The journal itself is not rewritten, the
+// parts appended by a newer client read the same.
+func readJournal(path string, sc *StateCipher) (journalHeader, map[int64]string, error) {
+	var h journalHeader
+	f, err := os.Open(path) //nolint:gosec
+	if err != nil {
+		return h, nil, fmt.Errorf("reading journal: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	// the header holds the URLs of all parts and can be larger than the buffer of
+	// a bufio.Scanner.
+	r := bufio.NewReader(f)
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return h, nil, fmt.Errorf("reading journal %s: missing header: %w", path, err)
+	}
+	if line, err = sc.Open(line); err != nil {
+		return h, nil, fmt.Errorf("reading journal %s: %w", path, err)
+	}
+	// the version is checked first, a newer header may not decode as this one.
+	var v struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(line, &v); err != nil {
+		return h, nil, fmt.Errorf("reading journal %s: %w: %w", path, ErrInvalidJournal, err)
+	}
+	if v.Version > journalVersion {
+		return h, nil, fmt.Errorf("reading journal %s: %w %d, this client supports up to version %d", path, ErrJournalVersion, v.Version, journalVersion)
+	}
+	if err := json.Unmarshal(line, &h); err != nil {
+		return h, nil, fmt.Errorf("reading journal %s: %w: %w", path, ErrInvalidJournal, err)
+	}
+	if err := h.migrate(); err != nil {
+		return h, nil, fmt.Errorf("reading journal %s: %w", path, err)
+	}
+	if err := h.validate(); err != nil {
+		return h, nil, fmt.Errorf("reading journal %s: %w", path, err)
+	}
+
+	done := make(map[int64]string)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return h, done, nil
+		}
+		if err != nil {
+			return h, nil, fmt.Errorf("reading journal %s: %w", path, err)
+		}
+		if line, err = sc.Open(line); err != nil {
+			return h, nil, fmt.Errorf("reading journal %s: %w", path, err)
+		}
+		var e journalEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return h, nil, fmt.Errorf("reading journal %s: %w: %w", path, ErrInvalidJournal, err)
+		}
+		if !e.Finalized.IsZero() {
+			h.Finalized = e.Finalized
+			continue
+		}
+		if !e.FinalizeRejected.IsZero() {
+			h.Finalized = time.Time{}
+			continue
+		}
+		if e.PartID <= 0 || e.ETag == "" {
+			return h, nil, fmt.Errorf("reading journal %s: %w: part %d: missing id or etag", path, ErrInvalidJournal, e.PartID)
+		}
+		done[e.PartID] = e.ETag
+	}
+}
+
+// appendJournal opens the journal at path to record further parts, sealed by sc.
+func appendJournal(path string, sc *StateCipher, log *slog.Logger) (*journal, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("opening journal: %w", err)
+	}
+	return &journal{path: path, f: f, sc: sc, log: log}, nil
+}
+
+// write appends line and syncs it to disk, as the journal has to survive the
+// process.
+func (j *journal) write(line []byte) error {
+	if _, err := j.f.Write(append(j.sc.Seal(line), '\n')); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+// add records an uploaded part. A part that can not be recorded is uploaded
+// again on resume, so failures stop the journal with a warning instead of
+// failing the upload.
+func (j *journal) add(p uploadTaskResponse) {
+	j.append(journalEntry{CompletedPart: CompletedPart{PartID: p.PartID, ETag: p.ETag}})
+}
+
+// finalizing records that the finalize of the ingest is about to be sent, so
+// that Resume does not send it again, see ErrFinalizeSent.
+func (j *journal) finalizing() {
+	j.append(journalEntry{Finalized: time.Now().UTC()})
+}
+
+// finalizeRejected records that the service rejected the finalize without
+// handling it, e.g. with 429, so that Resume may send it again.
+func (j *journal) finalizeRejected() {
+	j.append(journalEntry{FinalizeRejected: time.Now().UTC()})
+}
+
+// append writes e to the journal, failures stop the journal with a warning.
+func (j *journal) append(e journalEntry) {
+	if j == nil || j.f == nil {
+		return
+	}
+	line, err := json.Marshal(e)
+	if err == nil {
+		err = j.write(line)
+	}
+	if err != nil {
+		logger(j.log).Warn("journal stopped, resume will upload the remaining parts again", "journal", j.path, "error", err)
+		j.close()
+	}
+}
+
+// close closes the journal and keeps it for a resume.
+func (j *journal) close() {
+	if j == nil || j.f == nil {
+		return
+	}
+	if err := j.f.Close(); err != nil {
+		logger(j.log).Warn("closing journal failed", "journal", j.path, "error", err)
+	}
+	j.f = nil
+}
+
+// remove closes and removes the journal of a finished upload.
+func (j *journal) remove() {
+	if j == nil {
+		return
+	}
+	j.close()
+	if err := os.Remove(j.path); err != nil {
+		logger(j.log).Warn("removing journal failed", "journal", j.path, "error", err)
+	}
+}
+
+// startJournal creates the journal of cfg for the upload of resp from the file
+// at fp, or returns nil if cfg has none.
+func (c *Client) startJournal(cfg ingestConfig, resp IngestResponse, fp string, info os.FileInfo) (*journal, error) {
+	if cfg.journal == "" {
+		return nil, nil //nolint:nilnil
+	}
+	abs, err := filepath.Abs(fp)
+	if err != nil {
+		return nil, fmt.Errorf("creating journal: %w", err)
+	}
+	return createJournal(cfg.journal, journalHeader{
+		IngestID:   resp.ID,
+		File:       abs,
+		Size:       info.Size(),
+		ModTime:    info.ModTime(),
+		PartSize:   resp.Upload.PartSize,
+		Parts:      resp.Upload.Parts,
+		UploadType: resp.Upload.Type,
+	}, c.state, c.log)
+}
+
+// Resume continues the upload recorded in the journal at path, see WithJournal,
+// and finalizes the ingest. Parts the journal records as uploaded are skipped.
+// The file must not have changed since the upload started, and the upload URLs
+// of the ingest must still be valid. The upload options of opts apply, e.g.
+// WithProgress and WithPartRetries. The journal is removed once the ingest is
+// finalized, and kept if the upload fails again.
+//
+// An ingest that is already processing or completed was finalized by the run
+// that wrote the journal, which died before it read the response. It is
+// returned as Reconciled without uploading anything, and the journal removed.
+// An ingest still waiting for its upload although the journal records its
+// finalize fails with ErrFinalizeSent, unless WithForceFinalize is given. A
+// finalize the service rejected without handling it, e.g. with 429, is sent
+// again.
+func (c *Client) Resume(ctx context.Context, path string, opts ...IngestOption) (IngestResponse, error) {
+	cfg := newIngestConfig(opts...)
+	h, done, err := readJournal(path, c.state)
+	if err != nil {
+		return IngestResponse{}, err
+	}
+
+	ir, err := c.Get(ctx, h.IngestID)
+	if err != nil {
+		return IngestResponse{}, fmt.Errorf("resuming %s: %w", h.IngestID, err)
+	}
+	switch ir.State {
+	case StateUpload:
+		if !h.Finalized.IsZero() && !cfg.forceFinalize {
+			return IngestResponse{}, fmt.Errorf("resuming %s: %w at %s", h.IngestID, ErrFinalizeSent, h.Finalized.Format(time.RFC3339))
+		}
+	case StateProcessing, StateCompleted:
+		logger(c.log).Info("ingest was finalized by an earlier run", "ingest_id", ir.ID, "journal", path, "state", ir.State)
+		(&journal{path: path, log: c.log}).remove()
+		return IngestResponse{
+			ID:         ir.ID,
+			DocumentID: ir.DocumentID,
+			State:      ir.State,
+			Filename:   ir.Filename,
+			Size:       h.Size,
+			Progress:   ir.Progress,
+			Errors:     ir.Errors,
+			Warnings:   ir.Warnings,
+			Tileset:    c.tileset(ir.State, ir.DocumentID),
+			Reconciled: true,
+		}, nil
+	default:
+		return IngestResponse{}, fmt.Errorf("resuming %s: %w: ingest is %s", h.IngestID, ErrNotResumable, ir.State)
+	}
+
+	info, err := os.Stat(osPath(h.File))
+	if err != nil {
+		return IngestResponse{}, fmt.Errorf("resuming %s: %w: %w", h.IngestID, ErrInvalidFile, err)
+	}
+	if info.Size() != h.Size || !info.ModTime().Equal(h.ModTime) {
+		return IngestResponse{}, fmt.Errorf("resuming %s: %w: %s changed since the upload started", h.IngestID, ErrInvalidFile, h.File)
+	}
+
+	j, err := appendJournal(path, c.state, c.log)
+	if err != nil {
+		return IngestResponse{}, err
+	}
+	resp := IngestResponse{
+		ID:         ir.ID,
+		DocumentID: ir.DocumentID,
+		State:      ir.State,
+		Filename:   ir.Filename,
+		Size:       h.Size,
+		Upload:     upload{PartSize: h.PartSize, Parts: h.Parts, Type: h.UploadType},
+	}
+	logger(c.log).Debug("resuming upload", "ingest_id", resp.ID, "journal", path, "parts", len(h.Parts), "done", len(done))
+	return c.finish(ctx, resp, osPath(h.File), cfg, j, done)
+}
+
+// pendingSize returns the number and size of the parts of resp that are not in done.
+func pendingSize(resp IngestResponse, done map[int64]string) (int, int64) {
+	var n int
+	var size int64
+	for i, p := range resp.Upload.Parts {
+		if _, ok := done[p.PartID]; ok {
+			continue
+		}
+		_, length := getRange(int64(i), resp.Upload.PartSize, resp.Size)
+		n++
+		size += max(length, 0)
+	}
+	return n, size
+}
+
+// PrunedJournal is the outcome of PruneJournals for a journal.
+type PrunedJournal struct {
+	Path     string `json:"path"`
+	IngestID string `json:"ingest_id,omitempty"`
+	// State is the state of the ingest, empty if it no longer exists.
+	State   IngestState `json:"state,omitempty"`
+	Removed bool        `json:"removed"`
+	Error   string      `json:"error,omitempty"`
+}
+
+func (p PrunedJournal) String() string { return toJSONString(p) }
+
+// PruneJournals removes the journals at paths that can no longer be resumed,
+// because their ingest left the upload state, e.g. it completed or was
+// canceled, or no longer exists. Journals of ingests waiting for their upload
+// are kept. The result reports every journal in the order of paths, journals
+// that can not be read or checked are kept and their errors joined into the
+// returned error.
+func (c *Client) PruneJournals(ctx context.Context, paths ...string) ([]PrunedJournal, error) {
+	res := make([]PrunedJournal, 0, len(paths))
+	var errs []error
+	for _, path := range paths {
+		p, err := c.pruneJournal(ctx, path)
+		if err != nil {
+			p.Error = err.Error()
+			errs = append(errs, err)
+		}
+		res = append(res, p)
+	}
+	return res, errors.Join(errs...)
+}
+
+// pruneJournal removes the journal at path if its ingest can not be resumed.
+func (c *Client) pruneJournal(ctx context.Context, path string) (PrunedJournal, error) {
+	p := PrunedJournal{Path: path}
+	h, _, err := readJournal(path, c.state)
+	if err != nil {
+		return p, fmt.Errorf("pruning journal: %w", err)
+	}
+	p.IngestID = h.IngestID
+
+	ir, err := c.Get(ctx, h.IngestID)
+	var aerr APIError
+	switch {
+	case errors.As(err, &aerr) && aerr.StatusCode == http.StatusNotFound:
+	case err != nil:
+		return p, fmt.Errorf("pruning journal %s: %w", path, err)
+	case ir.State == StateUpload:
+		return p, nil
+	default:
+		p.State = ir.State
+	}
+
+	if err := os.Remove(path); err != nil {
+		return p, fmt.Errorf("pruning journal: %w", err)
+	}
+	p.Removed = true
+	logger(c.log).Debug("journal pruned", "journal", path, "ingest_id", h.IngestID, "state", p.State)
+	return p, nil
+}
@@ -0,0 +1,458 @@
+package maptiler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+// processorFunc adapts a function to a processor of parts.
+type processorFunc func(ctx context.Context, tsk task[uploadTask]) (uploadTaskResponse, error)
+
+func (f processorFunc) Process(ctx context.Context, tsk task[uploadTask]) (uploadTaskResponse, error) {
+	return f(ctx, tsk)
+}
+func (f processorFunc) Close() {}
+
+func TestResume(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	jp := filepath.Join(dir, "tiles.journal")
+
+	// the first client uploads the first part and loses the others.
+	c, err := New(srv.URL, "token", WithConcurrency(1))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	up := c.up
+	var calls atomic.Int32
+	c.up = processorFunc(func(ctx context.Context, tsk task[uploadTask]) (uploadTaskResponse, error) {
+		if calls.Add(1) > 1 {
+			return uploadTaskResponse{}, ErrInvalidFile
+		}
+		return up.Process(ctx, tsk)
+	})
+
+	_, err = c.Create(t.Context(), fp, WithJournal(jp))
+	var uerr UploadFailedError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("Create() error = %v, want UploadFailedError", err)
+	}
+	if uerr.Journal != jp {
+		t.Errorf("UploadFailedError.Journal = %q, want %q", uerr.Journal, jp)
+	}
+	if got := IngestState(srv.State(uerr.ID)); got != StateUpload {
+		t.Fatalf("ingest is %q, want it not to be canceled", got)
+	}
+
+	_, done, err := readJournal(jp, nil)
+	if err != nil {
+		t.Fatalf("readJournal() unexpected error: %v", err)
+	}
+	if len(done) != 1 {
+		t.Fatalf("journal records %d parts, want 1", len(done))
+	}
+
+	c, err = New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	var first Progress
+	ir, err := c.Resume(t.Context(), jp, WithProgress(func(p Progress) {
+		if first.IngestID == "" {
+			first = p
+		}
+	}))
+	if err != nil {
+		t.Fatalf("Resume() unexpected error: %v", err)
+	}
+	if ir.ID != uerr.ID || ir.State != StateCompleted {
+		t.Errorf("Resume() = %s %s, want %s completed", ir.ID, ir.State, uerr.ID)
+	}
+	if first.PartsTotal != 2 || first.BytesTotal != 6 {
+		t.Errorf("resumed %d parts of %d bytes, want 2 parts of 6 bytes", first.PartsTotal, first.BytesTotal)
+	}
+	if _, err := os.Stat(jp); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the journal to be removed, got %v", err)
+	}
+}
+
+func TestResumeRejectsChangedFile(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ir, err := c.Begin(t.Context(), "", fp)
+	if err != nil {
+		t.Fatalf("Begin() unexpected error: %v", err)
+	}
+	jp := filepath.Join(dir, "tiles.journal")
+	j, err := c.startJournal(ingestConfig{journal: jp}, ir, fp, info)
+	if err != nil {
+		t.Fatalf("startJournal() unexpected error: %v", err)
+	}
+	j.close()
+
+	if _, err := c.startJournal(ingestConfig{journal: jp}, ir, fp, info); !errors.Is(err, os.ErrExist) {
+		t.Errorf("startJournal() error = %v, want the existing journal to be kept", err)
+	}
+
+	later := info.ModTime().Add(time.Minute)
+	if err := os.Chtimes(fp, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Resume(t.Context(), jp); !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("Resume() error = %v, want ErrInvalidFile", err)
+	}
+
+	if err := os.Chtimes(fp, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Cancel(t.Context(), ir.ID); err != nil {
+		t.Fatalf("Cancel() unexpected error: %v", err)
+	}
+	if _, err := c.Resume(t.Context(), jp); !errors.Is(err, ErrNotResumable) {
+		t.Errorf("Resume() error = %v, want ErrNotResumable", err)
+	}
+}
+
+func TestReadJournalIgnoresPartialLine(t *testing.T) {
+	t.Parallel()
+
+	jp := filepath.Join(t.TempDir(), "tiles.journal")
+	data := `{"version":2,"ingest_id":"ingest-1","file":"/tiles.pmtiles","size":10,"part_size":4,"parts":[{"part_id":1,"url":"u1"}],"upload_type":"s3_multipart"}
+{"part_id":1,"etag":"a"}
+{"part_id":2,"et`
+	if err := os.WriteFile(jp, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	h, done, err := readJournal(jp, nil)
+	if err != nil {
+		t.Fatalf("readJournal() unexpected error: %v", err)
+	}
+	if h.IngestID != "ingest-1" || len(done) != 1 || done[1] != "a" {
+		t.Errorf("readJournal() = %+v, %v", h, done)
+	}
+}
+
+func TestReadJournalVersions(t *testing.T) {
+	t.Parallel()
+
+	const parts = `"ingest_id":"ingest-1","file":"/tiles.pmtiles","size":10,"part_size":4,"parts":[{"part_id":1,"url":"u1"}]`
+	tests := []struct {
+		name    string
+		header  string
+		wantErr error
+	}{
+		{name: "current", header: `{"version":4,` + parts + `,"upload_type":"s3_multipart"}`},
+		{name: "migrates version 1", header: `{"version":1,` + parts + `}`},
+		{name: "migrates version 2", header: `{"version":2,` + parts + `,"upload_type":"s3_multipart"}`},
+		{name: "migrates version 3", header: `{"version":3,` + parts + `,"upload_type":"s3_multipart"}`},
+		{name: "newer version", header: `{"version":5,"ingest":{"id":"ingest-1"}}`, wantErr: ErrJournalVersion},
+		{name: "missing version", header: `{` + parts + `}`, wantErr: ErrInvalidJournal},
+		{name: "missing parts", header: `{"version":3,"ingest_id":"ingest-1","file":"/tiles.pmtiles","size":10,"part_size":4,"upload_type":"s3_multipart"}`, wantErr: ErrInvalidJournal},
+		{name: "unknown upload type", header: `{"version":3,` + parts + `,"upload_type":"ftp"}`, wantErr: ErrInvalidJournal},
+		{name: "not json", header: `journal`, wantErr: ErrInvalidJournal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			jp := filepath.Join(t.TempDir(), "tiles.journal")
+			if err := os.WriteFile(jp, []byte(tt.header+"\n"+`{"part_id":1,"etag":"a"}`+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			h, done, err := readJournal(jp, nil)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("readJournal() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readJournal() unexpected error: %v", err)
+			}
+			if h.Version != journalVersion || h.UploadType != ingestUploadTypeS3MultiPart || done[1] != "a" {
+				t.Errorf("readJournal() = %+v, %v", h, done)
+			}
+		})
+	}
+}
+
+func TestPruneJournals(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	journalOf := func(name string) (string, IngestResponse) {
+		ir, err := c.Begin(t.Context(), "", fp)
+		if err != nil {
+			t.Fatalf("Begin() unexpected error: %v", err)
+		}
+		jp := filepath.Join(dir, name)
+		j, err := c.startJournal(ingestConfig{journal: jp}, ir, fp, info)
+		if err != nil {
+			t.Fatalf("startJournal() unexpected error: %v", err)
+		}
+		j.close()
+		return jp, ir
+	}
+	waiting, _ := journalOf("waiting.journal")
+	canceled, ir := journalOf("canceled.journal")
+	if _, err := c.Cancel(t.Context(), ir.ID); err != nil {
+		t.Fatalf("Cancel() unexpected error: %v", err)
+	}
+	gone := filepath.Join(dir, "gone.journal")
+	data := `{"version":2,"ingest_id":"ingest-99","file":"/tiles.pmtiles","size":10,"part_size":4,"parts":[{"part_id":1,"url":"u1"}],"upload_type":"s3_multipart"}` + "\n"
+	if err := os.WriteFile(gone, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(dir, "broken.journal")
+	if err := os.WriteFile(broken, []byte("journal\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.PruneJournals(t.Context(), waiting, canceled, gone, broken)
+	if !errors.Is(err, ErrInvalidJournal) {
+		t.Fatalf("PruneJournals() error = %v, want ErrInvalidJournal of the broken journal", err)
+	}
+	want := []struct {
+		path    string
+		state   IngestState
+		removed bool
+	}{
+		{path: waiting},
+		{path: canceled, state: StateCanceled, removed: true},
+		{path: gone, removed: true},
+		{path: broken},
+	}
+	if len(res) != len(want) {
+		t.Fatalf("PruneJournals() = %v, want %d journals", res, len(want))
+	}
+	for i, w := range want {
+		if res[i].Path != w.path || res[i].State != w.state || res[i].Removed != w.removed {
+			t.Errorf("PruneJournals()[%d] = %+v, want %+v", i, res[i], w)
+		}
+		if _, err := os.Stat(w.path); errors.Is(err, os.ErrNotExist) != w.removed {
+			t.Errorf("journal %s exists = %v, want removed = %v", w.path, err == nil, w.removed)
+		}
+	}
+	if res[3].Error == "" {
+		t.Error("expected the error of the broken journal to be reported")
+	}
+}
+
+// lostResponse sends requests with http.DefaultTransport and drops the
+// responses of finalize, as if the process died before reading them.
+type lostResponse struct{}
+
+func (lostResponse) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err == nil && strings.HasSuffix(r.URL.Path, "/process") {
+		resp.Body.Close() //nolint:errcheck,gosec
+		return nil, io.ErrUnexpectedEOF
+	}
+	return resp, err
+}
+
+func TestResumeReconcilesFinalizedIngest(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	jp := filepath.Join(dir, "tiles.journal")
+
+	c, err := New(srv.URL, "token", WithRoundTripper(lostResponse{}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	_, err = c.Create(t.Context(), fp, WithJournal(jp))
+	var uerr UploadFailedError
+	if !errors.As(err, &uerr) || uerr.Journal != jp {
+		t.Fatalf("Create() error = %v, want UploadFailedError keeping the journal", err)
+	}
+	if got := IngestState(srv.State(uerr.ID)); got != StateCompleted {
+		t.Fatalf("ingest is %q, want the lost finalize to have completed it", got)
+	}
+
+	// the file may change once the ingest was finalized.
+	if err := os.WriteFile(fp, []byte("changed"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err = New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	var uploaded bool
+	ir, err := c.Resume(t.Context(), jp, WithProgress(func(Progress) { uploaded = true }))
+	if err != nil {
+		t.Fatalf("Resume() unexpected error: %v", err)
+	}
+	if ir.ID != uerr.ID || ir.State != StateCompleted || !ir.Reconciled || uploaded {
+		t.Errorf("Resume() = %+v, want the completed ingest without an upload", ir)
+	}
+	if _, err := os.Stat(jp); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the journal to be removed, got %v", err)
+	}
+}
+
+// lostRequest fails the finalize requests before they are sent and sends other
+// requests with http.DefaultTransport.
+type lostRequest struct{}
+
+func (lostRequest) RoundTrip(r *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(r.URL.Path, "/process") {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestResumeGuardsFinalize(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	jp := filepath.Join(dir, "tiles.journal")
+
+	c, err := New(srv.URL, "token", WithRoundTripper(lostRequest{}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	_, err = c.Create(t.Context(), fp, WithJournal(jp))
+	var uerr UploadFailedError
+	if !errors.As(err, &uerr) || uerr.Journal != jp {
+		t.Fatalf("Create() error = %v, want UploadFailedError keeping the journal", err)
+	}
+
+	c, err = New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if _, err := c.Resume(t.Context(), jp); !errors.Is(err, ErrFinalizeSent) {
+		t.Fatalf("Resume() error = %v, want ErrFinalizeSent", err)
+	}
+	ir, err := c.Resume(t.Context(), jp, WithForceFinalize())
+	if err != nil {
+		t.Fatalf("Resume() unexpected error: %v", err)
+	}
+	if ir.State != StateCompleted || ir.Reconciled {
+		t.Errorf("Resume() = %+v, want the ingest finalized again", ir)
+	}
+}
+
+// rejectedFinalize rejects the finalize requests with 429, as if the service
+// was rate limiting, and sends other requests with http.DefaultTransport.
+type rejectedFinalize struct{}
+
+func (rejectedFinalize) RoundTrip(r *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(r.URL.Path, "/process") {
+		return &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("slow down")),
+			Request:    r,
+		}, nil
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestResumeAfterRejectedFinalize(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	jp := filepath.Join(dir, "tiles.journal")
+
+	c, err := New(srv.URL, "token",
+		WithRoundTripper(rejectedFinalize{}),
+		WithRetryPolicy(FixedDelay{Attempts: 2, Delay: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	_, err = c.Create(t.Context(), fp, WithJournal(jp))
+	var rerr RateLimitError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("Create() error = %v, want RateLimitError", err)
+	}
+	if _, err := os.Stat(jp); err != nil {
+		t.Fatalf("expected the journal to be kept: %v", err)
+	}
+
+	c, err = New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ir, err := c.Resume(t.Context(), jp)
+	if err != nil {
+		t.Fatalf("Resume() error = %v, want the rejected finalize to be sent again", err)
+	}
+	if ir.State != StateCompleted {
+		t.Errorf("Resume() = %+v, want the ingest finalized", ir)
+	}
+}
@@ -0,0 +1,90 @@
+package maptiler
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Tilestats is the summary of the features of a tileset some tilesets carry in
+// their TileJSON.
+type Tilestats struct {
+	LayerCount int              `json:"layerCount"`
+	Layers     []TilestatsLayer `json:"layers"`
+}
+
+// TilestatsLayer summarizes the features of a single source layer.
+type TilestatsLayer struct {
+	Layer          string               `json:"layer"`
+	Count          int64                `json:"count"`
+	Geometry       string               `json:"geometry,omitempty"`
+	AttributeCount int                  `json:"attributeCount"`
+	Attributes     []TilestatsAttribute `json:"attributes,omitempty"`
+}
+
+// TilestatsAttribute summarizes the values of an attribute of a source layer.
+type TilestatsAttribute struct {
+	Attribute string `json:"attribute"`
+	Count     int64  `json:"count"`
+	Type      string `json:"type"`
+}
+
+// LayerStats describes a source layer of a tileset. Features and Geometry are
+// only known if the tileset has tilestats.
+type LayerStats struct {
+	Layer      string   `json:"layer"`
+	Features   int64    `json:"features,omitempty"`
+	Geometry   string   `json:"geometry,omitempty"`
+	Attributes []string `json:"attributes,omitempty"`
+	MinZoom    float64  `json:"minzoom"`
+	MaxZoom    float64  `json:"maxzoom"`
+}
+
+func (s LayerStats) String() string { return toJSONString(s) }
+
+// LayerStats returns the statistics of the source layers of the tileset, ordered
+// by layer.
+func (t TileJSON) LayerStats() []LayerStats {
+	stats := make(map[string]LayerStats, len(t.VectorLayers))
+	for _, l := range t.VectorLayers {
+		stats[l.ID] = LayerStats{
+			Layer:      l.ID,
+			Attributes: slices.Sorted(maps.Keys(l.Fields)),
+			MinZoom:    l.MinZoom,
+			MaxZoom:    l.MaxZoom,
+		}
+	}
+
+	if t.Tilestats != nil {
+		for _, l := range t.Tilestats.Layers {
+			s, ok := stats[l.Layer]
+			if !ok {
+				s = LayerStats{Layer: l.Layer, MinZoom: t.MinZoom, MaxZoom: t.MaxZoom}
+				for _, a := range l.Attributes {
+					s.Attributes = append(s.Attributes, a.Attribute)
+				}
+				slices.Sort(s.Attributes)
+			}
+			s.Features = l.Count
+			s.Geometry = l.Geometry
+			stats[l.Layer] = s
+		}
+	}
+
+	out := make([]LayerStats, 0, len(stats))
+	for _, id := range slices.Sorted(maps.Keys(stats)) {
+		out = append(out, stats[id])
+	}
+	return out
+}
+
+// LayerStats returns the statistics of the source layers of the tileset of a
+// dataset, see TileJSON.
+func (c *Client) LayerStats(ctx context.Context, datasetID, key string) ([]LayerStats, error) {
+	tj, err := c.TileJSON(ctx, datasetID, key)
+	if err != nil {
+		return nil, fmt.Errorf("getting layer stats: %w", err)
+	}
+	return tj.LayerStats(), nil
+}
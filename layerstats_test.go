@@ -0,0 +1,92 @@
+package maptiler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTileJSONLayerStats(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		tj   TileJSON
+		want []LayerStats
+	}{
+		{
+			name: "vector layers only",
+			tj: TileJSON{
+				VectorLayers: []VectorLayer{
+					{ID: "water", MaxZoom: 10},
+					{ID: "roads", Fields: map[string]string{"name": "String", "class": "String"}, MinZoom: 4, MaxZoom: 14},
+				},
+			},
+			want: []LayerStats{
+				{Layer: "roads", Attributes: []string{"class", "name"}, MinZoom: 4, MaxZoom: 14},
+				{Layer: "water", MaxZoom: 10},
+			},
+		},
+		{
+			name: "with tilestats",
+			tj: TileJSON{
+				MinZoom: 0,
+				MaxZoom: 12,
+				VectorLayers: []VectorLayer{
+					{ID: "roads", Fields: map[string]string{"name": "String"}, MinZoom: 4, MaxZoom: 12},
+				},
+				Tilestats: &Tilestats{
+					LayerCount: 2,
+					Layers: []TilestatsLayer{
+						{Layer: "roads", Count: 120, Geometry: "LineString"},
+						{
+							Layer:      "pois",
+							Count:      7,
+							Geometry:   "Point",
+							Attributes: []TilestatsAttribute{{Attribute: "rank"}, {Attribute: "kind"}},
+						},
+					},
+				},
+			},
+			want: []LayerStats{
+				{Layer: "pois", Features: 7, Geometry: "Point", Attributes: []string{"kind", "rank"}, MaxZoom: 12},
+				{Layer: "roads", Features: 120, Geometry: "LineString", Attributes: []string{"name"}, MinZoom: 4, MaxZoom: 12},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.tj.LayerStats(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("LayerStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientLayerStats(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"tilejson":"3.0.0","maxzoom":14,
+			"vector_layers":[{"id":"roads","fields":{"name":"String"},"maxzoom":14}],
+			"tilestats":{"layerCount":1,"layers":[{"layer":"roads","count":42,"geometry":"LineString"}]}}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token", WithTilesHost(srv.URL))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	stats, err := c.LayerStats(t.Context(), "ds-1", "k")
+	if err != nil {
+		t.Fatalf("LayerStats() unexpected error: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Features != 42 || stats[0].Geometry != "LineString" {
+		t.Fatalf("unexpected layer stats %+v", stats)
+	}
+}
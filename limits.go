@@ -0,0 +1,120 @@
+package maptiler
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// v1MemoryUnlimited is the smallest memory.limit_in_bytes of cgroup v1 that is
+// treated as unlimited, the kernel reports unlimited as a page aligned maximum.
+const v1MemoryUnlimited = 1 << 62
+
+// Limits are the CPU and memory available to the process, as set by the cgroup
+// of a container or service. Zero values mean unlimited.
+type Limits struct {
+	// CPUs is the CPU quota in cores, e.g. 0.5 for half a core.
+	CPUs float64
+	// Memory is the memory limit in bytes.
+	Memory int64
+}
+
+// DetectLimits reads the limits of the cgroup the process runs in. Both cgroup
+// v2 and v1 are supported. It returns zero Limits on other platforms or if no
+// limits are set.
+func DetectLimits() Limits {
+	return detectLimits()
+}
+
+// Concurrency returns the number of parts to upload at the same time: four per
+// CPU, at least 2 and at most the default of 10.
+func (l Limits) Concurrency() int {
+	if l.CPUs <= 0 {
+		return defaultConcurrency
+	}
+	n := int(math.Ceil(l.CPUs * 4))
+	return min(max(n, 2), defaultConcurrency)
+}
+
+// InFlightBytes returns the budget for the parts uploaded at the same time, a
+// quarter of the memory limit, or 0 if memory is not limited.
+func (l Limits) InFlightBytes() int64 {
+	return l.Memory / 4
+}
+
+// readCgroupLimits reads the limits below root, usually /sys/fs/cgroup. The
+// files of cgroup v2 are preferred over those of v1.
+func readCgroupLimits(root string) Limits {
+	var l Limits
+
+	if quota, period, ok := readCPUMax(filepath.Join(root, "cpu.max")); ok {
+		l.CPUs = cpus(quota, period)
+	} else {
+		quota, qok := readInt(filepath.Join(root, "cpu", "cpu.cfs_quota_us"))
+		period, pok := readInt(filepath.Join(root, "cpu", "cpu.cfs_period_us"))
+		if qok && pok {
+			l.CPUs = cpus(quota, period)
+		}
+	}
+
+	if mem, ok := readInt(filepath.Join(root, "memory.max")); ok {
+		l.Memory = max(mem, 0)
+	} else if mem, ok := readInt(filepath.Join(root, "memory", "memory.limit_in_bytes")); ok && mem < v1MemoryUnlimited {
+		l.Memory = max(mem, 0)
+	}
+
+	return l
+}
+
+// readCPUMax parses the "$MAX $PERIOD" of a cgroup v2 cpu.max file, where $MAX
+// is "max" if the CPU is not limited.
+func readCPUMax(path string) (quota, period int64, ok bool) {
+	b, err := os.ReadFile(path) //nolint:gosec // the path is not user input.
+	if err != nil {
+		return 0, 0, false
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) != 2 {
+		return 0, 0, false
+	}
+	period, err = strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if fields[0] == "max" {
+		return -1, period, true
+	}
+	quota, err = strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return quota, period, true
+}
+
+// readInt reads a file holding a single integer. "max" is read as 0, i.e.
+// unlimited.
+func readInt(path string) (int64, bool) {
+	b, err := os.ReadFile(path) //nolint:gosec // the path is not user input.
+	if err != nil {
+		return 0, false
+	}
+	s := strings.TrimSpace(string(b))
+	if s == "max" {
+		return 0, true
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// cpus converts a CFS quota and period to cores. A negative quota is unlimited.
+func cpus(quota, period int64) float64 {
+	if quota <= 0 || period <= 0 {
+		return 0
+	}
+	return float64(quota) / float64(period)
+}
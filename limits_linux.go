@@ -0,0 +1,8 @@
+//go:build linux
+
+package maptiler
+
+// detectLimits reads the limits of cgroup v2, or v1, as mounted in containers.
+func detectLimits() Limits {
+	return readCgroupLimits("/sys/fs/cgroup")
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package maptiler
+
+// detectLimits returns zero Limits, cgroups only exist on linux.
+func detectLimits() Limits {
+	return Limits{}
+}
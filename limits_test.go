@@ -0,0 +1,152 @@
+package maptiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCgroupLimits(t *testing.T) {
+	t.Parallel()
+
+	const mib = 1 << 20
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  Limits
+	}{
+		{
+			name: "v2 limited",
+			files: map[string]string{
+				"cpu.max":    "150000 100000\n",
+				"memory.max": "268435456\n",
+			},
+			want: Limits{CPUs: 1.5, Memory: 256 * mib},
+		},
+		{
+			name: "v2 unlimited",
+			files: map[string]string{
+				"cpu.max":    "max 100000\n",
+				"memory.max": "max\n",
+			},
+			want: Limits{},
+		},
+		{
+			name: "v1 limited",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":         "50000\n",
+				"cpu/cpu.cfs_period_us":        "100000\n",
+				"memory/memory.limit_in_bytes": "536870912\n",
+			},
+			want: Limits{CPUs: 0.5, Memory: 512 * mib},
+		},
+		{
+			name: "v1 unlimited",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":         "-1\n",
+				"cpu/cpu.cfs_period_us":        "100000\n",
+				"memory/memory.limit_in_bytes": "9223372036854771712\n",
+			},
+			want: Limits{},
+		},
+		{
+			name: "malformed",
+			files: map[string]string{
+				"cpu.max":    "lots\n",
+				"memory.max": "some\n",
+			},
+			want: Limits{},
+		},
+		{
+			name:  "no cgroup",
+			files: map[string]string{},
+			want:  Limits{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			for name, content := range tt.files {
+				fp := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(fp, []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := readCgroupLimits(root); got != tt.want {
+				t.Errorf("readCgroupLimits() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitsDefaults(t *testing.T) {
+	t.Parallel()
+
+	const mib = 1 << 20
+
+	tests := []struct {
+		name            string
+		limits          Limits
+		wantConcurrency int
+		wantInFlight    int64
+	}{
+		{name: "unlimited", limits: Limits{}, wantConcurrency: defaultConcurrency, wantInFlight: 0},
+		{name: "small container", limits: Limits{CPUs: 0.25, Memory: 256 * mib}, wantConcurrency: 2, wantInFlight: 64 * mib},
+		{name: "two cores", limits: Limits{CPUs: 2}, wantConcurrency: 8, wantInFlight: 0},
+		{name: "many cores", limits: Limits{CPUs: 16, Memory: 8192 * mib}, wantConcurrency: defaultConcurrency, wantInFlight: 2048 * mib},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.limits.Concurrency(); got != tt.wantConcurrency {
+				t.Errorf("Concurrency() = %d, want %d", got, tt.wantConcurrency)
+			}
+			if got := tt.limits.InFlightBytes(); got != tt.wantInFlight {
+				t.Errorf("InFlightBytes() = %d, want %d", got, tt.wantInFlight)
+			}
+		})
+	}
+}
+
+func TestClientPartConcurrency(t *testing.T) {
+	t.Parallel()
+
+	const mib = 1 << 20
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		partSize int64
+		want     int
+	}{
+		{name: "no budget", opts: []Option{WithConcurrency(4)}, partSize: 100 * mib, want: 4},
+		{name: "budget limits", opts: []Option{WithInFlightBytes(64 * mib)}, partSize: 16 * mib, want: 4},
+		{name: "budget above concurrency", opts: []Option{WithConcurrency(3), WithInFlightBytes(64 * mib)}, partSize: 5 * mib, want: 3},
+		{name: "part larger than budget", opts: []Option{WithInFlightBytes(64 * mib)}, partSize: 100 * mib, want: 1},
+		{name: "negative budget", opts: []Option{WithInFlightBytes(-1)}, partSize: 100 * mib, want: defaultConcurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := New("http://localhost", "token", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.partConcurrency(tt.partSize); got != tt.want {
+				t.Errorf("partConcurrency(%d) = %d, want %d", tt.partSize, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package maptiler
+
+import (
+	"errors"
+	"fmt"
+)
+
+// lockFile is not supported on this platform.
+func lockFile(fp string) (func() error, error) {
+	return nil, fmt.Errorf("locking %q: file locking is not supported on this platform: %w", fp, errors.ErrUnsupported)
+}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package maptiler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockFile acquires an exclusive advisory lock on fp without blocking.
+// The returned function releases the lock.
+func lockFile(fp string) (func() error, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file at path '%s': %w", fp, err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil { //nolint:gosec
+		_ = f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("locking %q: %w", fp, ErrFileLocked)
+		}
+		return nil, fmt.Errorf("locking %q: %w", fp, err)
+	}
+
+	// closing the file releases the lock.
+	return f.Close, nil
+}
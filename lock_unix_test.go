@@ -0,0 +1,48 @@
+//go:build unix
+
+package maptiler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCreateWithFileLockFailsFast(t *testing.T) {
+	t.Parallel()
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("abc"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := lockFile(fp)
+	if err != nil {
+		t.Fatalf("lockFile() failed: %v", err)
+	}
+	defer unlock()
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		http.Error(w, "unexpected", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	cl, err := New(srv.URL+"/v1", "test-token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	_, err = cl.Create(t.Context(), fp, WithFileLock())
+	if !errors.Is(err, ErrFileLocked) {
+		t.Fatalf("expected ErrFileLocked, got %v", err)
+	}
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Fatalf("expected no requests before the lock is acquired, got %d", hits)
+	}
+}
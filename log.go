@@ -0,0 +1,42 @@
+package maptiler
+
+import (
+	"context"
+	"log/slog"
+)
+
+// logger returns l, or the default logger of slog at the time of the call if l
+// is nil, so a Client without WithLogger follows slog.SetDefault.
+func logger(l *slog.Logger) *slog.Logger {
+	if l != nil {
+		return l
+	}
+	return slog.Default()
+}
+
+// partLogListener logs the lifecycle of the parts of ingest id at debug level,
+// and retries at info level. Uploaded parts are logged by the uploadProcessor,
+// with their response headers. Events below the level of the logger return
+// before their attributes are built, as there is one per part.
+func partLogListener(l *slog.Logger, id string) taskListener[uploadTask] {
+	return taskListenerFunc[uploadTask](func(e taskEvent[uploadTask]) {
+		log := logger(l)
+		level := slog.LevelDebug
+		if e.Kind == taskRetried {
+			level = slog.LevelInfo
+		}
+		if !log.Enabled(context.Background(), level) {
+			return
+		}
+		part := e.Task.Body
+		switch e.Kind {
+		case taskStarted:
+			log.Debug("part started", "ingest_id", id, "part_id", part.PartID, "offset", part.Offset, "length", part.Length)
+		case taskRetried:
+			log.Info("retrying part", "ingest_id", id, "part_id", part.PartID, "attempt", e.Attempt, "error", e.Err)
+		case taskFailed:
+			log.Debug("part failed", "ingest_id", id, "part_id", part.PartID, "duration", e.Duration, "error", e.Err)
+		case taskEnqueued, taskFinished:
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package maptiler
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of the workers.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWithLogger(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	var buf syncBuffer
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c, err := New(srv.URL, "token", WithLogger(l))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ir, err := c.Create(t.Context(), fp)
+	if err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`msg="ingest created" ingest_id=` + ir.ID,
+		`msg="part started" ingest_id=` + ir.ID + " part_id=1",
+		`msg="part uploaded" ingest_id=` + ir.ID,
+		`msg="upload finished" ingest_id=` + ir.ID,
+		`msg="ingest finalized" ingest_id=` + ir.ID,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected log to contain %q, got:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, `msg="part uploaded"`); n != 3 {
+		t.Errorf("expected 3 uploaded parts to be logged, got %d", n)
+	}
+}
+
+func TestJoinListeners(t *testing.T) {
+	t.Parallel()
+
+	if l := joinListeners[int](nil, nil); l != nil {
+		t.Fatalf("expected nil listener, got %v", l)
+	}
+
+	var got []string
+	record := func(name string) taskListener[int] {
+		return taskListenerFunc[int](func(e taskEvent[int]) { got = append(got, name) })
+	}
+	joinListeners(record("a"), nil, record("b")).OnTaskEvent(taskEvent[int]{Kind: taskStarted})
+	if strings.Join(got, ",") != "a,b" {
+		t.Fatalf("expected events forwarded to a,b, got %v", got)
+	}
+}
@@ -0,0 +1,313 @@
+package maptilertest
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+)
+
+// DefaultPartSize is the part size a Server hands out, the minimum part size
+// of S3 multipart uploads.
+const DefaultPartSize = 5 << 20
+
+// Server is a fake of the MapTiler service API and the upload targets of its
+// ingests. Ingests are processed as soon as they are finalized.
+type Server struct {
+	// URL is the service host to pass to maptiler.New.
+	URL string
+
+	srv      *httptest.Server
+	partSize int64
+
+	mu       sync.Mutex
+	next     int
+	ingests  map[string]*fakeIngest
+	datasets map[string]bool
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// WithPartSize sets the part size of the ingests of the Server. Ingest
+// requests with a part size of their own get that one.
+func WithPartSize(n int64) ServerOption {
+	return func(s *Server) {
+		s.partSize = n
+	}
+}
+
+type fakeError struct {
+	Message string `json:"message"`
+}
+
+type fakePart struct {
+	PartID int64  `json:"part_id"`
+	URL    string `json:"url"`
+}
+
+type fakeUpload struct {
+	PartSize int64      `json:"part_size"`
+	Parts    []fakePart `json:"parts"`
+	Type     string     `json:"type"`
+}
+
+type fakeIngest struct {
+	ID         string      `json:"id"`
+	DocumentID string      `json:"document_id"`
+	State      string      `json:"state"`
+	Filename   string      `json:"filename"`
+	Size       int64       `json:"size"`
+	Progress   float64     `json:"progress"`
+	Errors     []fakeError `json:"errors"`
+	Upload     fakeUpload  `json:"upload"`
+
+	etags    map[int64]string
+	sizes    map[int64]int64
+	metadata map[string]string
+}
+
+// NewServer starts a Server. It has to be closed with Close.
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{
+		partSize: DefaultPartSize,
+		ingests:  make(map[string]*fakeIngest),
+		datasets: make(map[string]bool),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/datasets/ingest", s.handleIngest)
+	mux.HandleFunc("POST /v1/datasets/{id}/ingest", s.handleIngest)
+	mux.HandleFunc("GET /v1/datasets/ingest/{id}", s.handleGet)
+	mux.HandleFunc("POST /v1/datasets/ingest/{id}/cancel", s.handleCancel)
+	mux.HandleFunc("POST /v1/datasets/ingest/{id}/process", s.handleProcess)
+	mux.HandleFunc("GET /v1/datasets/{id}", s.handleDataset)
+	mux.HandleFunc("DELETE /v1/datasets/{id}", s.handleDeleteDataset)
+	mux.HandleFunc("PUT /upload/{id}/{part}", s.handlePart)
+
+	s.srv = httptest.NewServer(mux)
+	s.URL = s.srv.URL + "/v1"
+	return s
+}
+
+// Close shuts the Server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// State returns the state of an ingest, or an empty string if it does not exist.
+func (s *Server) State(id string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if in, ok := s.ingests[id]; ok {
+		return in.State
+	}
+	return ""
+}
+
+// Metadata returns the metadata an ingest was created with.
+func (s *Server) Metadata(id string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if in, ok := s.ingests[id]; ok {
+		return in.metadata
+	}
+	return nil
+}
+
+func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Filename string            `json:"filename"`
+		Size     int64             `json:"size"`
+		PartSize int64             `json:"part_size"`
+		Metadata map[string]string `json:"metadata"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Size <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid ingest request")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	datasetID := r.PathValue("id")
+	if datasetID != "" && !s.datasets[datasetID] {
+		writeError(w, http.StatusNotFound, "dataset not found")
+		return
+	}
+	s.next++
+	if datasetID == "" {
+		datasetID = "dataset-" + strconv.Itoa(s.next)
+	}
+
+	partSize := s.partSize
+	if req.PartSize > 0 {
+		partSize = req.PartSize
+	}
+	in := &fakeIngest{
+		ID:         "ingest-" + strconv.Itoa(s.next),
+		DocumentID: datasetID,
+		State:      "upload",
+		Filename:   req.Filename,
+		Size:       req.Size,
+		Errors:     []fakeError{},
+		Upload:     fakeUpload{PartSize: partSize, Type: "s3_multipart"},
+		etags:      make(map[int64]string),
+		sizes:      make(map[int64]int64),
+		metadata:   req.Metadata,
+	}
+	for i := int64(1); (i-1)*partSize < req.Size; i++ {
+		in.Upload.Parts = append(in.Upload.Parts, fakePart{
+			PartID: i,
+			URL:    fmt.Sprintf("%s/upload/%s/%d", s.srv.URL, in.ID, i),
+		})
+	}
+	s.ingests[in.ID] = in
+	writeJSON(w, in)
+}
+
+func (s *Server) handlePart(w http.ResponseWriter, r *http.Request) {
+	part, err := strconv.ParseInt(r.PathValue("part"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "part not found")
+		return
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "reading part")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	in, ok := s.ingests[r.PathValue("id")]
+	if !ok || in.State != "upload" || part < 1 || part > int64(len(in.Upload.Parts)) {
+		writeError(w, http.StatusNotFound, "part not found")
+		return
+	}
+	etag := fmt.Sprintf(`"%x"`, h.Sum(nil)[:16])
+	in.etags[part] = etag
+	in.sizes[part] = n
+	w.Header().Set("ETag", etag)
+	w.Header().Set("X-Amz-Request-Id", fmt.Sprintf("%s-%d", in.ID, part))
+}
+
+func (s *Server) handleProcess(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UploadResult struct {
+			Parts []struct {
+				PartID int64  `json:"part_id"`
+				ETag   string `json:"etag"`
+			} `json:"parts"`
+		} `json:"upload_result"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid upload result")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	in, ok := s.ingests[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "ingest not found")
+		return
+	}
+	if in.State != "upload" {
+		writeError(w, http.StatusConflict, "ingest is "+in.State)
+		return
+	}
+
+	var size int64
+	for _, p := range req.UploadResult.Parts {
+		if in.etags[p.PartID] != p.ETag {
+			in.State = "failed"
+			in.Errors = append(in.Errors, fakeError{Message: fmt.Sprintf("part %d: etag mismatch", p.PartID)})
+			writeJSON(w, in)
+			return
+		}
+		size += in.sizes[p.PartID]
+	}
+	if len(req.UploadResult.Parts) != len(in.Upload.Parts) || size != in.Size {
+		in.State = "failed"
+		in.Errors = append(in.Errors, fakeError{Message: "incomplete upload"})
+		writeJSON(w, in)
+		return
+	}
+
+	in.State = "completed"
+	in.Progress = 100
+	s.datasets[in.DocumentID] = true
+	writeJSON(w, in)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	in, ok := s.ingests[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "ingest not found")
+		return
+	}
+	writeJSON(w, in)
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	in, ok := s.ingests[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "ingest not found")
+		return
+	}
+	if in.State == "upload" {
+		in.State = "canceled"
+	}
+	writeJSON(w, in)
+}
+
+func (s *Server) handleDataset(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	ok := s.datasets[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "dataset not found")
+		return
+	}
+	writeJSON(w, map[string]string{"id": id, "title": id})
+}
+
+func (s *Server) handleDeleteDataset(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.datasets[id] {
+		writeError(w, http.StatusNotFound, "dataset not found")
+		return
+	}
+	delete(s.datasets, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	//nolint:errcheck // the status is sent already.
+	json.NewEncoder(w).Encode(fakeError{Message: msg})
+}
@@ -0,0 +1,155 @@
+// Package maptilertest provides utilities for testing code that uses the maptiler client.
+package maptilertest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrConnectionReset is returned for requests a Transport resets.
+var ErrConnectionReset = errors.New("maptilertest: connection reset by peer")
+
+// ErrPartFailed is returned for the part uploads a Transport fails after
+// Faults.FailPartsAfter.
+var ErrPartFailed = errors.New("maptilertest: part upload failed")
+
+// WrongETag is the ETag a Transport replaces the ETag of a response with.
+const WrongETag = `"maptilertest-wrong-etag"`
+
+// Faults configures the faults a Transport injects. Every rate is the
+// probability between 0 and 1 that the fault is injected into a request.
+type Faults struct {
+	// LatencyRate requests are delayed by Latency before they are sent.
+	Latency     time.Duration
+	LatencyRate float64
+	// ResetRate requests are sent, but fail with ErrConnectionReset instead of
+	// returning the response.
+	ResetRate float64
+	// TruncateRate responses fail with io.ErrUnexpectedEOF after half of their body.
+	TruncateRate float64
+	// WrongETagRate responses that have an ETag get WrongETag instead.
+	WrongETagRate float64
+	// FailPartsAfter fails every part upload, a PUT request, with ErrPartFailed
+	// once this many of them succeeded, e.g. to verify that an interrupted
+	// upload can be resumed. Parts in flight at that moment still finish.
+	FailPartsAfter int
+	// Seed makes the injected faults reproducible for sequential requests.
+	Seed uint64
+}
+
+// Transport is an http.RoundTripper that injects faults into the requests it
+// forwards to Base.
+type Transport struct {
+	Base   http.RoundTripper
+	Faults Faults
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+	// parts is the number of part uploads that succeeded.
+	parts int
+}
+
+// NewTransport returns a Transport injecting f into requests sent with a clone
+// of http.DefaultTransport.
+func NewTransport(f Faults) *Transport {
+	var base http.RoundTripper = http.DefaultTransport
+	if dt, ok := http.DefaultTransport.(*http.Transport); ok {
+		base = dt.Clone()
+	}
+	return &Transport{Base: base, Faults: f}
+}
+
+// HTTPTransport returns an *http.Transport that sends all requests through t,
+// for clients that only accept an *http.Transport, e.g. maptiler.WithHTTPTransport.
+func (t *Transport) HTTPTransport() *http.Transport {
+	// an empty TLSNextProto keeps HTTP/2 from registering itself for https.
+	tr := &http.Transport{TLSNextProto: map[string]func(string, *tls.Conn) http.RoundTripper{}}
+	tr.RegisterProtocol("http", t)
+	tr.RegisterProtocol("https", t)
+	return tr
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.hit(t.Faults.LatencyRate) {
+		timer := time.NewTimer(t.Faults.Latency)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	part := req.Method == http.MethodPut && t.Faults.FailPartsAfter > 0
+	if part && t.partsDone() >= t.Faults.FailPartsAfter {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL, ErrPartFailed)
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if part && resp.StatusCode < http.StatusMultipleChoices {
+		t.mu.Lock()
+		t.parts++
+		t.mu.Unlock()
+	}
+
+	if t.hit(t.Faults.ResetRate) {
+		err := fmt.Errorf("%s %s: %w", req.Method, req.URL, ErrConnectionReset)
+		if cerr := resp.Body.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+		return nil, err
+	}
+	if resp.Header.Get("ETag") != "" && t.hit(t.Faults.WrongETagRate) {
+		resp.Header.Set("ETag", WrongETag)
+	}
+	if t.hit(t.Faults.TruncateRate) {
+		body, rerr := io.ReadAll(resp.Body)
+		if cerr := resp.Body.Close(); rerr == nil {
+			rerr = cerr
+		}
+		if rerr != nil {
+			return nil, rerr
+		}
+		resp.Body = io.NopCloser(io.MultiReader(
+			bytes.NewReader(body[:len(body)/2]),
+			errReader{io.ErrUnexpectedEOF},
+		))
+	}
+	return resp, nil
+}
+
+// partsDone returns the number of part uploads that succeeded.
+func (t *Transport) partsDone() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.parts
+}
+
+// hit reports whether a fault with the given rate is injected.
+func (t *Transport) hit(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rnd == nil {
+		//nolint:gosec // faults do not need a cryptographically secure source.
+		t.rnd = rand.New(rand.NewPCG(t.Faults.Seed, t.Faults.Seed))
+	}
+	return t.rnd.Float64() < rate
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
@@ -0,0 +1,101 @@
+package maptilertest
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"etag-1"`)
+		_, _ = w.Write([]byte("0123456789"))
+	}))
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		name     string
+		faults   Faults
+		wantErr  error
+		wantBody string
+		wantETag string
+		minTime  time.Duration
+	}{
+		{name: "no faults", wantBody: "0123456789", wantETag: `"etag-1"`},
+		{name: "latency", faults: Faults{Latency: 50 * time.Millisecond, LatencyRate: 1}, wantBody: "0123456789", wantETag: `"etag-1"`, minTime: 50 * time.Millisecond},
+		{name: "reset", faults: Faults{ResetRate: 1}, wantErr: ErrConnectionReset},
+		{name: "truncate", faults: Faults{TruncateRate: 1}, wantErr: io.ErrUnexpectedEOF, wantBody: "01234", wantETag: `"etag-1"`},
+		{name: "wrong etag", faults: Faults{WrongETagRate: 1}, wantBody: "0123456789", wantETag: WrongETag},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// requests go through the *http.Transport, as they do for the maptiler client.
+			c := &http.Client{Transport: NewTransport(tt.faults).HTTPTransport()}
+
+			start := time.Now()
+			resp, err := c.Get(srv.URL)
+			if err != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Get() error=%v want %v", err, tt.wantErr)
+				}
+				return
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("reading body error=%v want %v", err, tt.wantErr)
+			}
+			if string(body) != tt.wantBody {
+				t.Fatalf("body=%q want %q", body, tt.wantBody)
+			}
+			if got := resp.Header.Get("ETag"); got != tt.wantETag {
+				t.Fatalf("etag=%q want %q", got, tt.wantETag)
+			}
+			if elapsed := time.Since(start); elapsed < tt.minTime {
+				t.Fatalf("took %v, want at least %v", elapsed, tt.minTime)
+			}
+		})
+	}
+}
+
+func TestTransportFailPartsAfter(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(srv.Close)
+
+	c := &http.Client{Transport: NewTransport(Faults{FailPartsAfter: 2}).HTTPTransport()}
+	for i := range 4 {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPut, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := c.Do(req)
+		if i < 2 {
+			if err != nil {
+				t.Fatalf("part %d: unexpected error: %v", i+1, err)
+			}
+			_ = resp.Body.Close()
+			continue
+		}
+		if !errors.Is(err, ErrPartFailed) {
+			t.Fatalf("part %d: error=%v want %v", i+1, err, ErrPartFailed)
+		}
+	}
+
+	// other requests are not failed.
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+}
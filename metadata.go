@@ -0,0 +1,23 @@
+package maptiler
+
+import "maps"
+
+// metadataOf returns the metadata of the Client with the metadata of cfg
+// applied, or nil if there is none.
+func (c *Client) metadataOf(cfg ingestConfig) map[string]string {
+	md := maps.Clone(c.metadata)
+	for k, v := range cfg.metadata {
+		if md == nil {
+			md = make(map[string]string)
+		}
+		if v == "" {
+			delete(md, k)
+			continue
+		}
+		md[k] = v
+	}
+	if len(md) == 0 {
+		return nil
+	}
+	return md
+}
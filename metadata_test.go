@@ -0,0 +1,59 @@
+package maptiler
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestMetadata(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token", WithDefaultMetadata(map[string]string{"environment": "prod", "pipeline": "nightly"}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts []IngestOption
+		want map[string]string
+	}{
+		{
+			name: "defaults",
+			want: map[string]string{"environment": "prod", "pipeline": "nightly"},
+		},
+		{
+			name: "overridden",
+			opts: []IngestOption{WithMetadata(map[string]string{"pipeline": "hotfix", "ticket": "42"})},
+			want: map[string]string{"environment": "prod", "pipeline": "hotfix", "ticket": "42"},
+		},
+		{
+			name: "removed",
+			opts: []IngestOption{WithMetadata(map[string]string{"environment": "", "pipeline": ""})},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		ir, err := c.Create(t.Context(), fp, tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: Create() unexpected error: %v", tt.name, err)
+		}
+		if got := srv.Metadata(ir.ID); !maps.Equal(got, tt.want) {
+			t.Errorf("%s: metadata = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if len(c.metadata) != 2 || c.metadata["pipeline"] != "nightly" {
+		t.Errorf("defaults changed to %v", c.metadata)
+	}
+}
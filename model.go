@@ -3,10 +3,32 @@ package maptiler
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"time"
 )
 
 const ingestUploadTypeS3MultiPart = "s3_multipart"
 
+// IngestState is the state of an ingest as reported by the MapTiler service
+// API. States the service adds later are kept as they are.
+type IngestState string
+
+// States of an ingest. An ingest waits in StateUpload for its parts, is
+// processed once it is finalized and ends in one of the terminal states.
+const (
+	StateUpload     IngestState = "upload"
+	StateProcessing IngestState = "processing"
+	StateCompleted  IngestState = "completed"
+	StateFailed     IngestState = "failed"
+	StateCanceled   IngestState = "canceled"
+)
+
+// IsTerminal reports whether the ingest completed, failed or was canceled and
+// no longer changes.
+func (s IngestState) IsTerminal() bool {
+	return s == StateCompleted || s == StateFailed || s == StateCanceled
+}
+
 type MapTilerError struct {
 	Message string `json:"message"`
 }
@@ -14,35 +36,84 @@ type MapTilerError struct {
 type IngestResponse struct {
 	ID         string          `json:"id"`
 	DocumentID string          `json:"document_id"`
-	State      string          `json:"state"`
+	State      IngestState     `json:"state"`
 	Filename   string          `json:"filename"`
 	Size       int64           `json:"size"`
 	Progress   float64         `json:"progress"`
 	Errors     []MapTilerError `json:"errors"`
-	Upload     upload          `json:"upload"`
-	UploadURL  string          `json:"upload_url"`
+	// Warnings are non-fatal issues reported while processing, e.g. dropped features.
+	Warnings  []MapTilerError `json:"warnings,omitempty"`
+	Upload    upload          `json:"upload"`
+	UploadURL string          `json:"upload_url"`
+	Stats     UploadStats     `json:"upload_stats,omitzero"`
+	// Tileset is set once the ingest completed.
+	Tileset *Tileset `json:"tileset,omitempty"`
+	// Unchanged is set if an update was skipped as the dataset already holds
+	// the file, see WithIdempotentUpdates. There is no ingest then.
+	Unchanged bool `json:"unchanged,omitempty"`
+	// Reconciled is set by Resume if the ingest was finalized by an earlier
+	// run that did not read the response. Nothing was uploaded then.
+	Reconciled bool `json:"reconciled,omitempty"`
 }
 
 type IngestGetResponse struct {
 	ID         string          `json:"id"`
 	DocumentID string          `json:"document_id"`
-	State      string          `json:"state"`
+	State      IngestState     `json:"state"`
 	Filename   string          `json:"filename"`
 	Size       int64           `json:"size"`
 	Progress   float64         `json:"progress"`
 	Errors     []MapTilerError `json:"errors"`
+	// Warnings are non-fatal issues reported while processing, e.g. dropped features.
+	Warnings []MapTilerError `json:"warnings,omitempty"`
+}
+
+type Dataset struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Attribution string `json:"attribution"`
+	Size        int64  `json:"size,omitempty"`
+	Checksum    string `json:"checksum,omitempty"`
 }
 
 type UploadResult struct {
 	ID    string               `json:"-"`
 	Type  string               `json:"type"`
 	Parts []uploadTaskResponse `json:"parts"`
+	Stats UploadStats          `json:"-"`
+}
+
+// UploadStats describes how the parts of an ingest were uploaded.
+type UploadStats struct {
+	Parts     int           `json:"parts"`
+	Retries   int           `json:"retries"`
+	RetryTime time.Duration `json:"retry_time"`
+	Duration  time.Duration `json:"duration"`
+	// EnqueueWait is the time parts waited for a free upload worker.
+	EnqueueWait time.Duration `json:"enqueue_wait"`
+	// PartStats are ordered by part ID.
+	PartStats []PartStat `json:"part_stats,omitempty"`
+}
+
+// PartStat describes the upload of a single part. Header holds the response
+// headers of WithPartResponseHeaders, e.g. the request ID of the storage, to
+// correlate slow parts with its logs.
+type PartStat struct {
+	PartID   int64             `json:"part_id"`
+	Duration time.Duration     `json:"duration"`
+	Header   map[string]string `json:"header,omitempty"`
 }
 
 func (m MapTilerError) String() string     { return toJSONString(m) }
 func (r IngestResponse) String() string    { return toJSONString(r) }
 func (r IngestGetResponse) String() string { return toJSONString(r) }
 func (r UploadResult) String() string      { return toJSONString(r) }
+func (d Dataset) String() string           { return toJSONString(d) }
+func (s UploadStats) String() string       { return toJSONString(s) }
+
+// Ended reports whether the ingest completed, failed or was canceled.
+func (r IngestGetResponse) Ended() bool { return r.State.IsTerminal() }
 
 type uploadPart struct {
 	PartID int64  `json:"part_id"`
@@ -54,14 +125,32 @@ type uploadParts []uploadPart
 type uploadTaskResponse struct {
 	PartID int64  `json:"part_id"`
 	ETag   string `json:"etag"`
+	// Duration and Header are reported in PartStats, not sent to the service.
+	Duration time.Duration     `json:"-"`
+	Header   map[string]string `json:"-"`
 }
 
 type uploadTask struct {
 	uploadPart
+	IngestID string `json:"-"`
 	FilePath string
+	// Reader is read instead of the file at FilePath if it is set, see CreateFrom.
+	Reader   io.ReaderAt `json:"-"`
 	Offset   int64
 	Length   int64
-	RespCh   chan uploadTaskResponse
+	Progress *progressTracker `json:"-"`
+	// Limit is shared by all parts of an ingest, PartLimit applies per part in bytes per second.
+	Limit     *bandwidthLimiter `json:"-"`
+	PartLimit int64             `json:"-"`
+	// Retries is the number of times the part may be retried, unless Retry is set.
+	// Budget is shared by all parts of an ingest.
+	Retries int          `json:"-"`
+	Retry   RetryPolicy  `json:"-"`
+	Budget  *retryBudget `json:"-"`
+	// Breaker is shared by all parts of an ingest.
+	Breaker *circuitBreaker `json:"-"`
+	// Control holds the part before every attempt while the upload is paused.
+	Control *UploadControl `json:"-"`
 }
 
 type upload struct {
@@ -71,10 +160,22 @@ type upload struct {
 }
 
 type ingestRequest struct {
-	ID                   string   `json:"id"`
-	Filename             string   `json:"filename"`
-	Size                 int64    `json:"size"`
-	SupportedUploadTypes []string `json:"supported_upload_types"`
+	ID                   string            `json:"id"`
+	Filename             string            `json:"filename"`
+	Size                 int64             `json:"size"`
+	SupportedUploadTypes []string          `json:"supported_upload_types"`
+	Metadata             map[string]string `json:"metadata,omitempty"`
+	// PartSize is the part size preferred by the client, see WithPartSize.
+	PartSize int64 `json:"part_size,omitempty"`
+}
+
+// partStats returns the stats of the uploaded parts, sorted as parts.
+func partStats(parts []uploadTaskResponse) []PartStat {
+	stats := make([]PartStat, 0, len(parts))
+	for _, p := range parts {
+		stats = append(stats, PartStat{PartID: p.PartID, Duration: p.Duration, Header: p.Header})
+	}
+	return stats
 }
 
 func newUploadResult(id string, parts []uploadTaskResponse) UploadResult {
@@ -0,0 +1,35 @@
+package maptiler
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestIngestStateIsTerminal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		state IngestState
+		want  bool
+	}{
+		{state: StateUpload},
+		{state: StateProcessing},
+		{state: StateCompleted, want: true},
+		{state: StateFailed, want: true},
+		{state: StateCanceled, want: true},
+		{state: "queued"},
+	}
+	for _, tt := range tests {
+		if got := tt.state.IsTerminal(); got != tt.want {
+			t.Errorf("%s.IsTerminal() = %v, want %v", tt.state, got, tt.want)
+		}
+	}
+
+	var gr IngestGetResponse
+	if err := json.Unmarshal([]byte(`{"id":"ing-1","state":"failed"}`), &gr); err != nil {
+		t.Fatal(err)
+	}
+	if gr.State != StateFailed || !gr.Ended() {
+		t.Errorf("decoded state %q, want %q", gr.State, StateFailed)
+	}
+}
@@ -0,0 +1,613 @@
+package maptiler
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// clientConfig holds configuration values for the Client.
+type clientConfig struct {
+	etagExtractor  ETagExtractor
+	etagNormalizer ETagNormalizer
+	tilesHost      string
+	lockDir        string
+	conditional    bool
+	apiRateLimit   int
+	retry          RetryPolicy
+	transport      *http.Transport
+	wrapTransport  func(http.RoundTripper) http.RoundTripper
+	timeout        time.Duration
+	concurrency    int
+	inFlightBytes  int64
+	shared         int
+	sharedBPS      int64
+	apiVersion     string
+	endpoints      map[Endpoint]string
+	uploadHosts    []string
+	redirects      RedirectPolicy
+	uploadDebug    bool
+	partHeaders    []string
+	logger         *slog.Logger
+	metadata       map[string]string
+	idempotent     bool
+	state          *StateCipher
+	readOnly       bool
+}
+
+// Option configures the Client.
+type Option func(*clientConfig)
+
+// WithETagExtractor replaces HeaderETag, for upload targets that return the
+// part identifier in a different header or in the response body.
+func WithETagExtractor(fn ETagExtractor) Option {
+	return func(config *clientConfig) {
+		config.etagExtractor = fn
+	}
+}
+
+// WithETagNormalizer replaces NormalizeETag, e.g. for upload targets that expect
+// ETags exactly as returned.
+func WithETagNormalizer(fn ETagNormalizer) Option {
+	return func(config *clientConfig) {
+		config.etagNormalizer = fn
+	}
+}
+
+// WithTilesHost replaces the host of the MapTiler tiles API used by TileJSON and
+// the TileJSON URL of a Tileset.
+func WithTilesHost(host string) Option {
+	return func(config *clientConfig) {
+		config.tilesHost = host
+	}
+}
+
+// WithLockDir sets the directory for the lock files of WithDatasetLock and the
+// ingests recorded by WithConflictCheck. It defaults to a directory below
+// os.TempDir, and has to be shared by all processes that should see each other.
+func WithLockDir(dir string) Option {
+	return func(config *clientConfig) {
+		config.lockDir = dir
+	}
+}
+
+// WithConditionalRequests keeps the last response of Get, GetDataset and
+// TileJSON and revalidates it with If-None-Match, so that polling unchanged
+// resources, e.g. from a watcher, does not download them again.
+func WithConditionalRequests() Option {
+	return func(config *clientConfig) {
+		config.conditional = true
+	}
+}
+
+// WithIdempotentUpdates skips an Update whose file the dataset already holds,
+// by comparing the sha256 of the file to RemoteChecksum, so a replayed update
+// is not processed again. The skipped update returns the dataset with
+// IngestResponse.Unchanged set. Datasets the service reports no checksum for
+// are always updated, as are updates with WithForceUpdate.
+func WithIdempotentUpdates() Option {
+	return func(config *clientConfig) {
+		config.idempotent = true
+	}
+}
+
+// WithAPIRateLimit limits the calls to the MapTiler service API, e.g. to create,
+// get, cancel or finalize ingests, to rps per second across all goroutines using
+// the Client. Part uploads are not limited, see WithBandwidthLimit.
+func WithAPIRateLimit(rps int) Option {
+	return func(config *clientConfig) {
+		config.apiRateLimit = rps
+	}
+}
+
+// WithRetryPolicy retries failed calls to the service API that are safe to
+// repeat, i.e. Get, GetDataset, TileJSON and Cancel, as decided by p. Creating
+// and finalizing an ingest is only retried if the service rejected the call
+// with 429, 502 or 503. Retries wait at least as long as the Retry-After header
+// of the failed response asks for. p is also used for parts, unless
+// WithPartRetries or WithPartRetryPolicy is given. By default calls rejected
+// with 429, 502 or 503 are retried up to 3 times with exponential backoff,
+// WithRetryPolicy(NoRetry{}) turns retries off.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(config *clientConfig) {
+		config.retry = p
+	}
+}
+
+// WithHTTPTransport sends all requests of the Client, to the service API, the
+// upload targets and the tiles API, with tr, e.g. a maptilertest.Transport.
+func WithHTTPTransport(tr *http.Transport) Option {
+	return func(config *clientConfig) {
+		config.transport = tr
+	}
+}
+
+// WithRoundTripper is WithHTTPTransport for any http.RoundTripper, e.g. one
+// that routes requests through a proxy with a custom dialer or adds headers.
+func WithRoundTripper(rt http.RoundTripper) Option {
+	return func(config *clientConfig) {
+		config.transport = asHTTPTransport(rt)
+	}
+}
+
+// WithTransportWrapper wraps the transport of WithHTTPTransport, WithRoundTripper
+// or WithHTTPClient, or http.DefaultTransport, with fn, e.g. to inject faults
+// with a maptilertest.Transport, regardless of the order of the options.
+func WithTransportWrapper(fn func(http.RoundTripper) http.RoundTripper) Option {
+	return func(config *clientConfig) {
+		config.wrapTransport = fn
+	}
+}
+
+// WithHTTPClient sends all requests of the Client with the transport of hc,
+// or http.DefaultTransport if it has none. The timeout of hc applies to
+// requests to the service API and the tiles API. Part uploads are not limited
+// by it, and follow WithUploadRedirects instead of the CheckRedirect of hc.
+// The Jar of hc is not used.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(config *clientConfig) {
+		rt := hc.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		config.transport = asHTTPTransport(rt)
+		config.timeout = hc.Timeout
+	}
+}
+
+// WithTimeout limits requests to the service API and the tiles API to d, like
+// the timeout of WithHTTPClient. Part uploads are not limited by it.
+func WithTimeout(d time.Duration) Option {
+	return func(config *clientConfig) {
+		config.timeout = d
+	}
+}
+
+// WithConcurrency sets the number of parts of an ingest that are uploaded at
+// the same time. It defaults to 10, which is also used if n is not positive.
+func WithConcurrency(n int) Option {
+	return func(config *clientConfig) {
+		config.concurrency = n
+	}
+}
+
+// WithInFlightBytes limits the combined size of the parts of an ingest that are
+// uploaded at the same time to n bytes, by uploading fewer parts at once than
+// WithConcurrency allows. At least one part is always uploaded. 0 means no limit.
+func WithInFlightBytes(n int64) Option {
+	return func(config *clientConfig) {
+		config.inFlightBytes = n
+	}
+}
+
+// WithSharedConcurrency limits the parts uploaded at the same time across all
+// ingests of the Client to n, instead of WithConcurrency parts for every ingest
+// running at the same time. Ingests waiting for a free slot are served in
+// turn, so they share the slots fairly. 0 means no limit.
+func WithSharedConcurrency(n int) Option {
+	return func(config *clientConfig) {
+		config.shared = n
+	}
+}
+
+// WithSharedBandwidthLimit limits the combined upload throughput of all ingests
+// of the Client to bps bytes per second. Parts draw from the limit in small
+// chunks, so ingests share it by their parts in flight. It can be combined
+// with the limits of single ingests, e.g. WithBandwidthLimit.
+func WithSharedBandwidthLimit(bps int64) Option {
+	return func(config *clientConfig) {
+		config.sharedBPS = bps
+	}
+}
+
+// WithAPIVersion targets version v of the service API, e.g. "v1", with the
+// endpoints of that version. It defaults to DefaultAPIVersion. New fails with
+// ErrUnsupportedAPIVersion for versions not in SupportedAPIVersions. Pin the
+// version to keep its behavior when the default moves on.
+func WithAPIVersion(v string) Option {
+	return func(config *clientConfig) {
+		config.apiVersion = v
+	}
+}
+
+// WithEndpointPath replaces the path template of endpoint e, e.g. for a gateway
+// in front of the service API that rewrites paths. :id in path is replaced by
+// the ID of the ingest or dataset and is required where the default has it.
+// New fails for unknown endpoints and invalid paths.
+func WithEndpointPath(e Endpoint, path string) Option {
+	return func(config *clientConfig) {
+		if config.endpoints == nil {
+			config.endpoints = make(map[Endpoint]string)
+		}
+		config.endpoints[e] = path
+	}
+}
+
+// WithUploadHosts only allows parts to be uploaded to hosts matching one of
+// patterns, e.g. "*.amazonaws.com" or "proxy.example.com:8443". A pattern
+// without a port matches every port, *.domain matches all subdomains of domain.
+// An ingest whose part URLs point elsewhere is canceled with
+// ErrUploadHostNotAllowed before any part is sent. By default every host is allowed.
+func WithUploadHosts(patterns ...string) Option {
+	return func(config *clientConfig) {
+		config.uploadHosts = patterns
+	}
+}
+
+// WithUploadRedirects sets whether part uploads follow redirects of the upload
+// target. By default they fail with ErrRedirectNotAllowed, see RedirectPolicy.
+// Redirects are logged at debug level, without the query of the URL.
+func WithUploadRedirects(p RedirectPolicy) Option {
+	return func(config *clientConfig) {
+		config.redirects = p
+	}
+}
+
+// WithUploadDebug records the headers sent with failing part uploads, without
+// the payload, in APIError.Request. Signatures and session tokens are
+// redacted. The error document of S3 compatible targets is parsed into
+// APIError.S3 regardless.
+func WithUploadDebug(enabled bool) Option {
+	return func(config *clientConfig) {
+		config.uploadDebug = enabled
+	}
+}
+
+// WithPartResponseHeaders replaces the response headers of part uploads that
+// are kept in UploadStats.PartStats and logged at debug level, by default
+// DefaultPartResponseHeaders.
+func WithPartResponseHeaders(names ...string) Option {
+	return func(config *clientConfig) {
+		config.partHeaders = names
+	}
+}
+
+// WithLogger logs the lifecycle of ingests and their parts to l: creation,
+// parts starting, finishing and being retried, and finalization. Retries are
+// logged at info level, everything else at debug level. By default the Client
+// logs warnings to the default logger of slog.
+func WithLogger(l *slog.Logger) Option {
+	return func(config *clientConfig) {
+		config.logger = l
+	}
+}
+
+// WithDefaultMetadata attaches md, e.g. environment=prod or pipeline=nightly,
+// to every ingest the Client creates. WithMetadata overrides single keys per call.
+func WithDefaultMetadata(md map[string]string) Option {
+	return func(config *clientConfig) {
+		config.metadata = md
+	}
+}
+
+// WithStateCipher encrypts the journals of uploads with sc, see WithJournal, as
+// they hold presigned upload URLs. Journals in plain text can still be resumed.
+func WithStateCipher(sc *StateCipher) Option {
+	return func(config *clientConfig) {
+		config.state = sc
+	}
+}
+
+// WithReadOnly only lets the Client get ingests and datasets, e.g. for
+// dashboards and audits. Calls that would change anything fail with
+// ErrReadOnly before they are sent.
+func WithReadOnly() Option {
+	return func(config *clientConfig) {
+		config.readOnly = true
+	}
+}
+
+// ingestConfig holds per call configuration for Create and Update.
+type ingestConfig struct {
+	progress       ProgressFunc
+	lock           bool
+	rejectSymlinks bool
+	rejectSparse   bool
+	maxSize        int64
+	extensions     []string
+	bandwidth      int64
+	schedule       BandwidthSchedule
+	partBandwidth  int64
+	partRetries    int
+	partRetry      RetryPolicy
+	// partRetriesSet tells WithPartRetries(0) apart from no part retries
+	// configured, in which case the policy of the Client applies.
+	partRetriesSet bool
+	retryMax       int
+	retryMaxTime   time.Duration
+	breaker        int
+	drain          time.Duration
+	datasetLock    bool
+	datasetWait    time.Duration
+	conflictCheck  bool
+	cancelExisting bool
+	duplicateCheck bool
+	upsert         bool
+	// filenameOverride replaces the name of the file, see WithFilename.
+	filenameOverride string
+	rename           func(string) string
+	pollInterval     time.Duration
+	priority         Priority
+	filePriority     map[string]Priority
+	journal          string
+	control          *UploadControl
+	// reader is read instead of a file, see CreateFrom.
+	reader        io.ReaderAt
+	metadata      map[string]string
+	force         bool
+	forceFinalize bool
+	partSize      int64
+}
+
+// IngestOption configures a single Create or Update call.
+type IngestOption func(*ingestConfig)
+
+// WithProgress registers a ProgressFunc that is called whenever the ingest makes progress.
+func WithProgress(fn ProgressFunc) IngestOption {
+	return func(config *ingestConfig) {
+		config.progress = fn
+	}
+}
+
+// WithFileLock acquires an exclusive advisory lock (flock) on the file for the duration
+// of the ingest. The ingest fails fast with ErrFileLocked if another process holds a lock.
+func WithFileLock() IngestOption {
+	return func(config *ingestConfig) {
+		config.lock = true
+	}
+}
+
+// WithRejectSymlinks fails the ingest with ErrInvalidFile if the file is a symlink.
+// By default symlinks are followed.
+func WithRejectSymlinks() IngestOption {
+	return func(config *ingestConfig) {
+		config.rejectSymlinks = true
+	}
+}
+
+// WithRejectSparse fails the ingest with ErrInvalidFile if the file is sparse,
+// instead of uploading its holes as zeros. See FileStat.Sparse.
+func WithRejectSparse() IngestOption {
+	return func(config *ingestConfig) {
+		config.rejectSparse = true
+	}
+}
+
+// WithMaxSize fails the ingest with ErrFileTooLarge before any request is sent
+// if the file is larger than n bytes.
+func WithMaxSize(n int64) IngestOption {
+	return func(config *ingestConfig) {
+		config.maxSize = n
+	}
+}
+
+// WithAllowedExtensions fails the ingest with ErrUnsupportedExtension before any
+// request is sent if the file extension is not one of exts. Extensions are
+// compared case insensitive and include the leading dot. Use SupportedExtensions
+// to allow the formats supported by the MapTiler service.
+func WithAllowedExtensions(exts ...string) IngestOption {
+	return func(config *ingestConfig) {
+		config.extensions = make([]string, 0, len(exts))
+		for _, ext := range exts {
+			config.extensions = append(config.extensions, strings.ToLower(ext))
+		}
+	}
+}
+
+// WithFilename sends name instead of the name of the file as the filename of
+// the ingest, which the service uses as the name of the dataset, e.g. to publish
+// tmp-8f3a.pmtiles as europe-roads.pmtiles. The extension of the file is
+// appended unless name ends with it, the service detects the format of the file
+// by it. The name is normalized with NormalizeFilename. CreateAll uses it for
+// every file.
+func WithFilename(name string) IngestOption {
+	return func(config *ingestConfig) {
+		config.filenameOverride = name
+	}
+}
+
+// WithFilenameFunc renames the file for the ingest with fn, e.g. to add a
+// prefix. fn receives the name of the file, or the name of WithFilename, and
+// its result is normalized with NormalizeFilename.
+func WithFilenameFunc(fn func(name string) string) IngestOption {
+	return func(config *ingestConfig) {
+		config.rename = fn
+	}
+}
+
+// WithBandwidthLimit limits the combined upload throughput of all parts of an
+// ingest to bps bytes per second.
+func WithBandwidthLimit(bps int64) IngestOption {
+	return func(config *ingestConfig) {
+		config.bandwidth = bps
+	}
+}
+
+// WithBandwidthSchedule changes the limit of WithBandwidthLimit by the time of
+// day, e.g. to upload without a limit at night. The limit of WithBandwidthLimit
+// applies outside of the windows of s, it is unlimited if it is not set.
+func WithBandwidthSchedule(s BandwidthSchedule) IngestOption {
+	return func(config *ingestConfig) {
+		config.schedule = s
+	}
+}
+
+// WithPartBandwidthLimit limits the upload throughput of every single part
+// connection to bps bytes per second, so a large part cannot monopolize a shaped
+// link. It can be combined with WithBandwidthLimit.
+func WithPartBandwidthLimit(bps int64) IngestOption {
+	return func(config *ingestConfig) {
+		config.partBandwidth = bps
+	}
+}
+
+// WithPartRetries retries a failed part up to n times before the ingest is
+// canceled. Only transient failures are retried, see IsRetryable. 0 turns part
+// retries off, also if the Client has a policy of WithRetryPolicy.
+func WithPartRetries(n int) IngestOption {
+	return func(config *ingestConfig) {
+		config.partRetries = n
+		config.partRetry = nil
+		config.partRetriesSet = true
+	}
+}
+
+// WithPartRetryPolicy retries failed parts as decided by p, e.g. with an
+// ExponentialBackoff. It replaces WithPartRetries.
+func WithPartRetryPolicy(p RetryPolicy) IngestOption {
+	return func(config *ingestConfig) {
+		config.partRetry = p
+	}
+}
+
+// WithRetryBudget caps the retries of all parts of an ingest combined, by count
+// and by the total time spent retrying. A zero value means unlimited. Once the
+// budget is used up the next failing part fails with RetryBudgetExhaustedError.
+func WithRetryBudget(maxRetries int, maxTime time.Duration) IngestOption {
+	return func(config *ingestConfig) {
+		config.retryMax = maxRetries
+		config.retryMaxTime = maxTime
+	}
+}
+
+// WithCircuitBreaker fails the ingest with CircuitOpenError once the upload host
+// failed n times in a row with transient errors, instead of retrying against an
+// outage until the context times out.
+func WithCircuitBreaker(n int) IngestOption {
+	return func(config *ingestConfig) {
+		config.breaker = n
+	}
+}
+
+// WithDrainTimeout lets parts that are in flight when ctx is canceled finish for
+// up to d, instead of aborting them immediately. Parts that did not start yet are
+// abandoned and the ingest fails.
+func WithDrainTimeout(d time.Duration) IngestOption {
+	return func(config *ingestConfig) {
+		config.drain = d
+	}
+}
+
+// WithDatasetLock holds a local lock on the dataset for the duration of an Update,
+// so concurrent updates of the same dataset from different processes on this
+// host do not race. If the lock is held, the update waits for up to wait before
+// it fails with ErrIngestInProgress.
+func WithDatasetLock(wait time.Duration) IngestOption {
+	return func(config *ingestConfig) {
+		config.datasetLock = true
+		config.datasetWait = wait
+	}
+}
+
+// WithConflictCheck records the ingest of every Update, and fails the next Update
+// of the dataset with ErrDatasetBusy while that ingest is still uploading or
+// processing. With cancelExisting the conflicting ingest is canceled instead.
+// Ingests are recorded in the directory set by WithLockDir, so only updates
+// from this host are detected.
+func WithConflictCheck(cancelExisting bool) IngestOption {
+	return func(config *ingestConfig) {
+		config.conflictCheck = true
+		config.cancelExisting = cancelExisting
+	}
+}
+
+// WithDuplicateCheck records the name of every dataset created, see WithFilename,
+// and fails the next Create of the same name with ErrDuplicateName while that
+// dataset exists. With upsert the existing dataset is updated instead. The
+// service API has no search by name, names are recorded in the directory set by
+// WithLockDir, so only datasets created from this host are detected.
+func WithDuplicateCheck(upsert bool) IngestOption {
+	return func(config *ingestConfig) {
+		config.duplicateCheck = true
+		config.upsert = upsert
+	}
+}
+
+// WithPollInterval sets how often Wait, CreateAndWait and Watch get the state
+// of the ingest, 5 seconds by default.
+func WithPollInterval(d time.Duration) IngestOption {
+	return func(config *ingestConfig) {
+		config.pollInterval = d
+	}
+}
+
+// WithPriority sets the class of the ingest, PriorityNormal by default. A
+// PriorityBulk ingest uploads fewer parts at the same time than the Client
+// allows, see Priority.
+func WithPriority(p Priority) IngestOption {
+	return func(config *ingestConfig) {
+		config.priority = p
+	}
+}
+
+// WithFilePriorities sets the class of the files of CreateAll by their path in
+// fps, files that are not in priorities have the class of WithPriority.
+// CreateAll ingests critical files first and bulk files last, so an urgent
+// file is not stuck behind a backfill.
+func WithFilePriorities(priorities map[string]Priority) IngestOption {
+	return func(config *ingestConfig) {
+		config.filePriority = priorities
+	}
+}
+
+// WithJournal records the upload of Create and Update in a journal file at
+// path, to continue it with Resume after the process died or the upload
+// failed. The ingest is not canceled when its upload fails, resume it or
+// cancel it with Cancel. The journal must not exist, it is removed once the
+// ingest is finalized.
+func WithJournal(path string) IngestOption {
+	return func(config *ingestConfig) {
+		config.journal = path
+	}
+}
+
+// WithUploadControl pauses and resumes the upload with ctl, see UploadControl.
+func WithUploadControl(ctl *UploadControl) IngestOption {
+	return func(config *ingestConfig) {
+		config.control = ctl
+	}
+}
+
+// WithMetadata attaches md to the ingest, over the metadata of
+// WithDefaultMetadata. A key with an empty value removes the default.
+func WithMetadata(md map[string]string) IngestOption {
+	return func(config *ingestConfig) {
+		config.metadata = md
+	}
+}
+
+// WithForceUpdate updates the dataset even if it already holds the file, see
+// WithIdempotentUpdates.
+func WithForceUpdate() IngestOption {
+	return func(config *ingestConfig) {
+		config.force = true
+	}
+}
+
+// WithForceFinalize lets Resume finalize an ingest again although its journal
+// records that the finalize was sent, see ErrFinalizeSent.
+func WithForceFinalize() IngestOption {
+	return func(config *ingestConfig) {
+		config.forceFinalize = true
+	}
+}
+
+// WithPartSize asks the service to cut the file into parts of n bytes, e.g.
+// larger parts for fewer requests. The service may choose another part size,
+// the parts it returns are checked to cover the file either way, see
+// ErrUploadMismatch.
+func WithPartSize(n int64) IngestOption {
+	return func(config *ingestConfig) {
+		config.partSize = n
+	}
+}
+
+func newIngestConfig(options ...IngestOption) ingestConfig {
+	var config ingestConfig
+	for _, o := range options {
+		o(&config)
+	}
+	return config
+}
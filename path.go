@@ -0,0 +1,38 @@
+package maptiler
+
+import "strings"
+
+const (
+	// extendedPrefix marks windows paths that are passed to the file system as
+	// is, e.g. to exceed MAX_PATH.
+	extendedPrefix = `\\?\`
+	// extendedUNCPrefix is the extended-length form of \\server\share.
+	extendedUNCPrefix = extendedPrefix + `UNC\`
+)
+
+// OSPath returns fp in the form that file system calls accept for paths of any
+// length. On windows relative paths are made absolute, so that os adds the \\?\
+// extended-length prefix to paths beyond MAX_PATH, which it only does for
+// absolute paths. Elsewhere fp is returned unchanged.
+func OSPath(fp string) string {
+	return osPath(fp)
+}
+
+// SamePath reports whether a and b name the same file. On windows paths are
+// compared case-insensitively and with or without the \\?\ prefix.
+func SamePath(a, b string) bool {
+	return samePath(a, b)
+}
+
+// stripExtendedPrefix returns a windows path without its extended-length
+// prefix, \\?\C:\x becomes C:\x and \\?\UNC\server\share\x \\server\share\x.
+func stripExtendedPrefix(p string) string {
+	switch {
+	case len(p) >= len(extendedUNCPrefix) && strings.EqualFold(p[:len(extendedUNCPrefix)], extendedUNCPrefix):
+		return `\\` + p[len(extendedUNCPrefix):]
+	case strings.HasPrefix(p, extendedPrefix):
+		return p[len(extendedPrefix):]
+	default:
+		return p
+	}
+}
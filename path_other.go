@@ -0,0 +1,15 @@
+//go:build !windows
+
+package maptiler
+
+import "path/filepath"
+
+// osPath returns fp unchanged, paths are not limited in length.
+func osPath(fp string) string {
+	return fp
+}
+
+// samePath compares the cleaned a and b.
+func samePath(a, b string) bool {
+	return filepath.Clean(a) == filepath.Clean(b)
+}
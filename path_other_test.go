@@ -0,0 +1,25 @@
+//go:build !windows
+
+package maptiler
+
+import "testing"
+
+func TestSamePath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "/data/tiles.mbtiles", b: "/data/tiles.mbtiles", want: true},
+		{a: "/data/./tiles.mbtiles", b: "/data//tiles.mbtiles", want: true},
+		{a: "/data/tiles.mbtiles", b: "/data/TILES.mbtiles", want: false},
+		{a: "/data/tiles.mbtiles", b: "/other/tiles.mbtiles", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := SamePath(tt.a, tt.b); got != tt.want {
+			t.Errorf("SamePath(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package maptiler
+
+import "testing"
+
+func TestStripExtendedPrefix(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		`C:\data\tiles.mbtiles`:              `C:\data\tiles.mbtiles`,
+		`\\?\C:\data\tiles.mbtiles`:          `C:\data\tiles.mbtiles`,
+		`\\?\UNC\server\share\tiles.mbtiles`: `\\server\share\tiles.mbtiles`,
+		`\\?\unc\server\share\tiles.mbtiles`: `\\server\share\tiles.mbtiles`,
+		`\\server\share\tiles.mbtiles`:       `\\server\share\tiles.mbtiles`,
+		`/var/lib/maptiler/tiles.mbtiles`:    `/var/lib/maptiler/tiles.mbtiles`,
+		`\\?\`:                               ``,
+	} {
+		if got := stripExtendedPrefix(in); got != want {
+			t.Errorf("stripExtendedPrefix(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
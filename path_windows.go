@@ -0,0 +1,29 @@
+//go:build windows
+
+package maptiler
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// osPath makes fp absolute. UNC paths and paths with the \\?\ prefix are
+// absolute already.
+func osPath(fp string) string {
+	if fp == "" {
+		return fp
+	}
+	abs, err := filepath.Abs(fp)
+	if err != nil {
+		return fp
+	}
+	return abs
+}
+
+// samePath compares a and b case-insensitively, like NTFS does by default.
+func samePath(a, b string) bool {
+	return strings.EqualFold(
+		filepath.Clean(stripExtendedPrefix(a)),
+		filepath.Clean(stripExtendedPrefix(b)),
+	)
+}
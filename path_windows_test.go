@@ -0,0 +1,63 @@
+//go:build windows
+
+package maptiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSamePath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: `C:\data\tiles.mbtiles`, b: `c:\DATA\Tiles.MBTILES`, want: true},
+		{a: `C:\data\tiles.mbtiles`, b: `\\?\C:\data\tiles.mbtiles`, want: true},
+		{a: `\\server\share\tiles.mbtiles`, b: `\\?\UNC\server\share\tiles.mbtiles`, want: true},
+		{a: `C:\data\.\tiles.mbtiles`, b: `C:\data\tiles.mbtiles`, want: true},
+		{a: `C:\data\tiles.mbtiles`, b: `D:\data\tiles.mbtiles`, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := SamePath(tt.a, tt.b); got != tt.want {
+			t.Errorf("SamePath(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLongPath(t *testing.T) {
+	// not parallel, the test changes the working directory.
+	root := t.TempDir()
+
+	// a relative path beyond MAX_PATH, which os only handles once it is absolute.
+	rel := strings.Repeat(`long-directory-name\`, 14)
+	if err := os.MkdirAll(filepath.Join(root, rel), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	fp := filepath.Join(root, rel, "Tiles.MBTiles")
+	if err := os.WriteFile(fp, []byte("tiles"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if len(fp) <= 260 {
+		t.Fatalf("expected a path beyond MAX_PATH, got %d characters", len(fp))
+	}
+	t.Chdir(root)
+
+	for _, p := range []string{rel + "Tiles.MBTiles", `\\?\` + fp} {
+		info, err := checkFile(OSPath(p), newIngestConfig(WithAllowedExtensions(SupportedExtensions()...)))
+		if err != nil {
+			t.Fatalf("checkFile(%q) unexpected error: %v", p, err)
+		}
+		if info.Size() != 5 {
+			t.Errorf("checkFile(%q) size = %d, want 5", p, info.Size())
+		}
+		if _, err := FileChecksum(p); err != nil {
+			t.Errorf("FileChecksum(%q) unexpected error: %v", p, err)
+		}
+	}
+}
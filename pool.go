@@ -2,6 +2,9 @@ package maptiler
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 )
@@ -13,8 +16,10 @@ const (
 
 // poolConfig holds configuration values for the worker pool.
 type poolConfig struct {
-	queueSize   int
-	concurrency int
+	queueSize      int
+	concurrency    int
+	backpressure   time.Duration
+	onBackpressure func(poolStats)
 }
 
 type poolOption func(*poolConfig)
@@ -26,25 +31,73 @@ func withPoolConcurrency(c int) poolOption {
 	}
 }
 
-// withPoolQueueSize allows controlling the task channel buffer size.
+// withPoolQueueSize allows controlling the task and result channel buffer size.
 func withPoolQueueSize(qs int) poolOption {
 	return func(config *poolConfig) {
 		config.queueSize = qs
 	}
 }
 
-// pool is a generic worker pool that delegates processing tasks to a Processor.
-type pool[T any] struct {
-	processor processor[T]
+// errPoolStopped is returned by Enqueue and Submit once the pool was stopped
+// or its workers returned.
+var errPoolStopped = errors.New("pool stopped")
+
+// errStopDeadline is the cause of the cancellation of tasks that did not finish
+// before the deadline passed to StopWithDeadline.
+var errStopDeadline = errors.New("stop deadline exceeded")
+
+// withPoolBackpressure calls fn whenever Enqueue was blocked on a full queue for
+// at least d, i.e. the workers do not keep up with the producer.
+func withPoolBackpressure(d time.Duration, fn func(poolStats)) poolOption {
+	return func(config *poolConfig) {
+		config.backpressure = d
+		config.onBackpressure = fn
+	}
+}
+
+// poolStats describes how well the workers of a pool keep up with its producers.
+type poolStats struct {
+	// QueueDepth is the number of tasks waiting for a worker.
+	QueueDepth    int
+	MaxQueueDepth int
+	// EnqueueWait is the total time Enqueue was blocked on a full queue.
+	EnqueueWait    time.Duration
+	MaxEnqueueWait time.Duration
+}
+
+// pool is a generic worker pool that delegates processing tasks to a Processor
+// and collects their results. A pool processes a single batch of tasks, it can
+// not be started again once it is stopped.
+type pool[In, Out any] struct {
+	processor processor[In, Out]
 	config    *poolConfig
-	tasks     chan task[T]
+	tasks     chan task[In]
+	results   chan result[Out]
+	listener  taskListener[In]
+	retried   func(t task[In], attempt int, err error)
+
+	// quit is closed by StopWithDeadline, done once Start returned.
+	quit     chan struct{}
+	quitOnce sync.Once
+	done     chan struct{}
+	// stopped is closed by Stop before it closes tasks. Senders hold sending
+	// for reading, so tasks is only closed once no send is in flight.
+	stopped  chan struct{}
+	stopOnce sync.Once
+	sending  sync.RWMutex
+
+	mu        sync.Mutex
+	started   bool
+	cancel    context.CancelCauseFunc
+	abandoned []task[In]
+	stats     poolStats
 }
 
-// newPool creates a new worker pool for tasks of type T.
-func newPool[T any](
-	processor processor[T],
+// newPool creates a new worker pool for tasks of type In producing results of type Out.
+func newPool[In, Out any](
+	processor processor[In, Out],
 	options ...poolOption,
-) *pool[T] {
+) *pool[In, Out] {
 	config := &poolConfig{
 		concurrency: defaultConcurrency,
 		queueSize:   defaultQueueSize,
@@ -53,15 +106,30 @@ func newPool[T any](
 		o(config)
 	}
 
-	return &pool[T]{
-		tasks:     make(chan task[T], config.queueSize),
+	return &pool[In, Out]{
+		tasks:     make(chan task[In], config.queueSize),
+		results:   make(chan result[Out], config.queueSize),
 		processor: processor,
 		config:    config,
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
 	}
 }
 
-// Start launches the worker goroutines.
-func (wp *pool[T]) Start(ctx context.Context) error {
+// Start launches the worker goroutines and blocks until they are done.
+// The results channel is closed once all workers returned.
+func (wp *pool[In, Out]) Start(ctx context.Context) error {
+	defer close(wp.done)
+	defer close(wp.results)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	wp.mu.Lock()
+	wp.started = true
+	wp.cancel = cancel
+	wp.mu.Unlock()
+
 	g, ctx := errgroup.WithContext(ctx)
 	for range wp.config.concurrency {
 		g.Go(func() error {
@@ -71,29 +139,198 @@ func (wp *pool[T]) Start(ctx context.Context) error {
 	return g.Wait()
 }
 
-// Stop closes the tasks channel.
-func (wp *pool[T]) Stop() {
-	close(wp.tasks)
+// Listen registers l to receive the lifecycle events of all tasks. It has to be
+// called before tasks are enqueued.
+func (wp *pool[In, Out]) Listen(l taskListener[In]) {
+	wp.listener = l
+	wp.retried = nil
+	if l != nil {
+		wp.retried = wp.emitRetry
+	}
+}
+
+// Stop closes the tasks channel, the workers return once the queued tasks are
+// done. Tasks submitted afterwards are rejected with errPoolStopped.
+func (wp *pool[In, Out]) Stop() {
+	wp.stopOnce.Do(func() {
+		close(wp.stopped)
+		// wait for the senders woken up by stopped to return.
+		wp.sending.Lock()
+		defer wp.sending.Unlock()
+		close(wp.tasks)
+	})
+}
+
+// StopWithDeadline stops the pool from accepting and starting tasks and lets the
+// tasks in flight finish for up to d. Tasks still running after d are canceled.
+// It returns the tasks that were abandoned, either because they were canceled
+// or because they were still queued, and blocks until all workers returned.
+func (wp *pool[In, Out]) StopWithDeadline(d time.Duration) []task[In] {
+	wp.quitOnce.Do(func() { close(wp.quit) })
+
+	wp.mu.Lock()
+	started, cancel := wp.started, wp.cancel
+	wp.mu.Unlock()
+
+	if started {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-wp.done:
+		case <-timer.C:
+			cancel(errStopDeadline)
+			<-wp.done
+		}
+	}
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	for {
+		select {
+		case t, ok := <-wp.tasks:
+			if !ok {
+				return wp.abandoned
+			}
+			wp.abandoned = append(wp.abandoned, t)
+		default:
+			return wp.abandoned
+		}
+	}
 }
 
-// Enqueue adds a task to the tasks channel.
-func (wp *pool[T]) Enqueue(t task[T]) {
-	wp.tasks <- t
+// Enqueue adds a task to the tasks channel, see Submit.
+func (wp *pool[In, Out]) Enqueue(t task[In]) error {
+	return wp.Submit(context.Background(), t)
+}
+
+// Submit adds a task to the tasks channel, blocking while the queue is full.
+// It returns errPoolStopped if the pool was stopped or its workers returned,
+// e.g. as a task failed, and the cause of ctx once it is done, so a full queue
+// nobody reads from never blocks it forever.
+func (wp *pool[In, Out]) Submit(ctx context.Context, t task[In]) error {
+	wp.sending.RLock()
+	defer wp.sending.RUnlock()
+
+	select {
+	case <-wp.stopped:
+		return errPoolStopped
+	case <-wp.quit:
+		return errPoolStopped
+	case <-wp.done:
+		return errPoolStopped
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return context.Cause(ctx)
+	}
+
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-wp.stopped:
+		return errPoolStopped
+	case <-wp.quit:
+		return errPoolStopped
+	case <-wp.done:
+		return errPoolStopped
+	case wp.tasks <- t:
+	}
+	wp.recordEnqueue(time.Since(start))
+	wp.emit(taskEvent[In]{Kind: taskEnqueued, Task: t})
+	return nil
+}
+
+// Stats returns the backpressure statistics of the pool.
+func (wp *pool[In, Out]) Stats() poolStats {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	s := wp.stats
+	s.QueueDepth = len(wp.tasks)
+	return s
+}
+
+// recordEnqueue records an Enqueue that blocked for wait and reports backpressure.
+func (wp *pool[In, Out]) recordEnqueue(wait time.Duration) {
+	wp.mu.Lock()
+	wp.stats.EnqueueWait += wait
+	wp.stats.MaxEnqueueWait = max(wp.stats.MaxEnqueueWait, wait)
+	wp.stats.MaxQueueDepth = max(wp.stats.MaxQueueDepth, len(wp.tasks))
+	s := wp.stats
+	s.QueueDepth = len(wp.tasks)
+	wp.mu.Unlock()
+
+	if wp.config.onBackpressure != nil && wait >= wp.config.backpressure {
+		wp.config.onBackpressure(s)
+	}
+}
+
+// Results returns the channel results of successfully processed tasks are sent to.
+// It has to be drained while the pool is running.
+func (wp *pool[In, Out]) Results() <-chan result[Out] {
+	return wp.results
 }
 
 // process reads tasks from the channel and processes them using the given Processor.
-func (wp *pool[T]) process(ctx context.Context) error {
+func (wp *pool[In, Out]) process(ctx context.Context) error {
 	for {
+		// a stopped pool must not start queued tasks, even if one is ready.
+		select {
+		case <-wp.quit:
+			return nil
+		default:
+		}
+
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case <-wp.quit:
+			return nil
 		case t, ok := <-wp.tasks:
 			if !ok {
 				return nil
 			}
-			if err := wp.processor.Process(ctx, t); err != nil {
+			out, err := wp.run(ctx, t)
+			if err != nil {
 				return err
 			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case wp.results <- newResult(t, out):
+			}
+		}
+	}
+}
+
+// run processes a single task and emits its lifecycle events.
+func (wp *pool[In, Out]) run(ctx context.Context, t task[In]) (Out, error) {
+	start := time.Now()
+	t.retried = wp.retried
+	wp.emit(taskEvent[In]{Kind: taskStarted, Task: t})
+
+	out, err := wp.processor.Process(ctx, t)
+	if err != nil {
+		if errors.Is(context.Cause(ctx), errStopDeadline) {
+			wp.mu.Lock()
+			wp.abandoned = append(wp.abandoned, t)
+			wp.mu.Unlock()
 		}
+		wp.emit(taskEvent[In]{Kind: taskFailed, Task: t, Err: err, Duration: time.Since(start)})
+		return out, err
+	}
+	wp.emit(taskEvent[In]{Kind: taskFinished, Task: t, Duration: time.Since(start)})
+	return out, nil
+}
+
+// emitRetry emits the retry of t.
+func (wp *pool[In, Out]) emitRetry(t task[In], attempt int, err error) {
+	wp.emit(taskEvent[In]{Kind: taskRetried, Task: t, Attempt: attempt, Err: err})
+}
+
+// emit forwards e to the listener, if any.
+func (wp *pool[In, Out]) emit(e taskEvent[In]) {
+	if wp.listener != nil {
+		wp.listener.OnTaskEvent(e)
 	}
 }
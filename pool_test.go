@@ -2,11 +2,16 @@ package maptiler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/segmentio/ksuid"
 )
 
 func TestWorkerPoolProcessTasks(t *testing.T) {
@@ -21,7 +26,7 @@ func TestWorkerPoolProcessTasks(t *testing.T) {
 	go func() {
 		for i := range numTestTasks {
 			task := newTask(fmt.Sprintf("palimpalim-%d", i))
-			wp.Enqueue(task)
+			_ = wp.Enqueue(task)
 		}
 		wp.Stop()
 	}()
@@ -40,17 +45,54 @@ type errorProcessor struct {
 	tasks sync.Map
 }
 
-func (e *errorProcessor) Process(ctx context.Context, t task[string]) error {
+func (e *errorProcessor) Process(ctx context.Context, t task[string]) (string, error) {
 	// when we receive "fail", we force an error
 	if t.Body == "fail" {
-		return fmt.Errorf("processing failed")
+		return "", fmt.Errorf("processing failed")
 	}
 	e.tasks.Store(t.ID.String(), t)
-	return nil
+	return t.Body, nil
 }
 
 func (e *errorProcessor) Close() {}
 
+func TestWorkerPoolResults(t *testing.T) {
+	wp := newPool(&testProcessor{}, withPoolConcurrency(2), withPoolQueueSize(1))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+	defer cancel()
+
+	want := map[ksuid.KSUID]string{}
+	var tasks []task[string]
+	for i := range 5 {
+		tsk := newTask(fmt.Sprintf("palimpalim-%d", i))
+		want[tsk.ID] = strings.ToUpper(tsk.Body)
+		tasks = append(tasks, tsk)
+	}
+
+	go func() {
+		for _, tsk := range tasks {
+			_ = wp.Enqueue(tsk)
+		}
+		wp.Stop()
+	}()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- wp.Start(ctx) }()
+
+	got := map[ksuid.KSUID]string{}
+	for r := range wp.Results() {
+		got[r.TaskID] = r.Body
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	if !maps.Equal(got, want) {
+		t.Fatalf("expected results %v, got %v", want, got)
+	}
+}
+
 func TestWorkerPoolTaskError(t *testing.T) {
 	s := &errorProcessor{}
 
@@ -59,8 +101,8 @@ func TestWorkerPoolTaskError(t *testing.T) {
 	ctx := t.Context()
 
 	go func() {
-		wp.Enqueue(newTask("ok"))
-		wp.Enqueue(newTask("fail"))
+		_ = wp.Enqueue(newTask("ok"))
+		_ = wp.Enqueue(newTask("fail"))
 		wp.Stop()
 	}()
 
@@ -97,9 +139,9 @@ type testProcessor struct {
 	tasks sync.Map
 }
 
-func (d *testProcessor) Process(ctx context.Context, t task[string]) error {
+func (d *testProcessor) Process(ctx context.Context, t task[string]) (string, error) {
 	d.tasks.Store(t.ID.String(), t)
-	return nil
+	return strings.ToUpper(t.Body), nil
 }
 
 func (d *testProcessor) Close() {}
@@ -112,3 +154,248 @@ func (d *testProcessor) Count() int {
 	})
 	return i
 }
+
+type retryingProcessor struct{}
+
+func (retryingProcessor) Process(ctx context.Context, t task[string]) (string, error) {
+	switch t.Body {
+	case "retry":
+		t.reportRetry(1, errors.New("transient"))
+	case "fail":
+		return "", errors.New("processing failed")
+	}
+	return t.Body, nil
+}
+
+func (retryingProcessor) Close() {}
+
+func TestWorkerPoolTaskEvents(t *testing.T) {
+	tests := []struct {
+		body    string
+		want    []taskEventKind
+		wantErr bool
+	}{
+		{body: "ok", want: []taskEventKind{taskEnqueued, taskStarted, taskFinished}},
+		{body: "retry", want: []taskEventKind{taskEnqueued, taskStarted, taskRetried, taskFinished}},
+		{body: "fail", want: []taskEventKind{taskEnqueued, taskStarted, taskFailed}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			var (
+				mu  sync.Mutex
+				got []taskEventKind
+			)
+			wp := newPool[string, string](retryingProcessor{}, withPoolConcurrency(1), withPoolQueueSize(1))
+			wp.Listen(taskListenerFunc[string](func(e taskEvent[string]) {
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, e.Kind)
+				if e.Kind == taskRetried && (e.Attempt != 1 || e.Err == nil) {
+					t.Errorf("unexpected retried event %+v", e)
+				}
+			}))
+
+			_ = wp.Enqueue(newTask(tt.body))
+			wp.Stop()
+
+			err := wp.Start(t.Context())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Start() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// blockingProcessor signals started for every task and blocks until release is
+// closed or the context is canceled.
+type blockingProcessor struct {
+	started chan string
+	release chan struct{}
+}
+
+func (b *blockingProcessor) Process(ctx context.Context, t task[string]) (string, error) {
+	b.started <- t.Body
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-b.release:
+		return t.Body, nil
+	}
+}
+
+func (b *blockingProcessor) Close() {}
+
+func TestWorkerPoolStopWithDeadline(t *testing.T) {
+	tests := []struct {
+		name          string
+		release       bool
+		wantAbandoned []string
+		wantErr       bool
+	}{
+		{name: "in flight finishes", release: true, wantAbandoned: []string{"queued"}},
+		{name: "straggler canceled", wantAbandoned: []string{"inflight", "queued"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &blockingProcessor{started: make(chan string, 1), release: make(chan struct{})}
+			wp := newPool[string, string](p, withPoolConcurrency(1), withPoolQueueSize(2))
+
+			errCh := make(chan error, 1)
+			go func() { errCh <- wp.Start(t.Context()) }()
+			go func() {
+				for range wp.Results() {
+				}
+			}()
+
+			if err := wp.Enqueue(newTask("inflight")); err != nil {
+				t.Fatalf("Enqueue() unexpected error: %v", err)
+			}
+			<-p.started
+			if err := wp.Enqueue(newTask("queued")); err != nil {
+				t.Fatalf("Enqueue() unexpected error: %v", err)
+			}
+
+			if tt.release {
+				time.AfterFunc(10*time.Millisecond, func() { close(p.release) })
+			}
+			abandoned := wp.StopWithDeadline(200 * time.Millisecond)
+
+			var got []string
+			for _, tsk := range abandoned {
+				got = append(got, tsk.Body)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.wantAbandoned) {
+				t.Fatalf("abandoned = %v, want %v", got, tt.wantAbandoned)
+			}
+			if err := <-errCh; (err != nil) != tt.wantErr {
+				t.Fatalf("Start() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := wp.Enqueue(newTask("late")); !errors.Is(err, errPoolStopped) {
+				t.Fatalf("Enqueue() after stop error = %v, want %v", err, errPoolStopped)
+			}
+		})
+	}
+}
+
+func TestWorkerPoolStopWithDeadlineNotStarted(t *testing.T) {
+	wp := newPool[string, string](&testProcessor{}, withPoolQueueSize(2))
+	_ = wp.Enqueue(newTask("queued"))
+
+	abandoned := wp.StopWithDeadline(time.Second)
+	if len(abandoned) != 1 || abandoned[0].Body != "queued" {
+		t.Fatalf("abandoned = %v, want the queued task", abandoned)
+	}
+}
+
+func TestWorkerPoolBackpressure(t *testing.T) {
+	p := &blockingProcessor{started: make(chan string, 2), release: make(chan struct{})}
+
+	var reports []poolStats
+	wp := newPool[string, string](
+		p,
+		withPoolConcurrency(1),
+		withPoolQueueSize(1),
+		withPoolBackpressure(20*time.Millisecond, func(s poolStats) { reports = append(reports, s) }),
+	)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- wp.Start(t.Context()) }()
+	go func() {
+		for range wp.Results() {
+		}
+	}()
+
+	_ = wp.Enqueue(newTask("a"))
+	<-p.started
+	_ = wp.Enqueue(newTask("b"))
+	if got := wp.Stats().QueueDepth; got != 1 {
+		t.Fatalf("QueueDepth = %d, want 1", got)
+	}
+
+	// the queue is full, so the next Enqueue blocks until the worker is released.
+	time.AfterFunc(50*time.Millisecond, func() { close(p.release) })
+	_ = wp.Enqueue(newTask("c"))
+	wp.Stop()
+	if err := <-errCh; err != nil {
+		t.Fatalf("Start() unexpected error: %v", err)
+	}
+
+	if len(reports) != 1 {
+		t.Fatalf("got %d backpressure reports, want 1", len(reports))
+	}
+	stats := wp.Stats()
+	if stats.MaxEnqueueWait < 20*time.Millisecond || stats.EnqueueWait < stats.MaxEnqueueWait {
+		t.Fatalf("unexpected enqueue wait in %+v", stats)
+	}
+	if stats.MaxQueueDepth != 1 {
+		t.Fatalf("MaxQueueDepth = %d, want 1", stats.MaxQueueDepth)
+	}
+}
+
+func TestWorkerPoolSubmit(t *testing.T) {
+	t.Run("workers returned", func(t *testing.T) {
+		wp := newPool[string, string](&errorProcessor{}, withPoolConcurrency(1), withPoolQueueSize(1))
+		go func() {
+			for range wp.Results() {
+			}
+		}()
+		errCh := make(chan error, 1)
+		go func() { errCh <- wp.Start(t.Context()) }()
+
+		if err := wp.Submit(t.Context(), newTask("fail")); err != nil {
+			t.Fatalf("Submit() unexpected error: %v", err)
+		}
+		if err := <-errCh; err == nil {
+			t.Fatal("expected error from Start, got nil")
+		}
+		// nobody takes tasks from the queue anymore.
+		if err := wp.Submit(t.Context(), newTask("ok")); !errors.Is(err, errPoolStopped) {
+			t.Fatalf("Submit() error = %v, want %v", err, errPoolStopped)
+		}
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		wp := newPool[string, string](&testProcessor{}, withPoolQueueSize(1))
+		if err := wp.Submit(t.Context(), newTask("queued")); err != nil {
+			t.Fatalf("Submit() unexpected error: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+		if err := wp.Submit(ctx, newTask("full")); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Submit() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		wp := newPool[string, string](&testProcessor{}, withPoolQueueSize(1))
+		if err := wp.Submit(t.Context(), newTask("queued")); err != nil {
+			t.Fatalf("Submit() unexpected error: %v", err)
+		}
+
+		// a sender blocked on the full queue is woken up by Stop.
+		blocked := make(chan error, 1)
+		go func() { blocked <- wp.Submit(t.Context(), newTask("blocked")) }()
+		time.Sleep(10 * time.Millisecond)
+
+		wp.Stop()
+		if err := <-blocked; !errors.Is(err, errPoolStopped) {
+			t.Fatalf("blocked Submit() error = %v, want %v", err, errPoolStopped)
+		}
+		if err := wp.Submit(t.Context(), newTask("late")); !errors.Is(err, errPoolStopped) {
+			t.Fatalf("Submit() after Stop error = %v, want %v", err, errPoolStopped)
+		}
+		if err := wp.Enqueue(newTask("late")); !errors.Is(err, errPoolStopped) {
+			t.Fatalf("Enqueue() after Stop error = %v, want %v", err, errPoolStopped)
+		}
+	})
+}
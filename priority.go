@@ -0,0 +1,77 @@
+package maptiler
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// Priority is the class of an ingest, see WithPriority. It decides the order
+// of the files of CreateAll and the share of the part concurrency of the
+// Client an ingest uses.
+type Priority string
+
+const (
+	// PriorityCritical ingests run first, e.g. for a hotfix of a dataset.
+	PriorityCritical Priority = "critical"
+	// PriorityNormal is the class of ingests without a priority.
+	PriorityNormal Priority = "normal"
+	// PriorityBulk ingests run last and upload a quarter of the parts at the
+	// same time, leaving room for other ingests of the Client, e.g. for a
+	// backfill.
+	PriorityBulk Priority = "bulk"
+)
+
+// ParsePriority returns the Priority named s.
+func ParsePriority(s string) (Priority, error) {
+	switch p := Priority(s); p {
+	case PriorityCritical, PriorityNormal, PriorityBulk:
+		return p, nil
+	default:
+		return "", fmt.Errorf("parsing priority: unknown class %q, expected critical, normal or bulk", s)
+	}
+}
+
+// rank orders the classes, lower ranks run first.
+func (p Priority) rank() int {
+	switch p {
+	case PriorityCritical:
+		return 0
+	case PriorityBulk:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// concurrency returns the share of n parts at the same time for the class.
+func (p Priority) concurrency(n int) int {
+	if p == PriorityBulk {
+		return max(1, n/4)
+	}
+	return n
+}
+
+// priorityOf returns the class of the file at fp.
+func (cfg ingestConfig) priorityOf(fp string) Priority {
+	if p, ok := cfg.filePriority[fp]; ok {
+		return p
+	}
+	if cfg.priority != "" {
+		return cfg.priority
+	}
+	return PriorityNormal
+}
+
+// byPriority returns the positions of fps in the order they are ingested in,
+// by class and in the order of fps within a class.
+func byPriority(fps []string, cfg ingestConfig) []int {
+	order := make([]int, len(fps))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(cfg.priorityOf(fps[a]).rank(), cfg.priorityOf(fps[b]).rank())
+	})
+	return order
+}
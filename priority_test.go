@@ -0,0 +1,122 @@
+package maptiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestParsePriority(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"critical", "normal", "bulk"} {
+		if p, err := ParsePriority(s); err != nil || string(p) != s {
+			t.Errorf("ParsePriority(%q) = %q, %v", s, p, err)
+		}
+	}
+	if _, err := ParsePriority("urgent"); err == nil {
+		t.Error("ParsePriority(urgent) expected an error")
+	}
+}
+
+func TestPriorityConcurrency(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		p    Priority
+		n    int
+		want int
+	}{
+		{"", 8, 8},
+		{PriorityCritical, 8, 8},
+		{PriorityNormal, 8, 8},
+		{PriorityBulk, 8, 2},
+		{PriorityBulk, 2, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.p.concurrency(tt.n); got != tt.want {
+			t.Errorf("%q.concurrency(%d) = %d, want %d", tt.p, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCreateAllByPriority(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	names := []string{"backfill.pmtiles", "daily.pmtiles", "hotfix.pmtiles", "other.pmtiles"}
+	fps := make([]string, len(names))
+	for i, name := range names {
+		fps[i] = filepath.Join(dir, name)
+		if err := os.WriteFile(fps[i], []byte("0123456789"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := c.CreateAll(t.Context(), fps,
+		WithPriority(PriorityBulk),
+		WithFilePriorities(map[string]Priority{
+			fps[1]: PriorityNormal,
+			fps[2]: PriorityCritical,
+		}),
+	)
+	if err != nil {
+		t.Fatalf("CreateAll() unexpected error: %v", err)
+	}
+
+	// the fake server numbers ingests in the order they are created.
+	want := []string{"ingest-3", "ingest-2", "ingest-1", "ingest-4"}
+	for i, ir := range got {
+		if ir.Filename != names[i] {
+			t.Errorf("response %d is for %s, want %s", i, ir.Filename, names[i])
+		}
+		if ir.ID != want[i] {
+			t.Errorf("%s created as %s, want %s", names[i], ir.ID, want[i])
+		}
+	}
+}
+
+func TestCreateAllSameFileTwice(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var first, last Progress
+	_, err = c.CreateAll(t.Context(), []string{fp, fp}, WithProgress(func(p Progress) {
+		if first.BytesTotal == 0 {
+			first = p
+		}
+		last = p
+	}))
+	if err != nil {
+		t.Fatalf("CreateAll() unexpected error: %v", err)
+	}
+	// both ingests of the file count towards the totals.
+	if first.BytesTotal != 20 {
+		t.Fatalf("BytesTotal = %d at the start, want 20", first.BytesTotal)
+	}
+	if last.Phase != PhaseDone || last.BytesDone != 20 {
+		t.Fatalf("unexpected final progress %+v", last)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !unix
+
+package maptiler
+
+import "os"
+
+// processAlive reports whether a process with the given pid is running.
+// On windows FindProcess fails for processes that do not exist, other
+// platforms are assumed to run the process.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
@@ -4,70 +4,238 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
+	"net/http"
 	"os"
-
-	"github.com/iwpnd/rip"
+	"slices"
+	"time"
 )
 
-// processor defines the interface for processing a task.
-type processor[T any] interface {
-	Process(ctx context.Context, t task[T]) error
+// processor defines the interface for processing a task into a result.
+type processor[In, Out any] interface {
+	Process(ctx context.Context, t task[In]) (Out, error)
 	Close()
 }
 
-func newUploadProcessor(h *rip.Client) processor[uploadTask] {
+func newUploadProcessor(h transport, config *clientConfig) processor[uploadTask, uploadTaskResponse] {
 	return &uploadProcessor{
-		h: h,
+		h:          h,
+		extract:    config.etagExtractor,
+		normalize:  config.etagNormalizer,
+		retryDelay: partRetryDelay,
+		debug:      config.uploadDebug,
+		headers:    config.partHeaders,
+		shared:     newFairSemaphore(config.shared),
+		limit:      newBandwidthLimiter(config.sharedBPS),
+		log:        config.logger,
 	}
 }
 
 type uploadProcessor struct {
-	h *rip.Client
+	h          transport
+	extract    ETagExtractor
+	normalize  ETagNormalizer
+	retryDelay time.Duration
+	debug      bool
+	headers    []string
+	// shared and limit are shared by the parts of all ingests of the Client.
+	shared *fairSemaphore
+	limit  *bandwidthLimiter
+	log    *slog.Logger
 }
 
-func (u *uploadProcessor) Process(ctx context.Context, t task[uploadTask]) error {
+// DefaultPartResponseHeaders are the response headers of part uploads kept in
+// PartStats: the request IDs of S3 and the timing reported by the target.
+var DefaultPartResponseHeaders = []string{"X-Amz-Request-Id", "X-Amz-Id-2", "Server-Timing"}
+
+func (u *uploadProcessor) Process(ctx context.Context, t task[uploadTask]) (uploadTaskResponse, error) {
 	if err := ctx.Err(); err != nil {
-		return fmt.Errorf("processing upload: %w", err)
+		return uploadTaskResponse{}, fmt.Errorf("processing upload: %w", err)
+	}
+
+	host := hostOf(t.Body.URL)
+	policy := t.Body.Retry
+	if policy == nil {
+		policy = FixedDelay{Attempts: t.Body.Retries, Delay: u.retryDelay}
+	}
+
+	var retryStart time.Time
+	for attempt := 0; ; attempt++ {
+		if err := t.Body.Control.wait(ctx); err != nil {
+			return uploadTaskResponse{}, fmt.Errorf("sending part %d: %w", t.Body.PartID, err)
+		}
+		if err := t.Body.Breaker.allow(host); err != nil {
+			return uploadTaskResponse{}, fmt.Errorf("sending part %d: %w", t.Body.PartID, err)
+		}
+
+		if err := u.shared.acquire(ctx, t.Body.IngestID); err != nil {
+			return uploadTaskResponse{}, fmt.Errorf("sending part %d: %w", t.Body.PartID, err)
+		}
+		sent := time.Now()
+		etag, header, err := u.send(ctx, t.Body)
+		u.shared.release()
+		t.Body.Breaker.record(host, err)
+		if attempt > 0 {
+			t.Body.Budget.spend(time.Since(retryStart))
+		}
+		if err == nil {
+			r := uploadTaskResponse{
+				PartID:   t.Body.PartID,
+				ETag:     etag,
+				Duration: time.Since(sent),
+				Header:   u.selectHeaders(header),
+			}
+			u.logPart(ctx, t.Body.IngestID, r, attempt)
+			return r, nil
+		}
+
+		if ctx.Err() != nil {
+			return uploadTaskResponse{}, err
+		}
+		delay, ok := policy.Retry(attempt+1, err)
+		if !ok {
+			return uploadTaskResponse{}, err
+		}
+		delay = max(delay, retryAfter(err))
+		if berr := t.Body.Budget.take(err); berr != nil {
+			return uploadTaskResponse{}, fmt.Errorf("sending part %d: %w", t.Body.PartID, berr)
+		}
+
+		t.reportRetry(attempt+1, err)
+		retryStart = time.Now()
+		if err := sleep(ctx, delay); err != nil {
+			return uploadTaskResponse{}, fmt.Errorf("sending part %d: %w", t.Body.PartID, err)
+		}
 	}
+}
 
-	info, err := os.Stat(t.Body.FilePath)
+// sectionOpener is a reader of parts that streams a section more efficiently
+// than with ReadAt, e.g. a remote file with a single range request per part.
+type sectionOpener interface {
+	openSection(ctx context.Context, off, n int64) (io.ReadCloser, error)
+}
+
+// openPart opens the file at fp to read a part from.
+func openPart(fp string) (*os.File, error) {
+	info, err := os.Stat(fp)
 	if err == nil {
 		if info.IsDir() {
-			return fmt.Errorf("expected file %q to exist, but it is a directory", t.Body.FilePath)
+			return nil, fmt.Errorf("expected file %q to exist, but it is a directory: %w", fp, ErrInvalidFile)
 		}
 	}
 	if os.IsNotExist(err) {
-		return fmt.Errorf("expected file %q to exist, but it does not", t.Body.FilePath)
+		return nil, fmt.Errorf("expected file %q to exist, but it does not: %w", fp, ErrInvalidFile)
 	}
 
-	file, err := os.Open(t.Body.FilePath)
+	file, err := os.Open(fp)
 	if err != nil {
-		return fmt.Errorf("failed to open file at path '%s': %w", t.Body.FilePath, err)
+		return nil, fmt.Errorf("failed to open file at path '%s': %w", fp, err)
+	}
+	return file, nil
+}
+
+// send uploads a single part and returns its ETag and the response header.
+func (u *uploadProcessor) send(ctx context.Context, t uploadTask) (string, http.Header, error) {
+	r := t.Reader
+	if r == nil {
+		file, err := openPart(t.FilePath)
+		if err != nil {
+			return "", nil, err
+		}
+		defer file.Close() //nolint:errcheck
+		r = file
+	}
+	var body io.Reader = io.NewSectionReader(r, t.Offset, t.Length)
+	if s, ok := r.(sectionOpener); ok {
+		rc, err := s.openSection(ctx, t.Offset, t.Length)
+		if err != nil {
+			return "", nil, fmt.Errorf("reading part %d: %w", t.PartID, err)
+		}
+		defer rc.Close() //nolint:errcheck
+		body = rc
 	}
-	defer file.Close() //nolint:errcheck
 
-	part := io.NewSectionReader(file, t.Body.Offset, t.Body.Length)
-	resp, err := u.h.NR().SetBody(part).SetContentLength(t.Body.Length).Execute(ctx, "PUT", t.Body.URL)
+	part := &countingReader{
+		r: &limitedReader{
+			ctx:      ctx,
+			r:        body,
+			limiters: []*bandwidthLimiter{u.limit, t.Limit, newBandwidthLimiter(t.PartLimit)},
+		},
+		fn: func(n int64) {
+			t.Progress.partProgress(t.PartID, n)
+		},
+	}
+	var req *PartRequest
+	if u.debug {
+		ctx, req = withPartRequest(ctx)
+	}
+	resp, err := u.h.do(ctx, request{method: http.MethodPut, path: t.URL, body: part, length: t.Length})
 	if err != nil {
-		return fmt.Errorf("sending part %d: %w", t.Body.PartID, err)
+		return "", nil, fmt.Errorf("sending part %d: %w", t.PartID, err)
 	}
-	defer resp.Close() //nolint:errcheck
+	defer resp.Body.Close() //nolint:errcheck
 
-	if resp.IsError() {
-		return fmt.Errorf("sending part %d: %w", t.Body.PartID, err)
+	if isError(resp) {
+		aerr := newAPIError(resp)
+		aerr.S3 = parseS3Error(resp.Header, aerr.Body)
+		if req != nil && req.Method != "" {
+			aerr.Request = req
+		}
+		return "", nil, fmt.Errorf("sending part %d: %w", t.PartID, aerr)
 	}
 
-	etag := resp.Header().Clone().Get("ETag")
+	etag, err := u.etag(resp)
+	if err != nil {
+		return "", nil, fmt.Errorf("sending part %d: extracting etag: %w", t.PartID, err)
+	}
 	if etag == "" {
-		return fmt.Errorf("empty etag in response header")
+		return "", nil, fmt.Errorf("sending part %d: %w", t.PartID, ErrEmptyETag)
+	}
+	if u.normalize != nil {
+		if etag, err = u.normalize(etag); err != nil {
+			return "", nil, fmt.Errorf("sending part %d: normalizing etag: %w", t.PartID, err)
+		}
 	}
 
-	t.Body.RespCh <- uploadTaskResponse{
-		PartID: t.Body.PartID,
-		ETag:   etag,
+	return etag, resp.Header, nil
+}
+
+// etag extracts the part identifier from a part upload response.
+func (u *uploadProcessor) etag(resp *http.Response) (string, error) {
+	if u.extract == nil {
+		return resp.Header.Get("ETag"), nil
 	}
+	return u.extract(resp)
+}
 
-	return err
+// selectHeaders returns the values of the configured headers that are set.
+func (u *uploadProcessor) selectHeaders(h http.Header) map[string]string {
+	var selected map[string]string
+	for _, name := range u.headers {
+		v := h.Get(name)
+		if v == "" {
+			continue
+		}
+		if selected == nil {
+			selected = make(map[string]string, len(u.headers))
+		}
+		selected[http.CanonicalHeaderKey(name)] = v
+	}
+	return selected
+}
+
+// logPart logs an uploaded part with its selected headers at debug level.
+func (u *uploadProcessor) logPart(ctx context.Context, id string, r uploadTaskResponse, retries int) {
+	log := logger(u.log)
+	if !log.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	attrs := []any{"ingest_id", id, "part_id", r.PartID, "duration", r.Duration, "retries", retries}
+	for _, k := range slices.Sorted(maps.Keys(r.Header)) {
+		attrs = append(attrs, k, r.Header[k])
+	}
+	log.DebugContext(ctx, "part uploaded", attrs...)
 }
 
 func (*uploadProcessor) Close() {}
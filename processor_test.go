@@ -0,0 +1,257 @@
+package maptiler
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestUploadProcessor(t *testing.T) *uploadProcessor {
+	t.Helper()
+
+	h, err := newRipTransport(transportConfig{})
+	if err != nil {
+		t.Fatalf("creating http client: %v", err)
+	}
+	return &uploadProcessor{h: h, normalize: NormalizeETag, retryDelay: time.Millisecond}
+}
+
+func writeTestFile(t *testing.T, data []byte) string {
+	t.Helper()
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return fp
+}
+
+func TestUploadProcessorRetriesTransientFailures(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) < 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("ETag", `"etag-1"`)
+	}))
+	defer srv.Close()
+
+	fp := writeTestFile(t, []byte("abcdefghij"))
+	budget := newRetryBudget(0, 0)
+
+	proc := newTestUploadProcessor(t)
+	got, err := proc.Process(t.Context(), newTask(uploadTask{
+		uploadPart: uploadPart{PartID: 1, URL: srv.URL},
+		FilePath:   fp,
+		Length:     10,
+		Retries:    3,
+		Budget:     budget,
+	}))
+	if err != nil {
+		t.Fatalf("Process() unexpected error: %v", err)
+	}
+	if got.ETag != `"etag-1"` {
+		t.Fatalf("etag=%q want %q", got.ETag, `"etag-1"`)
+	}
+	if retries, _ := budget.consumed(); retries != 2 {
+		t.Fatalf("retries=%d want 2", retries)
+	}
+}
+
+func TestUploadProcessorDoesNotRetryClientErrors(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		http.Error(w, "expired", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	proc := newTestUploadProcessor(t)
+	_, err := proc.Process(t.Context(), newTask(uploadTask{
+		uploadPart: uploadPart{PartID: 1, URL: srv.URL},
+		FilePath:   writeTestFile(t, []byte("abc")),
+		Length:     3,
+		Retries:    3,
+	}))
+
+	var aerr APIError
+	if !errors.As(err, &aerr) || aerr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected APIError 403, got %v", err)
+	}
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Fatalf("expected a single attempt, got %d", hits)
+	}
+}
+
+func TestUploadProcessorRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	proc := newTestUploadProcessor(t)
+	_, err := proc.Process(t.Context(), newTask(uploadTask{
+		uploadPart: uploadPart{PartID: 1, URL: srv.URL},
+		FilePath:   writeTestFile(t, []byte("abc")),
+		Length:     3,
+		// the policy replaces Retries.
+		Retries: 5,
+		Retry:   ExponentialBackoff{Attempts: 2, Base: time.Millisecond},
+	}))
+	if !errors.As(err, new(APIError)) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 3 {
+		t.Fatalf("requests=%d want 3", got)
+	}
+}
+
+func TestUploadProcessorRetryBudgetExhausted(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	fp := writeTestFile(t, []byte("abc"))
+	budget := newRetryBudget(2, 0)
+	proc := newTestUploadProcessor(t)
+
+	var lastErr error
+	for i := range 3 {
+		_, lastErr = proc.Process(t.Context(), newTask(uploadTask{
+			uploadPart: uploadPart{PartID: int64(i + 1), URL: srv.URL},
+			FilePath:   fp,
+			Length:     3,
+			Retries:    5,
+			Budget:     budget,
+		}))
+	}
+
+	var berr RetryBudgetExhaustedError
+	if !errors.As(lastErr, &berr) {
+		t.Fatalf("expected RetryBudgetExhaustedError, got %v", lastErr)
+	}
+	if berr.Retries != 2 {
+		t.Fatalf("consumed retries=%d want 2", berr.Retries)
+	}
+	if !errors.As(lastErr, new(APIError)) {
+		t.Fatalf("expected the last part error to be wrapped, got %v", lastErr)
+	}
+}
+
+func TestUploadProcessorCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		http.Error(w, "slow down", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	fp := writeTestFile(t, []byte("abc"))
+	breaker := newCircuitBreaker(3)
+	proc := newTestUploadProcessor(t)
+
+	_, err := proc.Process(t.Context(), newTask(uploadTask{
+		uploadPart: uploadPart{PartID: 1, URL: srv.URL},
+		FilePath:   fp,
+		Length:     3,
+		Retries:    10,
+		Breaker:    breaker,
+	}))
+
+	var cerr CircuitOpenError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("expected CircuitOpenError, got %v", err)
+	}
+	if cerr.Failures != 3 {
+		t.Fatalf("failures=%d want 3", cerr.Failures)
+	}
+
+	// further parts to the same host fail without a request.
+	_, _ = proc.Process(t.Context(), newTask(uploadTask{
+		uploadPart: uploadPart{PartID: 2, URL: srv.URL},
+		FilePath:   fp,
+		Length:     3,
+		Breaker:    breaker,
+	}))
+	if got := atomic.LoadInt32(&hits); got != 3 {
+		t.Fatalf("expected 3 requests before the circuit opened, got %d", got)
+	}
+}
+
+func TestUploadProcessorETagExtractor(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Part-Id", "part-abc")
+		_, _ = w.Write([]byte(`{"etag":"ignored"}`))
+	}))
+	defer srv.Close()
+
+	proc := newTestUploadProcessor(t)
+	proc.extract = func(resp *http.Response) (string, error) {
+		return resp.Header.Get("X-Part-Id"), nil
+	}
+
+	got, err := proc.Process(t.Context(), newTask(uploadTask{
+		uploadPart: uploadPart{PartID: 1, URL: srv.URL},
+		FilePath:   writeTestFile(t, []byte("abc")),
+		Length:     3,
+	}))
+	if err != nil {
+		t.Fatalf("Process() unexpected error: %v", err)
+	}
+	if got.ETag != `"part-abc"` {
+		t.Fatalf("etag=%q want %q", got.ETag, `"part-abc"`)
+	}
+}
+
+func TestUploadProcessorResponseHeaders(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"etag-1"`)
+		w.Header().Set("X-Amz-Request-Id", "req-1")
+		w.Header().Set("Server-Timing", "storage;dur=12")
+		w.Header().Set("X-Other", "dropped")
+	}))
+	defer srv.Close()
+
+	proc := newTestUploadProcessor(t)
+	proc.headers = DefaultPartResponseHeaders
+
+	got, err := proc.Process(t.Context(), newTask(uploadTask{
+		uploadPart: uploadPart{PartID: 1, URL: srv.URL},
+		FilePath:   writeTestFile(t, []byte("abc")),
+		Length:     3,
+	}))
+	if err != nil {
+		t.Fatalf("Process() unexpected error: %v", err)
+	}
+	want := map[string]string{"X-Amz-Request-Id": "req-1", "Server-Timing": "storage;dur=12"}
+	if !maps.Equal(got.Header, want) {
+		t.Fatalf("header=%v want %v", got.Header, want)
+	}
+	if got.Duration <= 0 {
+		t.Fatalf("expected a duration, got %s", got.Duration)
+	}
+}
@@ -0,0 +1,213 @@
+package maptiler
+
+import (
+	"cmp"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Phase describes the step of the ingestion workflow an ingest is in.
+type Phase string
+
+const (
+	PhaseIngest   Phase = "ingest"
+	PhaseUpload   Phase = "upload"
+	PhaseFinalize Phase = "finalize"
+	PhaseDone     Phase = "done"
+)
+
+// progressInterval limits how often byte level progress of in-flight parts is reported.
+const progressInterval = 100 * time.Millisecond
+
+// Progress is a point in time snapshot of an ingest.
+type Progress struct {
+	IngestID   string `json:"ingest_id"`
+	Phase      Phase  `json:"phase"`
+	PartsTotal int    `json:"parts_total"`
+	PartsDone  int    `json:"parts_done"`
+	BytesTotal int64  `json:"bytes_total"`
+	// BytesDone is the number of bytes of completed parts.
+	BytesDone int64 `json:"bytes_done"`
+	// BytesInFlight is the number of bytes sent for parts that are not completed yet.
+	BytesInFlight int64 `json:"bytes_in_flight"`
+	// InFlight lists the parts currently being uploaded, ordered by part ID.
+	InFlight []PartProgress `json:"in_flight,omitempty"`
+	// ETA is the estimated time until all bytes are sent, if known.
+	ETA time.Duration `json:"eta,omitempty"`
+}
+
+// Percent returns the share of bytes sent, including bytes of parts in flight,
+// between 0 and 100.
+func (p Progress) Percent() float64 {
+	if p.BytesTotal <= 0 {
+		if p.Phase == PhaseDone {
+			return 100
+		}
+		return 0
+	}
+	return min(100, float64(p.BytesDone+p.BytesInFlight)/float64(p.BytesTotal)*100)
+}
+
+// PartProgress is the progress of a single part upload.
+type PartProgress struct {
+	PartID    int64 `json:"part_id"`
+	BytesSent int64 `json:"bytes_sent"`
+	Length    int64 `json:"length"`
+}
+
+// ProgressFunc receives progress updates during an ingest. It is called synchronously
+// from the upload path and should return quickly.
+type ProgressFunc func(Progress)
+
+func (p Progress) String() string     { return toJSONString(p) }
+func (p PartProgress) String() string { return toJSONString(p) }
+
+// progressTracker keeps track of an ingests progress and reports every change
+// to a ProgressFunc. A nil tracker is valid and reports nothing.
+type progressTracker struct {
+	mu       sync.Mutex
+	fn       ProgressFunc
+	p        Progress
+	inflight map[int64]PartProgress
+	last     time.Time
+	rate     rateEstimator
+}
+
+func newProgressTracker(fn ProgressFunc) *progressTracker {
+	if fn == nil {
+		return nil
+	}
+	return &progressTracker{
+		fn:       fn,
+		inflight: make(map[int64]PartProgress),
+		rate:     rateEstimator{tau: DefaultRateWindow},
+	}
+}
+
+// start sets the totals of the ingest and moves it to the upload phase.
+func (t *progressTracker) start(id string, parts int, size int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.p = Progress{
+		IngestID:   id,
+		Phase:      PhaseUpload,
+		PartsTotal: parts,
+		BytesTotal: size,
+	}
+	t.report()
+}
+
+// partStart marks a part as in flight, resetting the bytes sent of a previous attempt.
+func (t *progressTracker) partStart(id, length int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inflight[id] = PartProgress{PartID: id, Length: length}
+}
+
+// partProgress records n more bytes sent for an in-flight part. Reports are
+// throttled to progressInterval.
+func (t *progressTracker) partProgress(id, n int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	pp, ok := t.inflight[id]
+	if !ok {
+		return
+	}
+	pp.BytesSent += n
+	t.inflight[id] = pp
+	if time.Since(t.last) >= progressInterval {
+		t.report()
+	}
+}
+
+// partDone records a successfully uploaded part of length n.
+func (t *progressTracker) partDone(id, n int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.inflight, id)
+	t.p.PartsDone++
+	t.p.BytesDone += n
+	t.report()
+}
+
+// phase moves the ingest to the given phase.
+func (t *progressTracker) phase(ph Phase) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.p.Phase = ph
+	t.report()
+}
+
+// report sends a snapshot to the ProgressFunc, t.mu must be held.
+func (t *progressTracker) report() {
+	p := t.p
+	p.BytesInFlight = 0
+	p.InFlight = nil
+	if len(t.inflight) > 0 {
+		// a report is sent for every finished part, so it allocates only the
+		// slice handed to the ProgressFunc.
+		p.InFlight = make([]PartProgress, 0, len(t.inflight))
+		for _, pp := range t.inflight {
+			p.BytesInFlight += pp.BytesSent
+			p.InFlight = append(p.InFlight, pp)
+		}
+		slices.SortFunc(p.InFlight, func(a, b PartProgress) int {
+			return cmp.Compare(a.PartID, b.PartID)
+		})
+	}
+	t.last = time.Now()
+	if p.Phase == PhaseUpload {
+		sent := p.BytesDone + p.BytesInFlight
+		t.rate.add(t.last, sent)
+		p.ETA = etaOf(p.BytesTotal, sent, t.rate.rate())
+	}
+	t.fn(p)
+}
+
+// partProgressListener tracks the parts of an upload from their task lifecycle.
+// A retried part starts over, so the bytes sent by the failed attempt are dropped.
+func partProgressListener(t *progressTracker) taskListener[uploadTask] {
+	if t == nil {
+		return nil
+	}
+	return taskListenerFunc[uploadTask](func(e taskEvent[uploadTask]) {
+		switch e.Kind {
+		case taskStarted, taskRetried:
+			t.partStart(e.Task.Body.PartID, e.Task.Body.Length)
+		case taskFinished:
+			t.partDone(e.Task.Body.PartID, e.Task.Body.Length)
+		case taskEnqueued, taskFailed:
+		}
+	})
+}
+
+// countingReader reports the number of bytes read from r to fn.
+type countingReader struct {
+	r  io.Reader
+	fn func(n int64)
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.fn(int64(n))
+	}
+	return n, err
+}
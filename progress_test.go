@@ -0,0 +1,70 @@
+package maptiler
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressTrackerInFlight(t *testing.T) {
+	t.Parallel()
+
+	var events []Progress
+	pt := newProgressTracker(func(p Progress) { events = append(events, p) })
+
+	pt.start("ing-1", 2, 30)
+	pt.partStart(2, 10)
+	pt.partStart(1, 20)
+
+	// drop the throttle so in-flight progress is reported immediately.
+	pt.last = time.Time{}
+	pt.partProgress(1, 5)
+	pt.last = time.Time{}
+	pt.partProgress(2, 3)
+
+	got := events[len(events)-1]
+	if got.BytesInFlight != 8 || len(got.InFlight) != 2 {
+		t.Fatalf("unexpected in-flight progress: %+v", got)
+	}
+	if got.InFlight[0].PartID != 1 || got.InFlight[0].BytesSent != 5 || got.InFlight[0].Length != 20 {
+		t.Fatalf("expected in-flight parts ordered by id, got %+v", got.InFlight)
+	}
+
+	// throttled, no new event.
+	n := len(events)
+	pt.partProgress(1, 5)
+	if len(events) != n {
+		t.Fatalf("expected in-flight progress to be throttled")
+	}
+
+	pt.partDone(1, 20)
+	got = events[len(events)-1]
+	if got.PartsDone != 1 || got.BytesDone != 20 || got.BytesInFlight != 3 {
+		t.Fatalf("unexpected progress after part done: %+v", got)
+	}
+}
+
+func TestCountingReader(t *testing.T) {
+	t.Parallel()
+
+	var total int64
+	r := &countingReader{r: strings.NewReader("abcdefghij"), fn: func(n int64) { total += n }}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	if total != 10 {
+		t.Fatalf("counted %d bytes, want 10", total)
+	}
+}
+
+func TestNilProgressTracker(t *testing.T) {
+	t.Parallel()
+
+	var pt *progressTracker
+	pt.start("ing-1", 1, 1)
+	pt.partStart(1, 1)
+	pt.partProgress(1, 1)
+	pt.partDone(1, 1)
+	pt.phase(PhaseDone)
+}
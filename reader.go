@@ -0,0 +1,46 @@
+package maptiler
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// CreateFrom creates a new dataset named name from size bytes of r, e.g. a
+// dataset generated in memory or an object in a bucket, without writing it to
+// a file first. Parts are read concurrently with ReadAt. Of the file checks of
+// opts only WithAllowedExtensions, applied to name, and WithMaxSize apply, and
+// an ingest from r can not be journaled.
+func (c *Client) CreateFrom(ctx context.Context, name string, size int64, r io.ReaderAt, opts ...IngestOption) (IngestResponse, error) {
+	cfg := newIngestConfig(opts...)
+	cfg.reader = r
+	run := func(ctx context.Context, _, name string, cfg ingestConfig) (IngestResponse, error) {
+		return c.processReader(ctx, name, size, cfg)
+	}
+	return c.withCancel(ctx, run, "", name, cfg)
+}
+
+// processReader creates a dataset from the reader of cfg like process does
+// from a file.
+func (c *Client) processReader(ctx context.Context, name string, size int64, cfg ingestConfig) (IngestResponse, error) {
+	if cfg.reader == nil || size <= 0 {
+		return IngestResponse{}, fmt.Errorf("expected %q to have content, got %d bytes: %w", name, size, ErrInvalidFile)
+	}
+	if err := checkGuardrails(name, size, cfg); err != nil {
+		return IngestResponse{}, err
+	}
+
+	name = cfg.filename(name)
+	req := newIngestRequest("", name, size)
+	req.Metadata = c.metadataOf(cfg)
+	req.PartSize = cfg.partSize
+	resp, err := c.ingest(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	logger(c.log).Debug("ingest created",
+		"ingest_id", resp.ID, "dataset_id", resp.DocumentID, "filename", name, "size", resp.Size,
+		"parts", len(resp.Upload.Parts), "part_size", resp.Upload.PartSize,
+	)
+	return c.finish(ctx, resp, "", cfg, nil, nil)
+}
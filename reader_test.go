@@ -0,0 +1,68 @@
+package maptiler
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestCreateFrom(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	data := []byte("0123456789")
+	ir, err := c.CreateFrom(t.Context(), "generated.geojson", int64(len(data)), bytes.NewReader(data),
+		WithAllowedExtensions(SupportedExtensions()...),
+	)
+	if err != nil {
+		t.Fatalf("CreateFrom() unexpected error: %v", err)
+	}
+	if ir.State != StateCompleted || ir.Filename != "generated.geojson" {
+		t.Errorf("CreateFrom() = %s %s, want generated.geojson completed", ir.Filename, ir.State)
+	}
+	if ir.Stats.Parts != 3 {
+		t.Errorf("uploaded %d parts, want 3", ir.Stats.Parts)
+	}
+
+	// a reader shorter than size fails the ingest, which is canceled.
+	_, err = c.CreateFrom(t.Context(), "short.geojson", 12, strings.NewReader("0123"))
+	var uerr UploadFailedError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("CreateFrom() error = %v, want UploadFailedError", err)
+	}
+	if got := IngestState(srv.State(uerr.ID)); got != StateCanceled {
+		t.Errorf("ingest is %q, want it to be canceled", got)
+	}
+}
+
+func TestCreateFromGuardrails(t *testing.T) {
+	t.Parallel()
+
+	c := &Client{}
+	tests := []struct {
+		name string
+		size int64
+		opts []IngestOption
+		want error
+	}{
+		{name: "empty.geojson", size: 0, want: ErrInvalidFile},
+		{name: "notes.txt", size: 10, opts: []IngestOption{WithAllowedExtensions(SupportedExtensions()...)}, want: ErrUnsupportedExtension},
+		{name: "big.geojson", size: 10, opts: []IngestOption{WithMaxSize(5)}, want: ErrFileTooLarge},
+	}
+	for _, tt := range tests {
+		_, err := c.CreateFrom(t.Context(), tt.name, tt.size, strings.NewReader("0123456789"), tt.opts...)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("CreateFrom(%s) error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
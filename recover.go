@@ -0,0 +1,73 @@
+package maptiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// RecoveryAction is what Recover did with the last ingest of a dataset.
+type RecoveryAction string
+
+const (
+	// RecoveryNone means there was no stale ingest to recover.
+	RecoveryNone RecoveryAction = "none"
+	// RecoveryCanceled means a stale ingest was canceled.
+	RecoveryCanceled RecoveryAction = "canceled"
+)
+
+// Recovery is the outcome of Recover.
+type Recovery struct {
+	DatasetID string         `json:"dataset_id"`
+	IngestID  string         `json:"ingest_id,omitempty"`
+	State     IngestState    `json:"state,omitempty"`
+	Action    RecoveryAction `json:"action"`
+}
+
+func (r Recovery) String() string { return toJSONString(r) }
+
+// Recover cleans up after an update of datasetID that was interrupted, e.g. by a
+// crash. If the last ingest recorded for the dataset (see WithConflictCheck) is
+// still in the upload state, it is canceled so the dataset can be updated again.
+// Recover fails with ErrIngestInProgress while an update holds the dataset lock
+// (see WithDatasetLock), updates without the lock are not detected.
+func (c *Client) Recover(ctx context.Context, datasetID string) (Recovery, error) {
+	r := Recovery{DatasetID: datasetID, Action: RecoveryNone}
+
+	last, err := lastIngest(c.lockDir, datasetID)
+	if err != nil || last == "" {
+		return r, err
+	}
+	r.IngestID = last
+
+	// an update that is still running with WithDatasetLock holds the lock.
+	unlock, err := lockDataset(ctx, c.lockDir, datasetID, 0)
+	switch {
+	case err == nil:
+		defer unlock() //nolint:errcheck
+	case !errors.Is(err, errors.ErrUnsupported):
+		return r, err
+	}
+
+	ir, err := c.Get(ctx, last)
+	var aerr APIError
+	if errors.As(err, &aerr) && aerr.StatusCode == http.StatusNotFound {
+		return r, nil
+	}
+	if err != nil {
+		return r, fmt.Errorf("recovering dataset %s: %w", datasetID, err)
+	}
+	r.State = ir.State
+	if ir.State != StateUpload {
+		return r, nil
+	}
+
+	cr, err := c.cancel(ctx, last)
+	if err != nil {
+		return r, fmt.Errorf("recovering dataset %s: %w", datasetID, err)
+	}
+	r.State = cr.State
+	r.Action = RecoveryCanceled
+	return r, nil
+}
@@ -0,0 +1,76 @@
+package maptiler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientRecover(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		recorded string
+		state    IngestState
+		want     Recovery
+	}{
+		{
+			name: "nothing recorded",
+			want: Recovery{DatasetID: "ds-1", Action: RecoveryNone},
+		},
+		{
+			name:     "stale upload",
+			recorded: "ing-1",
+			state:    StateUpload,
+			want:     Recovery{DatasetID: "ds-1", IngestID: "ing-1", State: StateCanceled, Action: RecoveryCanceled},
+		},
+		{
+			name:     "completed",
+			recorded: "ing-1",
+			state:    StateCompleted,
+			want:     Recovery{DatasetID: "ds-1", IngestID: "ing-1", State: StateCompleted, Action: RecoveryNone},
+		},
+		{
+			name:     "unknown ingest",
+			recorded: "ing-gone",
+			want:     Recovery{DatasetID: "ds-1", IngestID: "ing-gone", Action: RecoveryNone},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /datasets/ingest/ing-1", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"id":"ing-1","state":"` + string(tt.state) + `"}`))
+			})
+			mux.HandleFunc("POST /datasets/ingest/ing-1/cancel", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"id":"ing-1","state":"canceled"}`))
+			})
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			dir := t.TempDir()
+			if tt.recorded != "" {
+				if err := recordIngest(dir, "ds-1", tt.recorded); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c, err := New(srv.URL, "token", WithLockDir(dir))
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+
+			got, err := c.Recover(t.Context(), "ds-1")
+			if err != nil {
+				t.Fatalf("Recover() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Recover() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package maptiler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// ErrRedirectNotAllowed is returned for part uploads that are redirected in
+// violation of the RedirectPolicy of the Client.
+var ErrRedirectNotAllowed = errors.New("redirect not allowed")
+
+// RedirectPolicy decides whether part uploads follow redirects of the upload
+// target, see WithUploadRedirects.
+type RedirectPolicy int
+
+const (
+	// RedirectDeny fails parts that are redirected. It is the default, presigned
+	// URLs are not expected to redirect.
+	RedirectDeny RedirectPolicy = iota
+	// RedirectSameHost follows redirects to the host of the part URL only.
+	RedirectSameHost
+	// RedirectFollow follows every redirect, as net/http does by default.
+	RedirectFollow
+)
+
+func (p RedirectPolicy) String() string {
+	switch p {
+	case RedirectDeny:
+		return "deny"
+	case RedirectSameHost:
+		return "same-host"
+	case RedirectFollow:
+		return "follow"
+	default:
+		return fmt.Sprintf("RedirectPolicy(%d)", int(p))
+	}
+}
+
+// redirectTransport fails redirect responses the policy does not allow, before
+// the http.Client gets to follow them. Allowed redirects have to point to a
+// host of the upload allowlist as well. It also records requests for
+// WithUploadDebug, as it sees every request that is sent.
+type redirectTransport struct {
+	base   http.RoundTripper
+	policy RedirectPolicy
+	hosts  hostAllowlist
+	log    *slog.Logger
+}
+
+// newRedirectTransport wraps base, it is registered for http and https so it
+// can be passed where an *http.Transport is expected.
+func newRedirectTransport(base *http.Transport, policy RedirectPolicy, hosts hostAllowlist, log *slog.Logger) *http.Transport {
+	return asHTTPTransport(&redirectTransport{base: base, policy: policy, hosts: hosts, log: log})
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recordPartRequest(req)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || !isRedirect(resp.StatusCode) {
+		return resp, err
+	}
+	loc, err := resp.Location()
+	if err != nil {
+		// without a location the redirect is not followed anyway.
+		return resp, nil //nolint:nilerr
+	}
+
+	// the query of presigned URLs holds credentials and is never logged.
+	allowed := t.allows(req.URL.Host, loc.Host)
+	logger(t.log).Debug("part upload redirected",
+		"status", resp.StatusCode, "from", req.URL.Host, "to", loc.Host,
+		"policy", t.policy.String(), "followed", allowed,
+	)
+	if allowed {
+		return resp, nil
+	}
+
+	resp.Body.Close() //nolint:errcheck,gosec
+	return nil, fmt.Errorf("%d redirect from %s to %s: %w", resp.StatusCode, req.URL.Host, loc.Host, ErrRedirectNotAllowed)
+}
+
+func (t *redirectTransport) allows(from, to string) bool {
+	switch t.policy {
+	case RedirectSameHost:
+		return from == to
+	case RedirectFollow:
+		return t.hosts.allows(to)
+	default:
+		return false
+	}
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,79 @@
+package maptiler
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectTransport(t *testing.T) {
+	t.Parallel()
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("ETag", `"other"`)
+	}))
+	t.Cleanup(target.Close)
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(w, r, "/done", http.StatusTemporaryRedirect)
+		case "/other":
+			http.Redirect(w, r, target.URL+"/done", http.StatusTemporaryRedirect)
+		default:
+			w.Header().Set("ETag", `"same"`)
+		}
+	}))
+	t.Cleanup(origin.Close)
+
+	tests := []struct {
+		name    string
+		policy  RedirectPolicy
+		hosts   []string
+		path    string
+		wantErr bool
+	}{
+		{name: "no redirect", policy: RedirectDeny, path: "/done"},
+		{name: "deny", policy: RedirectDeny, path: "/same", wantErr: true},
+		{name: "same host", policy: RedirectSameHost, path: "/same"},
+		{name: "same host to other host", policy: RedirectSameHost, path: "/other", wantErr: true},
+		{name: "follow", policy: RedirectFollow, path: "/other"},
+		{name: "follow outside allowlist", policy: RedirectFollow, hosts: []string{origin.Listener.Addr().String()}, path: "/other", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			base, ok := http.DefaultTransport.(*http.Transport)
+			if !ok {
+				t.Fatal("unexpected default transport")
+			}
+			c := &http.Client{Transport: newRedirectTransport(base.Clone(), tt.policy, newHostAllowlist(tt.hosts), nil)}
+
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodPut, origin.URL+tt.path, bytes.NewReader([]byte("part")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.Do(req)
+			if tt.wantErr {
+				if !errors.Is(err, ErrRedirectNotAllowed) {
+					t.Fatalf("expected ErrRedirectNotAllowed, got %v", err)
+				}
+				if IsRetryable(err) {
+					t.Errorf("expected a denied redirect not to be retryable")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected 200, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
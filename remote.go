@@ -0,0 +1,111 @@
+package maptiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+)
+
+// ErrRangeNotSupported is returned by CreateFromURL for a source that does not
+// serve byte ranges.
+var ErrRangeNotSupported = errors.New("source does not support range requests")
+
+// CreateFromURL creates a new dataset from the file at srcURL, an HTTP(S) URL
+// e.g. of an artifact server, without downloading it first. Its size is the
+// Content-Length of a HEAD request, and every part is streamed from the source
+// with a range request while it is uploaded, so the source has to support
+// them. The dataset is named after the last element of the URL path, unless
+// WithFilename is given. The checks of opts apply as for CreateFrom.
+func (c *Client) CreateFromURL(ctx context.Context, srcURL string, opts ...IngestOption) (IngestResponse, error) {
+	src, err := c.openSource(ctx, srcURL)
+	if err != nil {
+		return IngestResponse{}, err
+	}
+	return c.CreateFrom(ctx, src.name, src.size, src, opts...)
+}
+
+// httpSource reads a remote file with range requests.
+type httpSource struct {
+	h *http.Client
+	// ctx is the context of CreateFromURL, for reads with ReadAt.
+	ctx  context.Context //nolint:containedctx
+	url  string
+	name string
+	size int64
+}
+
+// openSource asks srcURL for its size and whether it serves byte ranges.
+func (c *Client) openSource(ctx context.Context, srcURL string) (*httpSource, error) {
+	u, err := url.Parse(srcURL)
+	if err != nil {
+		return nil, fmt.Errorf("opening source: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("opening source %s: expected an http or https URL: %w", u.Redacted(), ErrInvalidFile)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, srcURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("opening source %s: %w", u.Redacted(), err)
+	}
+	resp, err := c.src.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("opening source %s: %w", u.Redacted(), err)
+	}
+	resp.Body.Close() //nolint:errcheck,gosec
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("opening source %s: unexpected status %s", u.Redacted(), resp.Status)
+	}
+	if resp.Header.Get("Accept-Ranges") != "bytes" {
+		return nil, fmt.Errorf("opening source %s: %w", u.Redacted(), ErrRangeNotSupported)
+	}
+	size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("opening source %s: missing Content-Length: %w", u.Redacted(), err)
+	}
+	return &httpSource{h: c.src, ctx: ctx, url: srcURL, name: path.Base(u.Path), size: size}, nil
+}
+
+// openSection requests n bytes of s at off.
+func (s *httpSource) openSection(ctx context.Context, off, n int64) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+n-1))
+	resp, err := s.h.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("reading source: %w", err)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close() //nolint:errcheck,gosec
+		if resp.StatusCode == http.StatusOK {
+			return nil, fmt.Errorf("reading source: %w", ErrRangeNotSupported)
+		}
+		return nil, fmt.Errorf("reading source: unexpected status %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// ReadAt reads len(p) bytes of s at off with a range request.
+func (s *httpSource) ReadAt(p []byte, off int64) (int, error) {
+	if off >= s.size {
+		return 0, io.EOF
+	}
+	n := min(int64(len(p)), s.size-off)
+	rc, err := s.openSection(s.ctx, off, n)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close() //nolint:errcheck
+	read, err := io.ReadFull(rc, p[:n])
+	if err == nil && n < int64(len(p)) {
+		err = io.EOF
+	}
+	return read, err
+}
@@ -0,0 +1,75 @@
+package maptiler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestCreateFromURL(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	data := []byte("0123456789")
+	var ranges atomic.Int32
+	artifacts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			ranges.Add(1)
+		}
+		http.ServeContent(w, r, "tiles.pmtiles", time.Time{}, bytes.NewReader(data))
+	}))
+	defer artifacts.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ir, err := c.CreateFromURL(t.Context(), artifacts.URL+"/builds/42/tiles.pmtiles")
+	if err != nil {
+		t.Fatalf("CreateFromURL() unexpected error: %v", err)
+	}
+	if ir.State != StateCompleted || ir.Filename != "tiles.pmtiles" || ir.Size != int64(len(data)) {
+		t.Errorf("CreateFromURL() = %s %s of %d bytes, want tiles.pmtiles completed", ir.Filename, ir.State, ir.Size)
+	}
+	if n := ranges.Load(); n != 3 {
+		t.Errorf("source got %d range requests, want one per part", n)
+	}
+
+	src, err := c.openSource(t.Context(), artifacts.URL+"/tiles.pmtiles")
+	if err != nil {
+		t.Fatalf("openSource() unexpected error: %v", err)
+	}
+	b, err := io.ReadAll(io.NewSectionReader(src, 6, 10))
+	if err != nil || string(b) != "6789" {
+		t.Errorf("ReadAt() = %q, %v, want 6789", b, err)
+	}
+}
+
+func TestCreateFromURLWithoutRanges(t *testing.T) {
+	t.Parallel()
+
+	artifacts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("0123456789"))
+	}))
+	defer artifacts.Close()
+
+	c, err := New("http://127.0.0.1:0", "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if _, err := c.CreateFromURL(t.Context(), artifacts.URL+"/tiles.pmtiles"); !errors.Is(err, ErrRangeNotSupported) {
+		t.Errorf("CreateFromURL() error = %v, want ErrRangeNotSupported", err)
+	}
+	if _, err := c.CreateFromURL(t.Context(), "file:///tmp/tiles.pmtiles"); !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("CreateFromURL() error = %v, want ErrInvalidFile", err)
+	}
+}
@@ -0,0 +1,89 @@
+package maptiler
+
+import (
+	"errors"
+	"time"
+)
+
+// Report summarizes a batch of ingests in a machine-readable form, e.g. to attach
+// to a CI run.
+type Report struct {
+	Created time.Time     `json:"created"`
+	Ingests []ReportEntry `json:"ingests"`
+}
+
+// ReportEntry is the outcome of a single file of a batch.
+type ReportEntry struct {
+	File          string      `json:"file"`
+	IngestID      string      `json:"ingest_id,omitempty"`
+	DatasetID     string      `json:"dataset_id,omitempty"`
+	State         IngestState `json:"state"`
+	BytesUploaded int64       `json:"bytes_uploaded"`
+	// Duration is the time spent uploading the parts of the file.
+	Duration   time.Duration `json:"duration"`
+	Retries    int           `json:"retries"`
+	TilesetURL string        `json:"tileset_url,omitempty"`
+	Warnings   []string      `json:"warnings,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+func (r Report) String() string { return toJSONString(r) }
+
+// NewReport builds the report of a batch from the files, the responses and the
+// error returned by CreateAll.
+func NewReport(fps []string, resps []IngestResponse, err error) Report {
+	failed := make(map[string]error)
+	for _, e := range unjoin(err) {
+		var ferr FileError
+		if errors.As(e, &ferr) {
+			failed[ferr.Path] = ferr.Err
+		}
+	}
+
+	r := Report{Created: time.Now().UTC(), Ingests: make([]ReportEntry, 0, len(fps))}
+	for i, fp := range fps {
+		var ir IngestResponse
+		if i < len(resps) {
+			ir = resps[i]
+		}
+		e := ReportEntry{
+			File:      fp,
+			IngestID:  ir.ID,
+			DatasetID: ir.DocumentID,
+			State:     ir.State,
+			Duration:  ir.Stats.Duration,
+			Retries:   ir.Stats.Retries,
+		}
+		for _, w := range ir.Warnings {
+			e.Warnings = append(e.Warnings, w.Message)
+		}
+		if ferr, ok := failed[fp]; ok {
+			e.Error = ferr.Error()
+			if e.State == "" {
+				e.State = StateFailed
+			}
+		} else if ir.ID != "" {
+			e.BytesUploaded = ir.Size
+		}
+		if e.State == "" {
+			// the batch stopped before the file was ingested.
+			e.State = StateCanceled
+		}
+		if e.DatasetID != "" && e.Error == "" {
+			e.TilesetURL = TilesetURL(e.DatasetID)
+		}
+		r.Ingests = append(r.Ingests, e)
+	}
+	return r
+}
+
+// unjoin returns the errors joined by errors.Join, or err itself.
+func unjoin(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if j, ok := err.(interface{ Unwrap() []error }); ok { //nolint:errorlint
+		return j.Unwrap()
+	}
+	return []error{err}
+}
@@ -0,0 +1,53 @@
+package maptiler
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewReport(t *testing.T) {
+	t.Parallel()
+
+	fps := []string{"a.pmtiles", "b.pmtiles", "c.pmtiles"}
+	resps := []IngestResponse{
+		{
+			ID:         "ing-a",
+			DocumentID: "ds-a",
+			State:      StateCompleted,
+			Size:       100,
+			Stats:      UploadStats{Retries: 2, Duration: time.Second},
+			Warnings:   []MapTilerError{{Message: "dropped 3 features with invalid geometry"}},
+		},
+		{},
+		{},
+	}
+	err := errors.Join(FileError{Path: "b.pmtiles", Err: ErrInvalidFile}, errors.New("context canceled"))
+
+	r := NewReport(fps, resps, err)
+	if len(r.Ingests) != 3 {
+		t.Fatalf("got %d entries, want 3", len(r.Ingests))
+	}
+
+	want := []ReportEntry{
+		{
+			File:          "a.pmtiles",
+			IngestID:      "ing-a",
+			DatasetID:     "ds-a",
+			State:         StateCompleted,
+			BytesUploaded: 100,
+			Duration:      time.Second,
+			Retries:       2,
+			TilesetURL:    "https://api.maptiler.com/tiles/ds-a/tiles.json",
+			Warnings:      []string{"dropped 3 features with invalid geometry"},
+		},
+		{File: "b.pmtiles", State: StateFailed, Error: ErrInvalidFile.Error()},
+		{File: "c.pmtiles", State: StateCanceled},
+	}
+	for i, w := range want {
+		if !reflect.DeepEqual(r.Ingests[i], w) {
+			t.Fatalf("entry %d = %+v, want %+v", i, r.Ingests[i], w)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package maptiler
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// partRetryDelay is the pause before a failed part is sent again.
+const partRetryDelay = time.Second
+
+// defaultRetryPolicy is the policy of a Client without WithRetryPolicy. It
+// only retries calls the service rejected with 429, 502 or 503.
+var defaultRetryPolicy = onlyRejected(ExponentialBackoff{Attempts: 3, Base: time.Second, Max: 30 * time.Second})
+
+// RetryPolicy decides whether a failed call is tried again, and how long to wait
+// before. attempt is the number of the retry, starting at 1.
+type RetryPolicy interface {
+	Retry(attempt int, err error) (time.Duration, bool)
+}
+
+// ExponentialBackoff retries transient failures, see IsRetryable, up to Attempts
+// times. The delay starts at Base and doubles with every attempt up to Max, of
+// which a random delay between half and all of it is waited.
+type ExponentialBackoff struct {
+	Attempts int
+	Base     time.Duration
+	Max      time.Duration
+}
+
+func (b ExponentialBackoff) Retry(attempt int, err error) (time.Duration, bool) {
+	if attempt > b.Attempts || !IsRetryable(err) {
+		return 0, false
+	}
+	d := b.Base
+	for i := 1; i < attempt && d > 0 && d <= math.MaxInt64/2 && (b.Max <= 0 || d < b.Max); i++ {
+		d *= 2
+	}
+	if b.Max > 0 {
+		d = min(d, b.Max)
+	}
+	if d <= 0 {
+		return 0, true
+	}
+	//nolint:gosec // jitter does not need a cryptographically secure source.
+	return d/2 + rand.N(d/2+1), true
+}
+
+// FixedDelay retries transient failures, see IsRetryable, up to Attempts times
+// after Delay.
+type FixedDelay struct {
+	Attempts int
+	Delay    time.Duration
+}
+
+func (f FixedDelay) Retry(attempt int, err error) (time.Duration, bool) {
+	if attempt > f.Attempts || !IsRetryable(err) {
+		return 0, false
+	}
+	return f.Delay, true
+}
+
+// NoRetry never retries.
+type NoRetry struct{}
+
+func (NoRetry) Retry(int, error) (time.Duration, bool) { return 0, false }
+
+// rejectedOnly retries calls that are not safe to repeat as decided by p, but
+// only if the service rejected them, see isRejected.
+type rejectedOnly struct {
+	p RetryPolicy
+}
+
+// onlyRejected returns p restricted to rejected calls, or nil if p is nil.
+func onlyRejected(p RetryPolicy) RetryPolicy {
+	if p == nil {
+		return nil
+	}
+	return rejectedOnly{p: p}
+}
+
+func (r rejectedOnly) Retry(attempt int, err error) (time.Duration, bool) {
+	if !isRejected(err) {
+		return 0, false
+	}
+	return r.p.Retry(attempt, err)
+}
+
+// withRetry calls fn until it succeeds, ctx is done or p gives up. A nil policy
+// never retries. A Retry-After of the server is waited if it is longer than the
+// delay of p.
+func withRetry[T any](ctx context.Context, p RetryPolicy, fn func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		v, err := fn()
+		if err == nil || p == nil || ctx.Err() != nil {
+			return v, err
+		}
+		d, ok := p.Retry(attempt, err)
+		if !ok {
+			return v, err
+		}
+		if serr := sleep(ctx, max(d, retryAfter(err))); serr != nil {
+			return v, err
+		}
+	}
+}
+
+// retryBudget limits the retries of all parts of an ingest combined. A zero
+// limit means unlimited. A nil budget allows every retry and records nothing.
+type retryBudget struct {
+	mu         sync.Mutex
+	maxRetries int
+	maxTime    time.Duration
+	retries    int
+	retryTime  time.Duration
+}
+
+func newRetryBudget(maxRetries int, maxTime time.Duration) *retryBudget {
+	return &retryBudget{maxRetries: maxRetries, maxTime: maxTime}
+}
+
+// take consumes one retry. It returns a RetryBudgetExhaustedError wrapping cause
+// if the budget is used up.
+func (b *retryBudget) take(cause error) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if (b.maxRetries > 0 && b.retries >= b.maxRetries) || (b.maxTime > 0 && b.retryTime >= b.maxTime) {
+		return RetryBudgetExhaustedError{
+			Retries:   b.retries,
+			RetryTime: b.retryTime,
+			Err:       cause,
+		}
+	}
+	b.retries++
+	return nil
+}
+
+// spend records time spent on a retry.
+func (b *retryBudget) spend(d time.Duration) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.retryTime += d
+}
+
+// consumed returns the retries taken and the time spent on them.
+func (b *retryBudget) consumed() (int, time.Duration) {
+	if b == nil {
+		return 0, 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.retries, b.retryTime
+}
+
+// sleep pauses for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
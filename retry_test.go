@@ -0,0 +1,323 @@
+package maptiler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestRetryPolicies(t *testing.T) {
+	t.Parallel()
+
+	transient := APIError{StatusCode: http.StatusServiceUnavailable}
+	permanent := APIError{StatusCode: http.StatusForbidden}
+
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		err     error
+		wantOK  bool
+		minWait time.Duration
+		maxWait time.Duration
+	}{
+		{name: "fixed", policy: FixedDelay{Attempts: 2, Delay: time.Second}, attempt: 2, err: transient, wantOK: true, minWait: time.Second, maxWait: time.Second},
+		{name: "fixed exhausted", policy: FixedDelay{Attempts: 2, Delay: time.Second}, attempt: 3, err: transient},
+		{name: "fixed permanent", policy: FixedDelay{Attempts: 2}, attempt: 1, err: permanent},
+		{name: "exponential first", policy: ExponentialBackoff{Attempts: 5, Base: time.Second, Max: time.Minute}, attempt: 1, err: transient, wantOK: true, minWait: 500 * time.Millisecond, maxWait: time.Second},
+		{name: "exponential third", policy: ExponentialBackoff{Attempts: 5, Base: time.Second, Max: time.Minute}, attempt: 3, err: transient, wantOK: true, minWait: 2 * time.Second, maxWait: 4 * time.Second},
+		{name: "exponential capped", policy: ExponentialBackoff{Attempts: 100, Base: time.Second, Max: 10 * time.Second}, attempt: 100, err: transient, wantOK: true, minWait: 5 * time.Second, maxWait: 10 * time.Second},
+		{name: "exponential uncapped", policy: ExponentialBackoff{Attempts: 100, Base: time.Second}, attempt: 100, err: transient, wantOK: true, minWait: time.Hour},
+		{name: "exponential exhausted", policy: ExponentialBackoff{Attempts: 1, Base: time.Second}, attempt: 2, err: transient},
+		{name: "none", policy: NoRetry{}, attempt: 1, err: transient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			d, ok := tt.policy.Retry(tt.attempt, tt.err)
+			if ok != tt.wantOK {
+				t.Fatalf("Retry() ok=%v want %v", ok, tt.wantOK)
+			}
+			if d < tt.minWait || (tt.maxWait > 0 && d > tt.maxWait) {
+				t.Fatalf("Retry() delay=%v want between %v and %v", d, tt.minWait, tt.maxWait)
+			}
+		})
+	}
+}
+
+func TestClientRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) < 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"ds-1"}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token", WithRetryPolicy(FixedDelay{Attempts: 2, Delay: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	d, err := c.GetDataset(t.Context(), "ds-1")
+	if err != nil {
+		t.Fatalf("GetDataset() unexpected error: %v", err)
+	}
+	if d.ID != "ds-1" || atomic.LoadInt32(&hits) != 3 {
+		t.Fatalf("dataset=%+v after %d requests, want ds-1 after 3", d, hits)
+	}
+
+	// NoRetry returns the first failure.
+	atomic.StoreInt32(&hits, 0)
+	c, err = New(srv.URL, "token", WithRetryPolicy(NoRetry{}))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if _, err := c.GetDataset(t.Context(), "ds-1"); !errors.As(err, new(APIError)) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+}
+
+func TestClientDefaultRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&hits, 1) {
+		case 1:
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		case 2:
+			_, _ = w.Write([]byte(`{"id":"ds-1"}`))
+		default:
+			http.Error(w, "broken", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	// a rejection is retried by default.
+	d, err := c.GetDataset(t.Context(), "ds-1")
+	if err != nil {
+		t.Fatalf("GetDataset() unexpected error: %v", err)
+	}
+	if d.ID != "ds-1" || atomic.LoadInt32(&hits) != 2 {
+		t.Fatalf("dataset=%+v after %d requests, want ds-1 after 2", d, hits)
+	}
+
+	// other failures are not.
+	if _, err := c.GetDataset(t.Context(), "ds-1"); !errors.As(err, new(APIError)) || atomic.LoadInt32(&hits) != 3 {
+		t.Fatalf("expected APIError after 3 requests, got %v after %d", err, hits)
+	}
+}
+
+func TestPartRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	client := FixedDelay{Attempts: 3, Delay: time.Second}
+	part := ExponentialBackoff{Attempts: 2, Base: time.Second}
+	c, err := New("http://localhost", "token", WithRetryPolicy(client))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts []IngestOption
+		want RetryPolicy
+	}{
+		{name: "client policy", want: client},
+		{name: "part retries", opts: []IngestOption{WithPartRetries(2)}},
+		{name: "part retries off", opts: []IngestOption{WithPartRetries(0)}},
+		{name: "part policy", opts: []IngestOption{WithPartRetries(2), WithPartRetryPolicy(part)}, want: part},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := c.partRetryPolicy(newIngestConfig(tt.opts...)); got != tt.want {
+				t.Fatalf("partRetryPolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetryStopsOnContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	var calls int
+	_, err := withRetry(ctx, FixedDelay{Attempts: 10, Delay: time.Hour}, func() (int, error) {
+		calls++
+		cancel()
+		return 0, APIError{StatusCode: http.StatusServiceUnavailable}
+	})
+	if calls != 1 || !errors.As(err, new(APIError)) {
+		t.Fatalf("calls=%d err=%v, want a single call returning its error", calls, err)
+	}
+}
+
+func TestClientRetriesInjectedFaults(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			w.Header().Set("ETag", `"etag-1"`)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"ds-1","title":"roads"}`))
+	}))
+	defer srv.Close()
+
+	faults := maptilertest.Faults{ResetRate: 0.3, TruncateRate: 0.3, Seed: 1}
+	c, err := New(srv.URL, "token",
+		WithHTTPTransport(maptilertest.NewTransport(faults).HTTPTransport()),
+		WithRetryPolicy(FixedDelay{Attempts: 20, Delay: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	for range 20 {
+		d, err := c.GetDataset(t.Context(), "ds-1")
+		if err != nil {
+			t.Fatalf("GetDataset() unexpected error: %v", err)
+		}
+		if d.Title != "roads" {
+			t.Fatalf("unexpected dataset %+v", d)
+		}
+	}
+
+	h, err := newRipTransport(transportConfig{transport: maptilertest.NewTransport(faults).HTTPTransport()})
+	if err != nil {
+		t.Fatalf("creating http client: %v", err)
+	}
+	proc := &uploadProcessor{h: h, normalize: NormalizeETag, retryDelay: time.Millisecond}
+	fp := writeTestFile(t, []byte("abc"))
+	for i := range 20 {
+		got, err := proc.Process(t.Context(), newTask(uploadTask{
+			uploadPart: uploadPart{PartID: int64(i + 1), URL: srv.URL},
+			FilePath:   fp,
+			Length:     3,
+			Retries:    20,
+		}))
+		if err != nil {
+			t.Fatalf("Process() unexpected error: %v", err)
+		}
+		if got.ETag != `"etag-1"` {
+			t.Fatalf("etag=%q want %q", got.ETag, `"etag-1"`)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "empty", value: "", want: 0},
+		{name: "seconds", value: "120", want: 2 * time.Minute},
+		{name: "zero", value: "0", want: 0},
+		{name: "negative", value: "-5", want: 0},
+		{name: "date", value: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90 * time.Second},
+		{name: "date in the past", value: now.Add(-time.Minute).Format(http.TimeFormat), want: 0},
+		{name: "invalid", value: "soon", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Fatalf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetryWaitsRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	const wait = 50 * time.Millisecond
+	var calls int
+	start := time.Now()
+	_, err := withRetry(t.Context(), FixedDelay{Attempts: 1}, func() (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: wait}
+		}
+		return 1, nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("calls=%d err=%v, want success after 2 calls", calls, err)
+	}
+	if elapsed := time.Since(start); elapsed < wait {
+		t.Fatalf("retried after %v, want at least the Retry-After of %v", elapsed, wait)
+	}
+}
+
+func TestClientRetriesRejectedCreate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		status    int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{name: "rate limited", status: http.StatusTooManyRequests, wantCalls: 2},
+		{name: "bad gateway", status: http.StatusBadGateway, wantCalls: 2},
+		{name: "unavailable", status: http.StatusServiceUnavailable, wantCalls: 2},
+		{name: "internal error is not retried", status: http.StatusInternalServerError, wantCalls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					w.Header().Set("Retry-After", "0")
+					http.Error(w, "try again", tt.status)
+					return
+				}
+				_, _ = w.Write([]byte(`{"id":"ingest-1","state":"upload"}`))
+			}))
+			t.Cleanup(srv.Close)
+
+			c, err := New(srv.URL, "token", WithRetryPolicy(FixedDelay{Attempts: 3, Delay: time.Millisecond}))
+			if err != nil {
+				t.Fatalf("New() failed: %v", err)
+			}
+
+			ir, err := c.ingest(t.Context(), newIngestRequest("", "tiles.pmtiles", 10))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ingest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Fatalf("ingest() sent %d requests, want %d", got, tt.wantCalls)
+			}
+			if !tt.wantErr && ir.ID != "ingest-1" {
+				t.Fatalf("unexpected response %+v", ir)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package maptiler
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// S3Error is the error document S3 compatible upload targets respond with
+// to failing part uploads. RequestID and HostID are what S3 support asks for.
+// It is wrapped by the APIError of the part, so errors.As finds it.
+type S3Error struct {
+	Code      string `xml:"Code"`
+	Message   string `xml:"Message"`
+	RequestID string `xml:"RequestId"`
+	HostID    string `xml:"HostId"`
+	// StringToSign and CanonicalRequest are set for SignatureDoesNotMatch.
+	StringToSign     string `xml:"StringToSign"`
+	CanonicalRequest string `xml:"CanonicalRequest"`
+}
+
+func (e S3Error) Error() string {
+	s := e.Code
+	if e.Message != "" {
+		s += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		s += " (request id " + e.RequestID + ")"
+	}
+	return s
+}
+
+// parseS3Error parses the error document in body. It returns nil if body is
+// not one, e.g. for targets that are not S3 compatible.
+func parseS3Error(header http.Header, body []byte) *S3Error {
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("<")) {
+		return nil
+	}
+	var doc struct {
+		XMLName xml.Name `xml:"Error"`
+		S3Error
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil || doc.Code == "" {
+		return nil
+	}
+	e := doc.S3Error
+	if e.RequestID == "" {
+		e.RequestID = header.Get("X-Amz-Request-Id")
+	}
+	if e.HostID == "" {
+		e.HostID = header.Get("X-Amz-Id-2")
+	}
+	return &e
+}
+
+// PartRequest is the request of a failing part upload without its payload,
+// see WithUploadDebug. Signatures and session tokens are redacted.
+type PartRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+}
+
+// String formats the request as it was sent, without the body.
+func (r PartRequest) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", r.Method, r.URL)
+	//nolint:errcheck // writing to a strings.Builder does not fail.
+	r.Header.Write(&b)
+	return b.String()
+}
+
+// redactedQuery are the query parameters of presigned URLs that grant access.
+var redactedQuery = []string{"X-Amz-Signature", "X-Amz-Security-Token", "X-Goog-Signature", "Signature"}
+
+// redactedHeaders are the headers that grant access.
+var redactedHeaders = []string{"Authorization", "X-Amz-Security-Token"}
+
+const redacted = "REDACTED"
+
+type partRequestKey struct{}
+
+// withPartRequest returns a context whose part upload request is recorded
+// into the returned PartRequest by the upload transport.
+func withPartRequest(ctx context.Context) (context.Context, *PartRequest) {
+	pr := &PartRequest{}
+	return context.WithValue(ctx, partRequestKey{}, pr), pr
+}
+
+// recordPartRequest records req if its context asks for it. Redirected
+// requests overwrite the previous one, so the request that failed remains.
+func recordPartRequest(req *http.Request) {
+	pr, ok := req.Context().Value(partRequestKey{}).(*PartRequest)
+	if !ok {
+		return
+	}
+
+	u := *req.URL
+	q := u.Query()
+	for _, k := range redactedQuery {
+		if q.Has(k) {
+			q.Set(k, redacted)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	h := req.Header.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	for _, k := range redactedHeaders {
+		if h.Get(k) != "" {
+			h.Set(k, redacted)
+		}
+	}
+	// net/http writes these from the request instead of its header.
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	h.Set("Host", host)
+	if req.ContentLength > 0 {
+		h.Set("Content-Length", strconv.FormatInt(req.ContentLength, 10))
+	}
+
+	*pr = PartRequest{Method: req.Method, URL: u.String(), Header: h}
+}
@@ -0,0 +1,142 @@
+package maptiler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const signatureMismatch = `<?xml version="1.0" encoding="UTF-8"?>
+<Error>
+  <Code>SignatureDoesNotMatch</Code>
+  <Message>The request signature we calculated does not match the signature you provided.</Message>
+  <StringToSign>AWS4-HMAC-SHA256</StringToSign>
+  <CanonicalRequest>PUT /part</CanonicalRequest>
+  <RequestId>4442587FB7D0A2F9</RequestId>
+  <HostId>host-id</HostId>
+</Error>`
+
+func TestParseS3Error(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   string
+		want   *S3Error
+	}{
+		{
+			name: "signature mismatch",
+			body: signatureMismatch,
+			want: &S3Error{
+				Code:             "SignatureDoesNotMatch",
+				Message:          "The request signature we calculated does not match the signature you provided.",
+				RequestID:        "4442587FB7D0A2F9",
+				HostID:           "host-id",
+				StringToSign:     "AWS4-HMAC-SHA256",
+				CanonicalRequest: "PUT /part",
+			},
+		},
+		{
+			name: "ids from headers",
+			header: http.Header{
+				"X-Amz-Request-Id": {"req"},
+				"X-Amz-Id-2":       {"host"},
+			},
+			body: `<Error><Code>AccessDenied</Code><Message>Request has expired</Message></Error>`,
+			want: &S3Error{Code: "AccessDenied", Message: "Request has expired", RequestID: "req", HostID: "host"},
+		},
+		{name: "json", body: `{"message":"forbidden"}`},
+		{name: "other document", body: `<html><body>forbidden</body></html>`},
+		{name: "empty", body: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := parseS3Error(tt.header, []byte(tt.body))
+			switch {
+			case tt.want == nil && got != nil:
+				t.Fatalf("expected nil, got %+v", got)
+			case tt.want != nil && (got == nil || *got != *tt.want):
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUploadProcessorDebug(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(signatureMismatch))
+	}))
+	defer srv.Close()
+
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		t.Fatal("unexpected default transport")
+	}
+	h, err := newRipTransport(transportConfig{transport: newRedirectTransport(base.Clone(), RedirectDeny, nil, nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := writeTestFile(t, []byte("abcdefghij"))
+	url := srv.URL + "/part?X-Amz-Expires=900&X-Amz-Signature=secret"
+
+	for _, debug := range []bool{false, true} {
+		proc := &uploadProcessor{h: h, retryDelay: time.Millisecond, debug: debug}
+		_, err := proc.Process(t.Context(), newTask(uploadTask{
+			uploadPart: uploadPart{PartID: 3, URL: url},
+			FilePath:   fp,
+			Length:     10,
+		}))
+
+		var aerr APIError
+		if !errors.As(err, &aerr) {
+			t.Fatalf("debug=%t: expected APIError, got %v", debug, err)
+		}
+		if aerr.S3 == nil || aerr.S3.Code != "SignatureDoesNotMatch" {
+			t.Fatalf("debug=%t: expected parsed S3 error, got %+v", debug, aerr.S3)
+		}
+		var s3err S3Error
+		if !errors.As(err, &s3err) || s3err.RequestID != "4442587FB7D0A2F9" {
+			t.Errorf("debug=%t: expected the S3 error to be wrapped, got %v", debug, err)
+		}
+		if !strings.Contains(err.Error(), "sending part 3: request failed with 403: SignatureDoesNotMatch") {
+			t.Errorf("debug=%t: expected the status and the S3 code in %q", debug, err)
+		}
+
+		if !debug {
+			if aerr.Request != nil {
+				t.Fatalf("expected no request without debug, got %+v", aerr.Request)
+			}
+			continue
+		}
+		req := aerr.Request
+		if req == nil {
+			t.Fatal("expected the request with debug")
+		}
+		if req.Method != http.MethodPut {
+			t.Errorf("expected PUT, got %s", req.Method)
+		}
+		if strings.Contains(req.URL, "secret") || !strings.Contains(req.URL, "X-Amz-Signature=REDACTED") {
+			t.Errorf("expected a redacted signature, got %s", req.URL)
+		}
+		if !strings.Contains(req.URL, "X-Amz-Expires=900") {
+			t.Errorf("expected the other query parameters, got %s", req.URL)
+		}
+		if got := req.Header.Get("Content-Length"); got != "10" {
+			t.Errorf("expected Content-Length 10, got %q", got)
+		}
+		if got := req.Header.Get("Host"); got != srv.Listener.Addr().String() {
+			t.Errorf("expected Host %s, got %q", srv.Listener.Addr(), got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package maptiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// smokeCancelTimeout bounds the cancel of the ingest of SmokeTest, which is
+// sent even if ctx is done.
+const smokeCancelTimeout = 30 * time.Second
+
+// SmokePhase is a step of SmokeTest: create, upload or cancel.
+type SmokePhase struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// SmokeResult is the outcome of SmokeTest.
+type SmokeResult struct {
+	IngestID string `json:"ingest_id,omitempty"`
+	// UploadHost is the host the part was uploaded to.
+	UploadHost string       `json:"upload_host,omitempty"`
+	PartSize   int64        `json:"part_size,omitempty"`
+	Phases     []SmokePhase `json:"phases"`
+}
+
+func (r SmokeResult) String() string { return toJSONString(r) }
+
+// SmokeTest verifies that the Client may ingest fp without a full upload: it
+// creates an ingest, uploads its first part and cancels it again, timing every
+// phase. It checks write permissions of the token and the reachability of the
+// upload targets, e.g. before a large ingest. ctx time boxes the test, the
+// ingest is canceled even if ctx is done. The result lists the phases that ran,
+// the failing one with its error, which is returned as well.
+func (c *Client) SmokeTest(ctx context.Context, fp string, opts ...IngestOption) (SmokeResult, error) {
+	cfg := newIngestConfig(opts...)
+	fp = osPath(fp)
+	info, err := checkFile(fp, cfg)
+	if err != nil {
+		return SmokeResult{}, fmt.Errorf("smoke test: %w", err)
+	}
+
+	var res SmokeResult
+	phase := func(name string, fn func() error) error {
+		start := time.Now()
+		err := fn()
+		p := SmokePhase{Name: name, Duration: time.Since(start)}
+		if err != nil {
+			p.Error = err.Error()
+		}
+		res.Phases = append(res.Phases, p)
+		return err
+	}
+
+	var ir IngestResponse
+	err = phase("create", func() error {
+		req := newIngestRequest("", cfg.filename(info.Name()), info.Size())
+		req.Metadata = c.metadataOf(cfg)
+		ir, err = c.ingest(ctx, req)
+		return err
+	})
+	if err != nil {
+		return res, fmt.Errorf("smoke test: %w", err)
+	}
+	res.IngestID = ir.ID
+	res.PartSize = ir.Upload.PartSize
+
+	uerr := phase("upload", func() error {
+		if len(ir.Upload.Parts) == 0 {
+			return errors.New("the ingest has no parts")
+		}
+		if u, err := url.Parse(ir.Upload.Parts[0].URL); err == nil {
+			res.UploadHost = u.Host
+		}
+		first := ir
+		first.Upload.Parts = ir.Upload.Parts[:1]
+		_, err := c.upload(ctx, first, fp, c.uploadOptions(cfg, nil))
+		return err
+	})
+
+	cerr := phase("cancel", func() error {
+		cctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), smokeCancelTimeout)
+		defer cancel()
+		_, err := c.cancel(cctx, ir.ID)
+		return err
+	})
+	if err := errors.Join(uerr, cerr); err != nil {
+		return res, fmt.Errorf("smoke test of ingest %s: %w", ir.ID, err)
+	}
+	return res, nil
+}
@@ -0,0 +1,56 @@
+package maptiler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestSmokeTest(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	defer srv.Close()
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	res, err := c.SmokeTest(t.Context(), fp)
+	if err != nil {
+		t.Fatalf("SmokeTest() unexpected error: %v", err)
+	}
+	if len(res.Phases) != 3 || res.Phases[0].Name != "create" || res.Phases[1].Name != "upload" || res.Phases[2].Name != "cancel" {
+		t.Fatalf("SmokeTest() phases = %+v, want create, upload and cancel", res.Phases)
+	}
+	if res.PartSize != 4 || res.UploadHost == "" {
+		t.Errorf("SmokeTest() = %+v, want the part size and upload host", res)
+	}
+	if got := IngestState(srv.State(res.IngestID)); got != StateCanceled {
+		t.Errorf("ingest is %q, want it to be canceled", got)
+	}
+
+	// a failing upload is reported in its phase, the ingest is canceled anyway.
+	c, err = New(srv.URL, "token", WithUploadHosts("*.amazonaws.com"))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	res, err = c.SmokeTest(t.Context(), fp)
+	if !errors.Is(err, ErrUploadHostNotAllowed) {
+		t.Fatalf("SmokeTest() error = %v, want ErrUploadHostNotAllowed", err)
+	}
+	if len(res.Phases) != 3 || res.Phases[1].Error == "" || res.Phases[2].Error != "" {
+		t.Errorf("SmokeTest() phases = %+v, want a failed upload and a cancel", res.Phases)
+	}
+	if got := IngestState(srv.State(res.IngestID)); got != StateCanceled {
+		t.Errorf("ingest is %q, want it to be canceled", got)
+	}
+}
@@ -0,0 +1,191 @@
+package maptiler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	spoolDataExt     = ".data"
+	spoolManifestExt = ".json"
+)
+
+// ErrSpoolLimit is returned when an input exceeds the configured spool size limit.
+var ErrSpoolLimit = errors.New("spool size limit exceeded")
+
+// spoolConfig holds configuration values for a Spool.
+type spoolConfig struct {
+	dir     string
+	maxSize int64
+}
+
+type SpoolOption func(*spoolConfig)
+
+// WithSpoolDir sets the directory spooled files are written to.
+func WithSpoolDir(dir string) SpoolOption {
+	return func(config *spoolConfig) {
+		config.dir = dir
+	}
+}
+
+// WithSpoolMaxSize limits the number of bytes a single spooled file may hold, 0 means unlimited.
+func WithSpoolMaxSize(n int64) SpoolOption {
+	return func(config *spoolConfig) {
+		config.maxSize = n
+	}
+}
+
+// Spool buffers inputs that cannot be read at random offsets, like stdin or
+// remote sources, in temporary files. It is safe to share a spool directory
+// between processes: every file is accompanied by a manifest naming the owning
+// process, so files left behind by crashed processes are removed by Cleanup.
+type Spool struct {
+	config *spoolConfig
+}
+
+type spoolManifest struct {
+	PID     int       `json:"pid"`
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+}
+
+// SpoolFile is a file buffered by a Spool. Close removes it.
+type SpoolFile struct {
+	// Name is the name the spooled input was given.
+	Name string
+	// Path is the location of the spooled data.
+	Path string
+	// Size is the number of bytes spooled.
+	Size int64
+
+	manifest string
+}
+
+// NewSpool creates a Spool, creating its directory if needed and removing
+// files abandoned by processes that are no longer running.
+func NewSpool(options ...SpoolOption) (*Spool, error) {
+	config := &spoolConfig{
+		dir: filepath.Join(os.TempDir(), "maptiler-spool"),
+	}
+	for _, o := range options {
+		o(config)
+	}
+	config.dir = osPath(config.dir)
+
+	if err := os.MkdirAll(config.dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating spool directory: %w", err)
+	}
+
+	s := &Spool{config: config}
+	if _, err := s.Cleanup(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Spool copies r into a new spooled file. The name is kept for reference only,
+// e.g. as the filename of the ingest.
+func (s *Spool) Spool(r io.Reader, name string) (*SpoolFile, error) {
+	id := ksuid.New().String()
+	sf := &SpoolFile{
+		Name:     name,
+		Path:     filepath.Join(s.config.dir, id+spoolDataExt),
+		manifest: filepath.Join(s.config.dir, id+spoolManifestExt),
+	}
+
+	// the manifest is written first, so the data file is never without owner.
+	m, err := json.Marshal(spoolManifest{PID: os.Getpid(), Name: name, Created: time.Now()})
+	if err != nil {
+		return nil, fmt.Errorf("encoding spool manifest: %w", err)
+	}
+	if err := os.WriteFile(sf.manifest, m, 0o600); err != nil {
+		return nil, fmt.Errorf("writing spool manifest: %w", err)
+	}
+
+	f, err := os.OpenFile(sf.Path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		_ = sf.Close()
+		return nil, fmt.Errorf("creating spool file: %w", err)
+	}
+
+	src := r
+	if s.config.maxSize > 0 {
+		// read one byte past the limit to detect oversized inputs.
+		src = io.LimitReader(r, s.config.maxSize+1)
+	}
+
+	n, err := io.Copy(f, src)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = sf.Close()
+		return nil, fmt.Errorf("spooling %s: %w", name, err)
+	}
+	if s.config.maxSize > 0 && n > s.config.maxSize {
+		_ = sf.Close()
+		return nil, fmt.Errorf("spooling %s: %w (%d bytes)", name, ErrSpoolLimit, s.config.maxSize)
+	}
+
+	sf.Size = n
+	return sf, nil
+}
+
+// Close removes the spooled data and its manifest.
+func (f *SpoolFile) Close() error {
+	err := os.Remove(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		err = nil
+	}
+	merr := os.Remove(f.manifest)
+	if errors.Is(merr, os.ErrNotExist) {
+		merr = nil
+	}
+	return errors.Join(err, merr)
+}
+
+// Cleanup removes spooled files whose owning process is no longer running and
+// returns the paths of the removed data files.
+func (s *Spool) Cleanup() ([]string, error) {
+	entries, err := os.ReadDir(s.config.dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading spool directory: %w", err)
+	}
+
+	var removed []string
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != spoolManifestExt {
+			continue
+		}
+
+		mp := filepath.Join(s.config.dir, e.Name())
+		b, err := os.ReadFile(mp)
+		if err != nil {
+			continue
+		}
+
+		var m spoolManifest
+		if err := json.Unmarshal(b, &m); err == nil && processAlive(m.PID) {
+			continue
+		}
+
+		sf := &SpoolFile{
+			Name:     m.Name,
+			Path:     filepath.Join(s.config.dir, strings.TrimSuffix(e.Name(), spoolManifestExt)+spoolDataExt),
+			manifest: mp,
+		}
+		if err := sf.Close(); err != nil {
+			return removed, fmt.Errorf("removing abandoned spool file: %w", err)
+		}
+		removed = append(removed, sf.Path)
+	}
+	return removed, nil
+}
@@ -0,0 +1,88 @@
+package maptiler
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSpoolRoundtrip(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewSpool(WithSpoolDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewSpool() failed: %v", err)
+	}
+
+	sf, err := s.Spool(strings.NewReader("abcdefghij"), "stdin.pmtiles")
+	if err != nil {
+		t.Fatalf("Spool() failed: %v", err)
+	}
+	if sf.Size != 10 {
+		t.Fatalf("size=%d want 10", sf.Size)
+	}
+	b, err := os.ReadFile(sf.Path)
+	if err != nil || string(b) != "abcdefghij" {
+		t.Fatalf("unexpected spooled content %q (%v)", b, err)
+	}
+
+	if err := sf.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if _, err := os.Stat(sf.Path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected spooled file to be removed, got %v", err)
+	}
+}
+
+func TestSpoolMaxSize(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	s, err := NewSpool(WithSpoolDir(dir), WithSpoolMaxSize(5))
+	if err != nil {
+		t.Fatalf("NewSpool() failed: %v", err)
+	}
+
+	if _, err := s.Spool(strings.NewReader("abcdef"), "big"); !errors.Is(err, ErrSpoolLimit) {
+		t.Fatalf("expected ErrSpoolLimit, got %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Fatalf("expected oversized input to be removed, found %d entries", len(entries))
+	}
+}
+
+func TestSpoolCleanupAbandoned(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	// pid far above any pid_max, the owner is surely gone.
+	m, _ := json.Marshal(spoolManifest{PID: 1 << 30, Name: "crashed"})
+	if err := os.WriteFile(filepath.Join(dir, "abc.json"), m, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "abc.data"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSpool(WithSpoolDir(dir))
+	if err != nil {
+		t.Fatalf("NewSpool() failed: %v", err)
+	}
+	live, err := s.Spool(strings.NewReader("y"), "live")
+	if err != nil {
+		t.Fatalf("Spool() failed: %v", err)
+	}
+	defer live.Close()
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Fatalf("expected only the live spool file and manifest, found %d entries", len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "abc.data")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected abandoned file to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package maptiler
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidStateKey is returned by NewStateCipher and ParseStateKey for a key
+// that is not a valid AES key.
+var ErrInvalidStateKey = errors.New("invalid state key")
+
+// ErrStateEncrypted is returned when reading an encrypted state file without
+// the key it was encrypted with, see WithStateCipher.
+var ErrStateEncrypted = errors.New("state file is encrypted")
+
+// sealedPrefix marks a line sealed by a StateCipher, lines without it are
+// plain text.
+const sealedPrefix = "enc:v1:"
+
+// StateCipher encrypts the local state files of uploads with AES-GCM, e.g.
+// journals, as they hold presigned upload URLs and dataset identifiers. Every
+// line is sealed on its own, so a file that is appended to stays readable up to
+// its last complete line. A nil StateCipher leaves the files in plain text.
+type StateCipher struct {
+	aead cipher.AEAD
+}
+
+// NewStateCipher returns a StateCipher of an AES key of 16, 24 or 32 bytes.
+func NewStateCipher(key []byte) (*StateCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidStateKey, err)
+	}
+	aead, err := cipher.NewGCMWithRandomNonce(block)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidStateKey, err)
+	}
+	return &StateCipher{aead: aead}, nil
+}
+
+// ParseStateKey returns the StateCipher of a base64 encoded key, e.g. from
+// MAPTILER_STATE_KEY. A key is created with `openssl rand -base64 32`.
+func ParseStateKey(s string) (*StateCipher, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidStateKey, err)
+	}
+	return NewStateCipher(key)
+}
+
+// Seal encrypts line, which must not contain a newline. A nil StateCipher
+// returns line as it is.
+func (s *StateCipher) Seal(line []byte) []byte {
+	if s == nil {
+		return line
+	}
+	sealed := s.aead.Seal(nil, nil, line, nil)
+	out := make([]byte, len(sealedPrefix)+base64.StdEncoding.EncodedLen(len(sealed)))
+	copy(out, sealedPrefix)
+	base64.StdEncoding.Encode(out[len(sealedPrefix):], sealed)
+	return out
+}
+
+// Open decrypts a line sealed by Seal. Lines in plain text are returned as they
+// are, so that files written before the key was set stay readable. A sealed
+// line fails with ErrStateEncrypted for a nil StateCipher or another key.
+func (s *StateCipher) Open(line []byte) ([]byte, error) {
+	line = bytes.TrimSpace(line)
+	b64, ok := bytes.CutPrefix(line, []byte(sealedPrefix))
+	if !ok {
+		return line, nil
+	}
+	if s == nil {
+		return nil, fmt.Errorf("%w, set its key to read it", ErrStateEncrypted)
+	}
+	sealed := make([]byte, base64.StdEncoding.DecodedLen(len(b64)))
+	n, err := base64.StdEncoding.Decode(sealed, b64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrStateEncrypted, err)
+	}
+	plain, err := s.aead.Open(nil, nil, sealed[:n], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w with another key or corrupted: %w", ErrStateEncrypted, err)
+	}
+	return plain, nil
+}
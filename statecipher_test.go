@@ -0,0 +1,90 @@
+package maptiler
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateCipher(t *testing.T) {
+	t.Parallel()
+
+	sc, err := ParseStateKey("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatalf("ParseStateKey() unexpected error: %v", err)
+	}
+	line := []byte(`{"url":"https://bucket.s3.amazonaws.com/part-1?X-Amz-Signature=abc"}`)
+	sealed := sc.Seal(line)
+	if bytes.Contains(sealed, []byte("amazonaws")) || bytes.ContainsRune(sealed, '\n') {
+		t.Fatalf("Seal() = %s, want a single encrypted line", sealed)
+	}
+	got, err := sc.Open(append(sealed, '\n'))
+	if err != nil || !bytes.Equal(got, line) {
+		t.Fatalf("Open() = %s, %v, want %s", got, err, line)
+	}
+	if got, err := sc.Open(line); err != nil || !bytes.Equal(got, line) {
+		t.Errorf("Open() = %s, %v, want plain text as it is", got, err)
+	}
+
+	var none *StateCipher
+	if _, err := none.Open(sealed); !errors.Is(err, ErrStateEncrypted) {
+		t.Errorf("Open() without key error = %v, want ErrStateEncrypted", err)
+	}
+	other, err := NewStateCipher(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("NewStateCipher() unexpected error: %v", err)
+	}
+	if _, err := other.Open(sealed); !errors.Is(err, ErrStateEncrypted) {
+		t.Errorf("Open() with another key error = %v, want ErrStateEncrypted", err)
+	}
+
+	for _, key := range []string{"c2hvcnQ=", "not base64"} {
+		if _, err := ParseStateKey(key); !errors.Is(err, ErrInvalidStateKey) {
+			t.Errorf("ParseStateKey(%q) error = %v, want ErrInvalidStateKey", key, err)
+		}
+	}
+}
+
+func TestEncryptedJournal(t *testing.T) {
+	t.Parallel()
+
+	sc, err := NewStateCipher(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatalf("NewStateCipher() unexpected error: %v", err)
+	}
+	jp := filepath.Join(t.TempDir(), "tiles.journal")
+	j, err := createJournal(jp, journalHeader{
+		IngestID:   "ingest-1",
+		File:       "/tiles.pmtiles",
+		Size:       10,
+		PartSize:   4,
+		Parts:      uploadParts{{PartID: 1, URL: "https://upload.example.com/part-1"}},
+		UploadType: ingestUploadTypeS3MultiPart,
+	}, sc, nil)
+	if err != nil {
+		t.Fatalf("createJournal() unexpected error: %v", err)
+	}
+	j.add(uploadTaskResponse{PartID: 1, ETag: "a"})
+	j.close()
+
+	b, err := os.ReadFile(jp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("upload.example.com")) || bytes.Contains(b, []byte("ingest-1")) {
+		t.Fatalf("journal is not encrypted:\n%s", b)
+	}
+
+	h, done, err := readJournal(jp, sc)
+	if err != nil {
+		t.Fatalf("readJournal() unexpected error: %v", err)
+	}
+	if h.IngestID != "ingest-1" || done[1] != "a" {
+		t.Errorf("readJournal() = %+v, %v", h, done)
+	}
+	if _, _, err := readJournal(jp, nil); !errors.Is(err, ErrStateEncrypted) {
+		t.Errorf("readJournal() without key error = %v, want ErrStateEncrypted", err)
+	}
+}
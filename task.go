@@ -6,6 +6,18 @@ import "github.com/segmentio/ksuid"
 type task[T any] struct {
 	Body T
 	ID   ksuid.KSUID
+
+	// retried is set by the pool to forward retries to its listener. It is shared
+	// by all tasks of a pool instead of a closure per task.
+	retried func(t task[T], attempt int, err error)
+}
+
+// reportRetry lets a processor announce that it is about to try the task again
+// after err.
+func (t task[T]) reportRetry(attempt int, err error) {
+	if t.retried != nil {
+		t.retried(t, attempt, err)
+	}
 }
 
 // newTask creates a new Task with the provided payload.
@@ -15,3 +27,17 @@ func newTask[T any](body T) task[T] {
 		Body: body,
 	}
 }
+
+// result is the outcome of a successfully processed task.
+type result[T any] struct {
+	Body   T
+	TaskID ksuid.KSUID
+}
+
+// newResult creates a new result for the task t.
+func newResult[In, Out any](t task[In], body Out) result[Out] {
+	return result[Out]{
+		TaskID: t.ID,
+		Body:   body,
+	}
+}
@@ -0,0 +1,143 @@
+package maptiler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const tilesTileJSON = "/:id/tiles.json"
+
+// TileJSON is the metadata of a published tileset.
+type TileJSON struct {
+	TileJSON     string        `json:"tilejson"`
+	Name         string        `json:"name,omitempty"`
+	Format       string        `json:"format,omitempty"`
+	Bounds       []float64     `json:"bounds,omitempty"`
+	MinZoom      float64       `json:"minzoom"`
+	MaxZoom      float64       `json:"maxzoom"`
+	VectorLayers []VectorLayer `json:"vector_layers,omitempty"`
+	Tilestats    *Tilestats    `json:"tilestats,omitempty"`
+}
+
+// VectorLayer describes a source layer of a vector tileset and its attributes.
+type VectorLayer struct {
+	ID      string            `json:"id"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	MinZoom float64           `json:"minzoom"`
+	MaxZoom float64           `json:"maxzoom"`
+}
+
+func (t TileJSON) String() string { return toJSONString(t) }
+
+// TileJSON returns the TileJSON of the tileset of a dataset. The tiles API is
+// authorized by an API key, not by the token of the Client.
+func (c *Client) TileJSON(ctx context.Context, datasetID, key string) (TileJSON, error) {
+	return withRetry(ctx, c.retry, func() (TileJSON, error) {
+		return c.tileJSON(ctx, datasetID, key)
+	})
+}
+
+// tileJSON fetches the TileJSON of the tileset of a dataset.
+func (c *Client) tileJSON(ctx context.Context, datasetID, key string) (TileJSON, error) {
+	req := request{
+		method: http.MethodGet,
+		path:   tilesTileJSON,
+		id:     datasetID,
+		query:  map[string]string{"key": key},
+	}
+	body, err := c.cache.get(ctx, c.tiles, req, "tilejson:"+datasetID+":"+key)
+	if err != nil {
+		return TileJSON{}, fmt.Errorf("getting tilejson: %w", err)
+	}
+
+	var tj TileJSON
+	if uerr := json.Unmarshal(body, &tj); uerr != nil {
+		return tj, fmt.Errorf("getting tilejson: %w", uerr)
+	}
+	return tj, nil
+}
+
+// TileJSONDiff is a difference between two TileJSON documents. A and B are
+// empty if the field is missing in the respective document.
+type TileJSONDiff struct {
+	Field string `json:"field"`
+	A     string `json:"a"`
+	B     string `json:"b"`
+}
+
+func (d TileJSONDiff) String() string { return toJSONString(d) }
+
+// DiffTileJSON compares the bounds, zoom levels, source layers and attribute
+// keys of two tilesets. Differences are ordered by field.
+func DiffTileJSON(a, b TileJSON) []TileJSONDiff {
+	var diffs []TileJSONDiff
+	add := func(field, va, vb string) {
+		if va != vb {
+			diffs = append(diffs, TileJSONDiff{Field: field, A: va, B: vb})
+		}
+	}
+
+	add("format", a.Format, b.Format)
+	add("bounds", formatFloats(a.Bounds), formatFloats(b.Bounds))
+	add("minzoom", formatFloats([]float64{a.MinZoom}), formatFloats([]float64{b.MinZoom}))
+	add("maxzoom", formatFloats([]float64{a.MaxZoom}), formatFloats([]float64{b.MaxZoom}))
+
+	la, lb := layersByID(a.VectorLayers), layersByID(b.VectorLayers)
+	ids := slices.Sorted(maps.Keys(la))
+	for id := range lb {
+		if _, ok := la[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		layerA, okA := la[id]
+		layerB, okB := lb[id]
+		add("layers."+id, present(okA), present(okB))
+		if !okA || !okB {
+			continue
+		}
+
+		keys := slices.Collect(maps.Keys(layerA.Fields))
+		for k := range layerB.Fields {
+			if _, ok := layerA.Fields[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			add("layers."+id+".fields."+k, layerA.Fields[k], layerB.Fields[k])
+		}
+	}
+	return diffs
+}
+
+func layersByID(layers []VectorLayer) map[string]VectorLayer {
+	m := make(map[string]VectorLayer, len(layers))
+	for _, l := range layers {
+		m[l.ID] = l
+	}
+	return m
+}
+
+func present(ok bool) string {
+	if ok {
+		return "present"
+	}
+	return ""
+}
+
+func formatFloats(fs []float64) string {
+	s := make([]string, len(fs))
+	for i, f := range fs {
+		s[i] = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return strings.Join(s, ",")
+}
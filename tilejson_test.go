@@ -0,0 +1,103 @@
+package maptiler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestClientTileJSON(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ds-1/tiles.json" || r.URL.Query().Get("key") != "k" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"tilejson":"3.0.0","format":"pbf","minzoom":0,"maxzoom":14,
+			"vector_layers":[{"id":"roads","fields":{"name":"String"}}]}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token", WithTilesHost(srv.URL))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	tj, err := c.TileJSON(t.Context(), "ds-1", "k")
+	if err != nil {
+		t.Fatalf("TileJSON() unexpected error: %v", err)
+	}
+	if tj.Format != "pbf" || tj.MaxZoom != 14 || len(tj.VectorLayers) != 1 || tj.VectorLayers[0].Fields["name"] != "String" {
+		t.Fatalf("unexpected tilejson %+v", tj)
+	}
+
+	if _, err := c.TileJSON(t.Context(), "missing", "k"); err == nil {
+		t.Fatalf("expected an error for a missing tileset")
+	}
+}
+
+func TestDiffTileJSON(t *testing.T) {
+	t.Parallel()
+
+	a := TileJSON{
+		Format:  "pbf",
+		Bounds:  []float64{-180, -85, 180, 85},
+		MinZoom: 0,
+		MaxZoom: 14,
+		VectorLayers: []VectorLayer{
+			{ID: "roads", Fields: map[string]string{"name": "String", "class": "String"}},
+			{ID: "water"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		b    func(TileJSON) TileJSON
+		want []TileJSONDiff
+	}{
+		{
+			name: "equal",
+			b:    func(b TileJSON) TileJSON { return b },
+		},
+		{
+			name: "zoom and bounds",
+			b: func(b TileJSON) TileJSON {
+				b.MaxZoom = 12
+				b.Bounds = []float64{0, 0, 1.5, 1}
+				return b
+			},
+			want: []TileJSONDiff{
+				{Field: "bounds", A: "-180,-85,180,85", B: "0,0,1.5,1"},
+				{Field: "maxzoom", A: "14", B: "12"},
+			},
+		},
+		{
+			name: "layers and fields",
+			b: func(b TileJSON) TileJSON {
+				b.VectorLayers = []VectorLayer{
+					{ID: "roads", Fields: map[string]string{"name": "Number", "ref": "String"}},
+					{ID: "buildings"},
+				}
+				return b
+			},
+			want: []TileJSONDiff{
+				{Field: "layers.buildings", B: "present"},
+				{Field: "layers.roads.fields.class", A: "String"},
+				{Field: "layers.roads.fields.name", A: "String", B: "Number"},
+				{Field: "layers.roads.fields.ref", B: "String"},
+				{Field: "layers.water", A: "present"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := DiffTileJSON(a, tt.b(a))
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("DiffTileJSON() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package maptiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrProcessingFailed is returned by Wait and CreateAndWait for ingests that
+// failed or were canceled while processing.
+var ErrProcessingFailed = errors.New("processing failed")
+
+// defaultPollInterval is how often Wait gets the state of an ingest.
+const defaultPollInterval = 5 * time.Second
+
+// minPollInterval and maxPollInterval bound the backoff between the polls of
+// WaitForState.
+const (
+	minPollInterval = 250 * time.Millisecond
+	maxPollInterval = 30 * time.Second
+)
+
+// Tileset references the tileset a completed ingest resulted in.
+type Tileset struct {
+	// ID is the ID of the dataset and its tileset.
+	ID string `json:"id"`
+	// TileJSONURL needs an API key, e.g. appended as ?key=<key>.
+	TileJSONURL string `json:"tilejson_url"`
+	// AdminURL is the MapTiler Cloud page of the dataset with a map of the tileset.
+	AdminURL string `json:"admin_url"`
+}
+
+func (t Tileset) String() string { return toJSONString(t) }
+
+// tileset returns the tileset of a dataset, or nil unless the ingest completed.
+func (c *Client) tileset(state IngestState, datasetID string) *Tileset {
+	if state != StateCompleted || datasetID == "" {
+		return nil
+	}
+	return &Tileset{
+		ID:          datasetID,
+		TileJSONURL: strings.TrimSuffix(c.tilesHost, "/") + "/" + url.PathEscape(datasetID) + "/tiles.json",
+		AdminURL:    AdminURL(datasetID),
+	}
+}
+
+// CreateAndWait creates a dataset like Create and waits until it is processed,
+// see Wait.
+func (c *Client) CreateAndWait(ctx context.Context, fp string, opts ...IngestOption) (IngestResponse, error) {
+	ir, err := c.Create(ctx, fp, opts...)
+	if err != nil {
+		return ir, err
+	}
+	return c.Wait(ctx, ir, opts...)
+}
+
+// Wait polls the ingest of ir until it completed and returns ir with the
+// final state and the resulting Tileset. Ingests that failed or were canceled
+// return an error wrapping ErrProcessingFailed. The interval is set by
+// WithPollInterval, the other options are ignored.
+func (c *Client) Wait(ctx context.Context, ir IngestResponse, opts ...IngestOption) (IngestResponse, error) {
+	cfg := newIngestConfig(opts...)
+	interval := cfg.pollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	for {
+		switch ir.State {
+		case StateCompleted:
+			ir.Tileset = c.tileset(ir.State, ir.DocumentID)
+			return ir, nil
+		case StateFailed, StateCanceled:
+			return ir, fmt.Errorf("ingest %s %s: %w", ir.ID, ir.State, processingError(ir.Errors))
+		}
+
+		if err := sleep(ctx, interval); err != nil {
+			return ir, fmt.Errorf("waiting for ingest %s: %w", ir.ID, err)
+		}
+		gr, err := c.Get(ctx, ir.ID)
+		if err != nil {
+			return ir, fmt.Errorf("waiting for ingest %s: %w", ir.ID, err)
+		}
+		ir.DocumentID = gr.DocumentID
+		ir.State = gr.State
+		ir.Progress = gr.Progress
+		ir.Errors = gr.Errors
+		ir.Warnings = gr.Warnings
+	}
+}
+
+// WaitForState polls the ingest id until it reaches one of states, e.g.
+// StateProcessing or StateCompleted, and returns it. The ingest is got right away and
+// then with a backoff from 250ms doubling up to 30s. Without states, or once
+// the ingest ended, the wait ends with the terminal state: completed returns
+// no error, failed and canceled an error wrapping ErrProcessingFailed unless
+// they are in states.
+func (c *Client) WaitForState(ctx context.Context, id string, states ...IngestState) (IngestGetResponse, error) {
+	delay := minPollInterval
+	for {
+		gr, err := c.Get(ctx, id)
+		if err != nil {
+			return gr, fmt.Errorf("waiting for ingest %s: %w", id, err)
+		}
+		if slices.Contains(states, gr.State) {
+			return gr, nil
+		}
+		switch gr.State {
+		case StateCompleted:
+			return gr, nil
+		case StateFailed, StateCanceled:
+			return gr, fmt.Errorf("ingest %s %s: %w", id, gr.State, processingError(gr.Errors))
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return gr, fmt.Errorf("waiting for ingest %s: %w", id, err)
+		}
+		delay = min(2*delay, maxPollInterval)
+	}
+}
+
+// processingError wraps the errors reported for an ingest into ErrProcessingFailed.
+func processingError(errs []MapTilerError) error {
+	if len(errs) == 0 {
+		return ErrProcessingFailed
+	}
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, e.Message)
+	}
+	return fmt.Errorf("%w: %s", ErrProcessingFailed, strings.Join(msgs, "; "))
+}
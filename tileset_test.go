@@ -0,0 +1,169 @@
+package maptiler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestCreateAttachesTileset(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer()
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ir, err := c.CreateAndWait(t.Context(), fp, WithPollInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("CreateAndWait() unexpected error: %v", err)
+	}
+	want := Tileset{
+		ID:          ir.DocumentID,
+		TileJSONURL: TilesetURL(ir.DocumentID),
+		AdminURL:    AdminURL(ir.DocumentID),
+	}
+	if ir.Tileset == nil || *ir.Tileset != want {
+		t.Fatalf("expected tileset %+v, got %+v", want, ir.Tileset)
+	}
+}
+
+func TestWait(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		states    []IngestState
+		wantState IngestState
+		wantErr   error
+	}{
+		{name: "completes", states: []IngestState{StateProcessing, StateProcessing, StateCompleted}, wantState: StateCompleted},
+		{name: "fails", states: []IngestState{StateProcessing, StateFailed}, wantState: StateFailed, wantErr: ErrProcessingFailed},
+		{name: "canceled", states: []IngestState{StateCanceled}, wantState: StateCanceled, wantErr: ErrProcessingFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var polls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/datasets/ingest/ing-1" {
+					http.NotFound(w, r)
+					return
+				}
+				state := tt.states[min(int(polls.Add(1))-1, len(tt.states)-1)]
+				gr := IngestGetResponse{ID: "ing-1", DocumentID: "ds-1", State: state}
+				if state == StateFailed {
+					gr.Errors = []MapTilerError{{Message: "invalid geometry"}}
+				}
+				_ = json.NewEncoder(w).Encode(gr)
+			}))
+			defer srv.Close()
+
+			c, err := New(srv.URL+"/v1", "token", WithTilesHost("https://tiles.example.com/"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ir, err := c.Wait(t.Context(), IngestResponse{ID: "ing-1", State: StateProcessing}, WithPollInterval(time.Millisecond))
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if ir.State != tt.wantState {
+				t.Fatalf("expected state %s, got %s", tt.wantState, ir.State)
+			}
+			if got := int(polls.Load()); got != len(tt.states) {
+				t.Fatalf("expected %d polls, got %d", len(tt.states), got)
+			}
+
+			if tt.wantErr != nil {
+				if ir.Tileset != nil {
+					t.Fatalf("expected no tileset, got %+v", ir.Tileset)
+				}
+				return
+			}
+			if ir.Tileset == nil || ir.Tileset.TileJSONURL != "https://tiles.example.com/ds-1/tiles.json" {
+				t.Fatalf("expected the tileset on the configured tiles host, got %+v", ir.Tileset)
+			}
+		})
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	t.Parallel()
+
+	c, err := New("http://127.0.0.1:0/v1", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err = c.Wait(ctx, IngestResponse{ID: "ing-1", State: StateProcessing})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestWaitForState(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		states    []IngestState
+		wait      []IngestState
+		wantState IngestState
+		wantPolls int
+		wantErr   error
+	}{
+		{name: "terminal", states: []IngestState{StateUpload, StateProcessing, StateCompleted}, wantState: StateCompleted, wantPolls: 3},
+		{name: "processing", states: []IngestState{StateUpload, StateProcessing, StateCompleted}, wait: []IngestState{StateProcessing}, wantState: StateProcessing, wantPolls: 2},
+		{name: "fails", states: []IngestState{StateFailed}, wait: []IngestState{StateCompleted}, wantState: StateFailed, wantPolls: 1, wantErr: ErrProcessingFailed},
+		{name: "expects failure", states: []IngestState{StateFailed}, wait: []IngestState{StateFailed}, wantState: StateFailed, wantPolls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var polls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				state := tt.states[min(int(polls.Add(1))-1, len(tt.states)-1)]
+				_ = json.NewEncoder(w).Encode(IngestGetResponse{ID: "ing-1", State: state})
+			}))
+			defer srv.Close()
+
+			c, err := New(srv.URL+"/v1", "token")
+			if err != nil {
+				t.Fatal(err)
+			}
+			gr, err := c.WaitForState(t.Context(), "ing-1", tt.wait...)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if gr.State != tt.wantState {
+				t.Fatalf("expected state %s, got %s", tt.wantState, gr.State)
+			}
+			if got := int(polls.Load()); got != tt.wantPolls {
+				t.Fatalf("expected %d polls, got %d", tt.wantPolls, got)
+			}
+		})
+	}
+}
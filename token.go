@@ -0,0 +1,100 @@
+package maptiler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/segmentio/ksuid"
+)
+
+// Access is what a token may do with an endpoint, as inferred by InspectToken.
+type Access string
+
+const (
+	// AccessAllowed means the endpoint accepted the token.
+	AccessAllowed Access = "allowed"
+	// AccessDenied means the endpoint rejected the token with 401 or 403.
+	AccessDenied Access = "denied"
+	// AccessUnknown means the endpoint failed for another reason, see Capability.Error.
+	AccessUnknown Access = "unknown"
+)
+
+// Capability is the access of a token to a single endpoint.
+type Capability struct {
+	Name       string `json:"name"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Access     Access `json:"access"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// tokenProbe is a read-only endpoint probed by InspectToken.
+type tokenProbe struct {
+	name string
+	path string
+}
+
+// tokenProbes returns the probes of InspectToken for the endpoints of paths.
+func tokenProbes(paths apiPaths) []tokenProbe {
+	return []tokenProbe{
+		{name: "read ingests", path: paths[EndpointIngestGet]},
+		{name: "read datasets", path: paths[EndpointDatasetGet]},
+	}
+}
+
+// InspectToken infers what the token of the Client may do by probing read-only
+// endpoints of the service API, e.g. to debug 403 responses. Every endpoint is
+// asked for a resource that does not exist, a 404 means the token is accepted.
+// Endpoints that change data are not probed.
+func (c *Client) InspectToken(ctx context.Context) ([]Capability, error) {
+	probeID := "maptiler-go-probe-" + ksuid.New().String()
+
+	probes := tokenProbes(c.paths)
+	caps := make([]Capability, 0, len(probes))
+	for _, p := range probes {
+		capability, err := c.probe(ctx, p, probeID)
+		if err != nil {
+			return caps, fmt.Errorf("inspecting token: %w", err)
+		}
+		caps = append(caps, capability)
+	}
+	return caps, nil
+}
+
+// probe requests resource id of p. Only a canceled ctx fails the probe, other
+// failures are reported in the Capability.
+func (c *Client) probe(ctx context.Context, p tokenProbe, id string) (Capability, error) {
+	capability := Capability{Name: p.name, Method: http.MethodGet, Path: p.path}
+	if err := c.apiLimit.wait(ctx, 1); err != nil {
+		return capability, err
+	}
+
+	resp, err := c.h.do(ctx, request{method: http.MethodGet, path: p.path, id: id})
+	if err != nil {
+		if ctx.Err() != nil {
+			return capability, err
+		}
+		capability.Access = AccessUnknown
+		capability.Error = err.Error()
+		return capability, nil
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	capability.StatusCode = resp.StatusCode
+	capability.Access = accessOf(resp.StatusCode)
+	return capability, nil
+}
+
+// accessOf infers the access of a token from the status of a probe.
+func accessOf(status int) Access {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return AccessDenied
+	case status < http.StatusBadRequest || status == http.StatusNotFound:
+		return AccessAllowed
+	default:
+		return AccessUnknown
+	}
+}
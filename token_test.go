@@ -0,0 +1,71 @@
+package maptiler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientInspectToken(t *testing.T) {
+	t.Parallel()
+
+	var probed []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("expected read-only probes, got %s %s", r.Method, r.URL.Path)
+		}
+		probed = append(probed, r.URL.Path)
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/datasets/ingest/"):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token")
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	caps, err := c.InspectToken(t.Context())
+	if err != nil {
+		t.Fatalf("InspectToken() unexpected error: %v", err)
+	}
+
+	want := map[string]Access{"read ingests": AccessAllowed, "read datasets": AccessDenied}
+	if len(caps) != len(want) {
+		t.Fatalf("expected %d capabilities, got %+v", len(want), caps)
+	}
+	for _, cp := range caps {
+		if cp.Access != want[cp.Name] {
+			t.Errorf("%s: expected %s, got %s (status %d)", cp.Name, want[cp.Name], cp.Access, cp.StatusCode)
+		}
+	}
+	for _, p := range probed {
+		if !strings.Contains(p, "maptiler-go-probe-") {
+			t.Errorf("expected a probe of a resource that does not exist, got %s", p)
+		}
+	}
+}
+
+func TestAccessOf(t *testing.T) {
+	t.Parallel()
+
+	for status, want := range map[int]Access{
+		http.StatusOK:                  AccessAllowed,
+		http.StatusNotModified:         AccessAllowed,
+		http.StatusNotFound:            AccessAllowed,
+		http.StatusUnauthorized:        AccessDenied,
+		http.StatusForbidden:           AccessDenied,
+		http.StatusBadRequest:          AccessUnknown,
+		http.StatusTooManyRequests:     AccessUnknown,
+		http.StatusInternalServerError: AccessUnknown,
+	} {
+		if got := accessOf(status); got != want {
+			t.Errorf("accessOf(%d) = %s, want %s", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package maptiler
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultRateWindow is the time constant of the moving average of the upload
+// rate, long enough to smooth out parts finishing in bursts.
+const DefaultRateWindow = 10 * time.Second
+
+// ProgressTracker keeps the latest Progress of an ingest for readers on other
+// goroutines, e.g. a UI that renders at its own pace instead of on every
+// update. Pass Update to WithProgress and call Snapshot from anywhere, it
+// does not block updates.
+type ProgressTracker struct {
+	latest  atomic.Pointer[ProgressSnapshot]
+	updates atomic.Int64
+
+	mu   sync.Mutex
+	rate rateEstimator
+}
+
+// ProgressSnapshot is the Progress last reported to a ProgressTracker.
+type ProgressSnapshot struct {
+	Progress
+	// Rate is the moving average of the bytes sent per second.
+	Rate float64 `json:"rate"`
+	// Updates is the number of updates received so far.
+	Updates int64 `json:"updates"`
+	// Updated is the time of the last update.
+	Updated time.Time `json:"updated"`
+}
+
+// NewProgressTracker returns a ProgressTracker that averages the rate with the
+// time constant window, or DefaultRateWindow if window is not positive.
+func NewProgressTracker(window time.Duration) *ProgressTracker {
+	if window <= 0 {
+		window = DefaultRateWindow
+	}
+	return &ProgressTracker{rate: rateEstimator{tau: window}}
+}
+
+// Update records p, it is a ProgressFunc. The ETA of p is estimated from the
+// rate if it is not set.
+func (t *ProgressTracker) Update(p Progress) {
+	now := time.Now()
+	sent := p.BytesDone + p.BytesInFlight
+
+	// the lock keeps concurrent updates from storing out of order.
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rate.add(now, sent)
+	rate := t.rate.rate()
+	if p.ETA == 0 {
+		p.ETA = etaOf(p.BytesTotal, sent, rate)
+	}
+	t.latest.Store(&ProgressSnapshot{
+		Progress: p,
+		Rate:     rate,
+		Updates:  t.updates.Add(1),
+		Updated:  now,
+	})
+}
+
+// Snapshot returns the latest update, or a zero ProgressSnapshot if there was none.
+func (t *ProgressTracker) Snapshot() ProgressSnapshot {
+	if s := t.latest.Load(); s != nil {
+		return *s
+	}
+	return ProgressSnapshot{}
+}
+
+func (s ProgressSnapshot) String() string { return toJSONString(s) }
+
+// rateEstimator estimates the rate of a growing byte count with an
+// exponentially weighted moving average. Every sample is weighted by the time
+// since the previous one, so frequent in-flight reports and parts of different
+// sizes finishing in bursts do not make the rate swing. Samples older than tau
+// contribute little. It is not safe for concurrent use.
+type rateEstimator struct {
+	tau   time.Duration
+	last  time.Time
+	bytes int64
+	value float64
+	known bool
+}
+
+// add records the count of bytes at a point in time. A count below the previous
+// one, e.g. of a part that started over, is taken as the new base.
+func (e *rateEstimator) add(at time.Time, bytes int64) {
+	if e.last.IsZero() {
+		e.last, e.bytes = at, bytes
+		return
+	}
+	dt := at.Sub(e.last).Seconds()
+	if dt <= 0 {
+		// the bytes are accounted with the next sample.
+		return
+	}
+
+	current := float64(max(bytes-e.bytes, 0)) / dt
+	if e.known {
+		alpha := 1 - math.Exp(-dt/e.tau.Seconds())
+		e.value += alpha * (current - e.value)
+	} else {
+		e.value, e.known = current, true
+	}
+	e.last, e.bytes = at, bytes
+}
+
+// rate returns the estimated bytes per second, or 0 if unknown.
+func (e *rateEstimator) rate() float64 {
+	return e.value
+}
+
+// etaOf estimates the time to send the remaining bytes at rate.
+func etaOf(total, sent int64, rate float64) time.Duration {
+	if rate <= 0 || sent >= total {
+		return 0
+	}
+	return time.Duration(float64(total-sent) / rate * float64(time.Second))
+}
@@ -0,0 +1,97 @@
+package maptiler
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateEstimator(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	e := rateEstimator{tau: 10 * time.Second}
+	if got := e.rate(); got != 0 {
+		t.Fatalf("expected no rate without samples, got %f", got)
+	}
+
+	e.add(start, 0)
+	e.add(start.Add(time.Second), 100)
+	if got := e.rate(); got != 100 {
+		t.Fatalf("expected the first rate as is, got %f", got)
+	}
+
+	// a steady rate stays where it is.
+	at := start.Add(time.Second)
+	sent := int64(100)
+	for range 30 {
+		at, sent = at.Add(time.Second), sent+100
+		e.add(at, sent)
+	}
+	if got := e.rate(); math.Abs(got-100) > 0.001 {
+		t.Fatalf("expected a steady 100 B/s, got %f", got)
+	}
+
+	// a large part finishing in a burst moves the rate only a little.
+	at, sent = at.Add(100*time.Millisecond), sent+1000
+	e.add(at, sent)
+	if got := e.rate(); got < 100 || got > 200 {
+		t.Fatalf("expected the burst to be smoothed, got %f", got)
+	}
+
+	// samples at the same time and a part starting over do not count as progress.
+	e.add(at, sent+5000)
+	before := e.rate()
+	e.add(at.Add(time.Second), sent-500)
+	if got := e.rate(); got >= before {
+		t.Fatalf("expected no progress from a part starting over, got %f after %f", got, before)
+	}
+}
+
+func TestProgressTracker(t *testing.T) {
+	t.Parallel()
+
+	tr := NewProgressTracker(time.Minute)
+	if got := tr.Snapshot(); got.Updates != 0 || got.Phase != "" {
+		t.Fatalf("expected a zero snapshot, got %+v", got)
+	}
+
+	tr.Update(Progress{IngestID: "ing-1", Phase: PhaseUpload, BytesTotal: 100})
+	time.Sleep(10 * time.Millisecond)
+	tr.Update(Progress{IngestID: "ing-1", Phase: PhaseUpload, BytesTotal: 100, BytesDone: 50})
+
+	s := tr.Snapshot()
+	if s.Updates != 2 || s.BytesDone != 50 || s.IngestID != "ing-1" {
+		t.Fatalf("unexpected snapshot %+v", s)
+	}
+	if s.Rate <= 0 || s.ETA <= 0 {
+		t.Fatalf("expected a rate and an ETA, got %+v", s)
+	}
+
+	// an ETA that is set is kept.
+	tr.Update(Progress{Phase: PhaseUpload, BytesTotal: 100, BytesDone: 60, ETA: time.Hour})
+	if got := tr.Snapshot().ETA; got != time.Hour {
+		t.Fatalf("expected the given ETA, got %s", got)
+	}
+}
+
+func TestProgressTrackerConcurrent(t *testing.T) {
+	t.Parallel()
+
+	tr := NewProgressTracker(0)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			for j := range 100 {
+				tr.Update(Progress{Phase: PhaseUpload, BytesTotal: 1000, BytesDone: int64(i + j)})
+				_ = tr.Snapshot()
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := tr.Snapshot().Updates; got != 800 {
+		t.Fatalf("expected 800 updates, got %d", got)
+	}
+}
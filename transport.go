@@ -0,0 +1,125 @@
+package maptiler
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/cookiejar"
+	"time"
+
+	"github.com/iwpnd/rip"
+)
+
+// request is a request to the service API, the tiles API or an upload target,
+// independent of the HTTP library sending it.
+type request struct {
+	method string
+	// path is appended to the host of the transport, :id is replaced by id.
+	path   string
+	id     string
+	query  map[string]string
+	header map[string]string
+	// body is sent as JSON, an io.Reader as it is with a Content-Length of
+	// length if that is positive.
+	body   any
+	length int64
+}
+
+// transport sends the requests of a Client, the caller closes the body of the
+// response. The rest of the package only uses transport, so the HTTP library
+// behind it can be replaced without changing the public API.
+type transport interface {
+	do(ctx context.Context, r request) (*http.Response, error)
+}
+
+// transportConfig configures a transport.
+type transportConfig struct {
+	host string
+	// transport sends the requests, http.DefaultTransport if nil.
+	transport *http.Transport
+	// timeout limits every request, 0 is no limit.
+	timeout time.Duration
+	// header is sent with every request.
+	header map[string]string
+	jar    *cookiejar.Jar
+}
+
+// ripTransport is the transport of rip.
+type ripTransport struct {
+	c *rip.Client
+}
+
+// newRipTransport returns a transport sending requests with rip.
+func newRipTransport(config transportConfig) (transport, error) {
+	var options []rip.Option
+	if config.transport != nil {
+		options = append(options, rip.WithTransport(config.transport))
+	}
+	if config.timeout > 0 {
+		options = append(options, rip.WithTimeout(config.timeout))
+	}
+	if config.header != nil {
+		options = append(options, rip.WithDefaultHeaders(config.header))
+	}
+	if config.jar != nil {
+		options = append(options, rip.WithCookieJar(config.jar))
+	}
+	c, err := rip.NewClient(config.host, options...)
+	if err != nil {
+		return nil, err
+	}
+	return ripTransport{c: c}, nil
+}
+
+func (t ripTransport) do(ctx context.Context, r request) (*http.Response, error) {
+	req := t.c.NR()
+	if r.id != "" {
+		req.SetParams(rip.Params{"id": r.id})
+	}
+	if r.query != nil {
+		q := make(rip.Query, len(r.query))
+		for k, v := range r.query {
+			q[k] = v
+		}
+		req.SetQuery(q)
+	}
+	for k, v := range r.header {
+		req.SetHeader(k, v)
+	}
+	if r.body != nil {
+		req.SetBody(r.body)
+	}
+	if r.length > 0 {
+		req.SetContentLength(r.length)
+	}
+
+	resp, err := req.Execute(ctx, r.method, r.path)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        resp.Status(),
+		StatusCode:    resp.StatusCode(),
+		Header:        resp.Header(),
+		Body:          resp.RawBody(),
+		ContentLength: resp.ContentLength(),
+	}, nil
+}
+
+// asHTTPTransport returns rt as an *http.Transport, as rip only accepts those.
+// Other round trippers are registered for http and https of an empty one.
+func asHTTPTransport(rt http.RoundTripper) *http.Transport {
+	if tr, ok := rt.(*http.Transport); ok {
+		return tr
+	}
+	// an empty TLSNextProto keeps HTTP/2 from registering itself for https.
+	tr := &http.Transport{TLSNextProto: map[string]func(string, *tls.Conn) http.RoundTripper{}}
+	tr.RegisterProtocol("http", rt)
+	tr.RegisterProtocol("https", rt)
+	return tr
+}
+
+// isError reports whether resp is an error response.
+func isError(resp *http.Response) bool {
+	return resp.StatusCode >= http.StatusBadRequest
+}
@@ -0,0 +1,186 @@
+package maptiler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+// recordingTransport records the paths of the requests it sends.
+type recordingTransport struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.paths = append(t.paths, req.URL.Path)
+	t.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWithRoundTripper(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	t.Cleanup(srv.Close)
+
+	for _, tc := range []struct {
+		name string
+		opt  func(rt http.RoundTripper) Option
+	}{
+		{name: "round tripper", opt: WithRoundTripper},
+		{name: "http client", opt: func(rt http.RoundTripper) Option {
+			return WithHTTPClient(&http.Client{Transport: rt})
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rt := &recordingTransport{}
+			c, err := New(srv.URL, "token", tc.opt(rt))
+			if err != nil {
+				t.Fatal(err)
+			}
+			fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+			if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Create(t.Context(), fp); err != nil {
+				t.Fatalf("Create() unexpected error: %v", err)
+			}
+
+			rt.mu.Lock()
+			defer rt.mu.Unlock()
+			var api, parts int
+			for _, p := range rt.paths {
+				switch {
+				case strings.HasPrefix(p, "/v1/"):
+					api++
+				case strings.HasPrefix(p, "/upload/"):
+					parts++
+				}
+			}
+			// create and process, and three parts.
+			if api != 2 || parts != 3 {
+				t.Fatalf("expected 2 API requests and 3 parts through the round tripper, got %v", rt.paths)
+			}
+		})
+	}
+}
+
+func TestWithTransportWrapper(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer(maptilertest.WithPartSize(4))
+	t.Cleanup(srv.Close)
+
+	rt := &recordingTransport{}
+	// the wrapper applies to the round tripper even if it is given first.
+	c, err := New(srv.URL, "token",
+		WithRetryPolicy(NoRetry{}),
+		WithTransportWrapper(func(base http.RoundTripper) http.RoundTripper {
+			tr := maptilertest.NewTransport(maptilertest.Faults{FailPartsAfter: 1})
+			tr.Base = base
+			return tr
+		}),
+		WithRoundTripper(rt),
+		WithConcurrency(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("0123456789"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Create(t.Context(), fp); !errors.Is(err, maptilertest.ErrPartFailed) {
+		t.Fatalf("Create() error = %v, want %v", err, maptilertest.ErrPartFailed)
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	var parts int
+	for _, p := range rt.paths {
+		if strings.HasPrefix(p, "/upload/") {
+			parts++
+		}
+	}
+	if parts != 1 {
+		t.Fatalf("expected the first part only through the round tripper, got %v", rt.paths)
+	}
+}
+
+func TestWithHTTPClientTimeout(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL+"/v1", "token", WithHTTPClient(&http.Client{Timeout: 50 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := c.Get(t.Context(), "ing-1"); err == nil {
+		t.Fatal("expected a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected the timeout of the http.Client to apply, took %s", elapsed)
+	}
+}
+
+func TestRipTransport(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Got", strings.Join([]string{
+			r.Method, r.URL.Path, r.URL.Query().Get("key"), r.Header.Get("X-Test"), r.Header.Get("Content-Type"), string(body),
+		}, " "))
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("short and stout"))
+	}))
+	t.Cleanup(srv.Close)
+
+	tr, err := newRipTransport(transportConfig{host: srv.URL, header: map[string]string{"X-Test": "default"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tr.do(t.Context(), request{
+		method: http.MethodPost,
+		path:   "/datasets/:id",
+		id:     "ds-1",
+		query:  map[string]string{"key": "k"},
+		body:   map[string]string{"a": "b"},
+	})
+	if err != nil {
+		t.Fatalf("do() unexpected error: %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	want := `POST /datasets/ds-1 k default application/json {"a":"b"}`
+	if got := resp.Header.Get("X-Got"); got != want {
+		t.Fatalf("sent %q, want %q", got, want)
+	}
+	if !isError(resp) {
+		t.Fatalf("expected status %d to be an error", resp.StatusCode)
+	}
+	if aerr := newAPIError(resp); string(aerr.Body) != "short and stout" {
+		t.Fatalf("unexpected error body %q", aerr.Body)
+	}
+}
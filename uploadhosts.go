@@ -0,0 +1,70 @@
+package maptiler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ErrUploadHostNotAllowed is returned when WithUploadHosts is used and the
+// service hands out a part URL of a host that is not allowed.
+var ErrUploadHostNotAllowed = errors.New("upload host is not allowed")
+
+// hostAllowlist is the set of hosts parts may be uploaded to. A nil allowlist
+// allows every host.
+type hostAllowlist []string
+
+// newHostAllowlist normalizes patterns, an empty list allows every host.
+func newHostAllowlist(patterns []string) hostAllowlist {
+	if len(patterns) == 0 {
+		return nil
+	}
+	l := make(hostAllowlist, 0, len(patterns))
+	for _, p := range patterns {
+		l = append(l, strings.ToLower(strings.TrimSpace(p)))
+	}
+	return l
+}
+
+// allows reports whether host, with an optional port, matches one of the
+// patterns. A pattern is a host, which matches on any port, a host:port, or
+// *.domain, which matches every subdomain of domain but not domain itself.
+func (l hostAllowlist) allows(host string) bool {
+	if l == nil {
+		return true
+	}
+	host = strings.ToLower(host)
+	name := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		name = h
+	}
+	for _, p := range l {
+		switch {
+		case p == host, p == name:
+			return true
+		case strings.HasPrefix(p, "*.") && strings.HasSuffix(name, p[1:]):
+			return true
+		}
+	}
+	return false
+}
+
+// check fails with ErrUploadHostNotAllowed for the first part whose URL points
+// to a host that is not allowed.
+func (l hostAllowlist) check(parts uploadParts) error {
+	if l == nil {
+		return nil
+	}
+	for _, p := range parts {
+		u, err := url.Parse(p.URL)
+		if err != nil {
+			return fmt.Errorf("part %d: parsing upload url: %w", p.PartID, err)
+		}
+		if !l.allows(u.Host) {
+			return fmt.Errorf("part %d: %q: %w", p.PartID, u.Hostname(), ErrUploadHostNotAllowed)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package maptiler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestHostAllowlist(t *testing.T) {
+	t.Parallel()
+
+	l := newHostAllowlist([]string{"*.amazonaws.com", "Proxy.example.com:8443", "127.0.0.1"})
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "bucket.s3.eu-west-1.amazonaws.com", want: true},
+		{host: "bucket.s3.amazonaws.com:443", want: true},
+		{host: "amazonaws.com", want: false},
+		{host: "evil-amazonaws.com", want: false},
+		{host: "amazonaws.com.evil.com", want: false},
+		{host: "proxy.example.com:8443", want: true},
+		{host: "proxy.example.com", want: false},
+		{host: "proxy.example.com:443", want: false},
+		{host: "127.0.0.1:54321", want: true},
+		{host: "127.0.0.2", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := l.allows(tt.host); got != tt.want {
+			t.Errorf("allows(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+
+	if !newHostAllowlist(nil).allows("anything.example.com") {
+		t.Errorf("expected an empty allowlist to allow every host")
+	}
+}
+
+func TestClientUploadHosts(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer()
+	defer srv.Close()
+
+	fp := filepath.Join(t.TempDir(), "tiles.pmtiles")
+	if err := os.WriteFile(fp, []byte("tiles"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	allowed, err := New(srv.URL, "token", WithUploadHosts("127.0.0.1"))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if _, err := allowed.Create(t.Context(), fp); err != nil {
+		t.Fatalf("Create() unexpected error: %v", err)
+	}
+
+	denied, err := New(srv.URL, "token", WithUploadHosts("*.amazonaws.com"))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	_, err = denied.Create(t.Context(), fp)
+	if !errors.Is(err, ErrUploadHostNotAllowed) {
+		t.Fatalf("expected ErrUploadHostNotAllowed, got %v", err)
+	}
+	var uerr UploadFailedError
+	if !errors.As(err, &uerr) || IngestState(srv.State(uerr.ID)) != StateCanceled {
+		t.Fatalf("expected the ingest to be canceled, got %v", err)
+	}
+}
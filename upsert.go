@@ -0,0 +1,46 @@
+package maptiler
+
+import (
+	"context"
+	"path/filepath"
+)
+
+// UpsertAction is the path Upsert took.
+type UpsertAction string
+
+const (
+	// UpsertCreated means a new dataset was created.
+	UpsertCreated UpsertAction = "created"
+	// UpsertUpdated means an existing dataset of the same name was updated.
+	UpsertUpdated UpsertAction = "updated"
+	// UpsertUnchanged means the existing dataset already holds the file, see
+	// WithIdempotentUpdates.
+	UpsertUnchanged UpsertAction = "unchanged"
+)
+
+// Upsert updates the dataset named name with the file at fp, or creates it if
+// there is none. An empty name uses the name of the file. Names are normalized
+// like with WithFilename, and resolved like with WithDuplicateCheck, so only
+// datasets created from this host by Upsert or WithDuplicateCheck are found.
+func (c *Client) Upsert(ctx context.Context, name, fp string, opts ...IngestOption) (IngestResponse, UpsertAction, error) {
+	cfg := newIngestConfig(append(opts, WithFilename(name))...)
+	id, err := c.datasetByName(ctx, cfg.filename(filepath.Base(fp)))
+	if err != nil {
+		return IngestResponse{}, "", err
+	}
+
+	if id != "" {
+		ir, err := c.withCancel(ctx, c.process, id, fp, cfg)
+		if ir.Unchanged {
+			return ir, UpsertUnchanged, err
+		}
+		return ir, UpsertUpdated, err
+	}
+
+	// a dataset of the same name created in the meantime fails the create with
+	// ErrDuplicateName, rather than updating it and reporting a create.
+	cfg.duplicateCheck = true
+	cfg.upsert = false
+	ir, err := c.withCancel(ctx, c.process, "", fp, cfg)
+	return ir, UpsertCreated, err
+}
@@ -0,0 +1,50 @@
+package maptiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iwpnd/maptiler-go/maptilertest"
+)
+
+func TestClientUpsert(t *testing.T) {
+	t.Parallel()
+
+	srv := maptilertest.NewServer()
+	defer srv.Close()
+
+	c, err := New(srv.URL, "token", WithLockDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	fp := filepath.Join(t.TempDir(), "tmp-8f3a.pmtiles")
+	if err := os.WriteFile(fp, []byte("tiles"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	created, action, err := c.Upsert(t.Context(), "europe-roads", fp)
+	if err != nil {
+		t.Fatalf("Upsert() unexpected error: %v", err)
+	}
+	if action != UpsertCreated || created.Filename != "europe-roads.pmtiles" {
+		t.Fatalf("expected europe-roads.pmtiles to be created, got %s of %q", action, created.Filename)
+	}
+
+	updated, action, err := c.Upsert(t.Context(), "europe-roads.pmtiles", fp)
+	if err != nil {
+		t.Fatalf("Upsert() unexpected error: %v", err)
+	}
+	if action != UpsertUpdated || updated.DocumentID != created.DocumentID {
+		t.Fatalf("expected dataset %s to be updated, got %s of %s", created.DocumentID, action, updated.DocumentID)
+	}
+
+	other, action, err := c.Upsert(t.Context(), "", fp)
+	if err != nil {
+		t.Fatalf("Upsert() unexpected error: %v", err)
+	}
+	if action != UpsertCreated || other.DocumentID == created.DocumentID {
+		t.Fatalf("expected a dataset named after the file to be created, got %s of %s", action, other.DocumentID)
+	}
+}
@@ -0,0 +1,21 @@
+package maptiler
+
+import "net/url"
+
+const (
+	// tilesHost serves the tilesets of published datasets.
+	tilesHost = "https://api.maptiler.com/tiles"
+	// cloudHost is the MapTiler Cloud admin interface.
+	cloudHost = "https://cloud.maptiler.com/tiles"
+)
+
+// TilesetURL returns the TileJSON URL of the tileset of a dataset. Requests to it
+// need an API key, e.g. appended as ?key=<key>.
+func TilesetURL(datasetID string) string {
+	return tilesHost + "/" + url.PathEscape(datasetID) + "/tiles.json"
+}
+
+// AdminURL returns the MapTiler Cloud page of a dataset.
+func AdminURL(datasetID string) string {
+	return cloudHost + "/" + url.PathEscape(datasetID) + "/"
+}
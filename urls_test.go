@@ -0,0 +1,25 @@
+package maptiler
+
+import "testing"
+
+func TestURLs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "tileset", got: TilesetURL("ds-1"), want: "https://api.maptiler.com/tiles/ds-1/tiles.json"},
+		{name: "admin", got: AdminURL("ds-1"), want: "https://cloud.maptiler.com/tiles/ds-1/"},
+		{name: "escaped", got: AdminURL("a/b"), want: "https://cloud.maptiler.com/tiles/a%2Fb/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if tt.got != tt.want {
+				t.Fatalf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
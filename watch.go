@@ -0,0 +1,54 @@
+package maptiler
+
+import (
+	"context"
+	"fmt"
+)
+
+// Watch polls the ingest id and sends its state whenever the state or the
+// progress changed, starting with the current one, e.g. to follow processing
+// milestones. The channel is closed once the ingest completed, failed or was
+// canceled, or ctx is done. A failing poll is logged and closes the channel
+// too, Get returns the cause. The interval is set by WithPollInterval, the
+// other options are ignored.
+func (c *Client) Watch(ctx context.Context, id string, opts ...IngestOption) (<-chan IngestGetResponse, error) {
+	cfg := newIngestConfig(opts...)
+	interval := cfg.pollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	gr, err := c.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("watching ingest %s: %w", id, err)
+	}
+
+	ch := make(chan IngestGetResponse, 1)
+	ch <- gr
+	go func() {
+		defer close(ch)
+		last := gr
+		for !last.Ended() {
+			if err := sleep(ctx, interval); err != nil {
+				return
+			}
+			next, err := c.Get(ctx, id)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger(c.log).Warn("watching ingest failed", "ingest_id", id, "error", err)
+				}
+				return
+			}
+			if next.State == last.State && next.Progress == last.Progress {
+				continue
+			}
+			select {
+			case ch <- next:
+			case <-ctx.Done():
+				return
+			}
+			last = next
+		}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,55 @@
+package maptiler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	polls := []IngestGetResponse{
+		{State: StateUpload},
+		{State: StateProcessing, Progress: 10},
+		{State: StateProcessing, Progress: 10},
+		{State: StateProcessing, Progress: 50},
+		{State: StateCompleted, Progress: 100},
+	}
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gr := polls[min(int(n.Add(1))-1, len(polls)-1)]
+		gr.ID = "ing-1"
+		_ = json.NewEncoder(w).Encode(gr)
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL+"/v1", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := c.Watch(t.Context(), "ing-1", WithPollInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("Watch() unexpected error: %v", err)
+	}
+
+	var got []IngestGetResponse
+	for gr := range ch {
+		got = append(got, gr)
+	}
+	want := []IngestGetResponse{polls[0], polls[1], polls[3], polls[4]}
+	if len(got) != len(want) {
+		t.Fatalf("got %d updates %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i].State != want[i].State || got[i].Progress != want[i].Progress {
+			t.Errorf("update %d = %s %.0f, want %s %.0f", i, got[i].State, got[i].Progress, want[i].State, want[i].Progress)
+		}
+	}
+	if int(n.Load()) != len(polls) {
+		t.Errorf("polled %d times after completion, want %d", n.Load(), len(polls))
+	}
+}